@@ -24,6 +24,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"runtime/pprof"
 	"strings"
 
@@ -37,6 +38,7 @@ import (
 	"github.com/Jeffail/benthos/lib/api"
 	"github.com/Jeffail/benthos/lib/buffer"
 	"github.com/Jeffail/benthos/lib/cache"
+	"github.com/Jeffail/benthos/lib/config"
 	"github.com/Jeffail/benthos/lib/input"
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/manager"
@@ -47,7 +49,10 @@ import (
 	"github.com/Jeffail/benthos/lib/processor/condition"
 	"github.com/Jeffail/benthos/lib/stream"
 	strmmgr "github.com/Jeffail/benthos/lib/stream/manager"
-	"github.com/Jeffail/benthos/lib/util/config"
+	"github.com/Jeffail/benthos/lib/test"
+	"github.com/Jeffail/benthos/lib/types"
+	uconfig "github.com/Jeffail/benthos/lib/util/config"
+	"github.com/Jeffail/benthos/lib/util/text"
 	yaml "gopkg.in/yaml.v2"
 )
 
@@ -119,12 +124,20 @@ func (c Config) Sanitised() (interface{}, error) {
 		return nil, err
 	}
 
+	var dlConf interface{}
+	if c.DeadLetter.Enabled {
+		if dlConf, err = stream.SanitiseDeadLetterConfig(c.DeadLetter); err != nil {
+			return nil, err
+		}
+	}
+
 	return struct {
 		HTTP                 interface{} `json:"http" yaml:"http"`
 		Input                interface{} `json:"input" yaml:"input"`
 		Buffer               interface{} `json:"buffer" yaml:"buffer"`
 		Pipeline             interface{} `json:"pipeline" yaml:"pipeline"`
 		Output               interface{} `json:"output" yaml:"output"`
+		DeadLetter           interface{} `json:"dead_letter,omitempty" yaml:"dead_letter,omitempty"`
 		Manager              interface{} `json:"resources" yaml:"resources"`
 		Logger               interface{} `json:"logger" yaml:"logger"`
 		Metrics              interface{} `json:"metrics" yaml:"metrics"`
@@ -135,6 +148,7 @@ func (c Config) Sanitised() (interface{}, error) {
 		Buffer:               bufConf,
 		Pipeline:             pipeConf,
 		Output:               outConf,
+		DeadLetter:           dlConf,
 		Manager:              c.Manager,
 		Logger:               c.Logger,
 		Metrics:              metConf,
@@ -155,15 +169,42 @@ var (
 	showConfigYAML = flag.Bool(
 		"print-yaml", false, "Print loaded configuration as YAML, then exit",
 	)
+	showSchema = flag.Bool(
+		"print-schema", false,
+		"Print a JSON Schema document describing the configuration format,"+
+			" then exit",
+	)
 	showAll = flag.Bool(
 		"all", false,
 		"Set whether _all_ fields should be shown when printing configuration"+
 			" via --print-yaml or --print-json, otherwise only used values"+
 			" will be printed.",
 	)
+	runTests = flag.Bool(
+		"test", false,
+		"Run the tests of any config test definitions ending with"+
+			" _benthos_test.yaml found within the paths provided as arguments,"+
+			" defaulting to the current directory, then exit",
+	)
 	configPath = flag.String(
 		"c", "", "Path to a configuration file",
 	)
+	lintConfig = flag.Bool(
+		"lint", false,
+		"Lint the loaded configuration file for fields that are not recognised"+
+			" or are ignored by the selected component type, print any"+
+			" problems found, then exit",
+	)
+	watchConfig = flag.Bool(
+		"watch", false,
+		"Watch the configuration file for changes, and when it changes replace"+
+			" the running stream (input, buffer, pipeline, output) with one"+
+			" built from the updated config. Invalid changes are logged and"+
+			" the current stream is left running. In streams mode the"+
+			" --streams-dir directory is watched instead, and files that are"+
+			" created, changed or removed result in their streams being"+
+			" created, updated or deleted.",
+	)
 	swapEnvs = flag.Bool(
 		"swap-envs", true,
 		"Swap ${FOO} patterns in config file with environment variables",
@@ -216,6 +257,18 @@ var (
 			" configuration (input, buffer, pipeline, output), where the"+
 			" filename less the extension will be the id of the stream.",
 	)
+	streamsStoreDir = flag.String(
+		"streams-store-dir", "",
+		"When running Benthos in streams mode, persist the config of each"+
+			" stream that is created, updated or deleted to this directory,"+
+			" and restore the persisted streams at startup.",
+	)
+	streamsStoreCache = flag.String(
+		"streams-store-cache", "",
+		"When running Benthos in streams mode, persist the config of each"+
+			" stream that is created, updated or deleted to the cache resource"+
+			" of this name, and restore the persisted streams at startup.",
+	)
 )
 
 //------------------------------------------------------------------------------
@@ -266,9 +319,22 @@ func addExamples(examples string, conf *Config) {
 	}
 }
 
+// lintFile reads a config file and returns any linting errors found within it.
+func lintFile(path string) ([]string, error) {
+	configBytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if *swapEnvs {
+		configBytes = text.ReplaceEnvVariables(configBytes)
+	}
+	return config.Lint(configBytes, Config{})
+}
+
 // bootstrap reads cmd args and either parses and config file or prints helper
-// text and exits.
-func bootstrap() Config {
+// text and exits. The path of the config file read, if any, is returned along
+// with the config.
+func bootstrap() (Config, string) {
 	conf := NewConfig()
 
 	// A list of default config paths to check for if not explicitly defined
@@ -297,8 +363,32 @@ func bootstrap() Config {
 		os.Exit(0)
 	}
 
-	if len(*configPath) > 0 {
-		if err := config.Read(*configPath, *swapEnvs, &conf); err != nil {
+	// If the user wants to run config tests we do so and then exit.
+	if *runTests {
+		paths := flag.Args()
+		if len(paths) == 0 {
+			paths = []string{"."}
+		}
+		if test.RunAll(paths, *swapEnvs, os.Stdout) {
+			os.Exit(0)
+		}
+		os.Exit(1)
+	}
+
+	// If the user wants the configuration schema we print it.
+	if *showSchema {
+		schema, err := config.JSONSchema(conf)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Configuration schema error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(schema))
+		os.Exit(0)
+	}
+
+	loadedPath := *configPath
+	if len(loadedPath) > 0 {
+		if err := uconfig.Read(loadedPath, *swapEnvs, &conf); err != nil {
 			fmt.Fprintf(os.Stderr, "Configuration file read error: %v\n", err)
 			os.Exit(1)
 		}
@@ -308,15 +398,36 @@ func bootstrap() Config {
 			if _, err := os.Stat(path); err == nil {
 				fmt.Fprintf(os.Stderr, "Config file not specified, reading from %v\n", path)
 
-				if err = config.Read(path, *swapEnvs, &conf); err != nil {
+				if err = uconfig.Read(path, *swapEnvs, &conf); err != nil {
 					fmt.Fprintf(os.Stderr, "Configuration file read error: %v\n", err)
 					os.Exit(1)
 				}
+				loadedPath = path
 				break
 			}
 		}
 	}
 
+	// If the user wants the configuration to be linted we do so and then exit.
+	if *lintConfig {
+		if len(loadedPath) == 0 {
+			fmt.Fprintln(os.Stderr, "A configuration file must be specified in order to lint")
+			os.Exit(1)
+		}
+		lints, err := lintFile(loadedPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Configuration file lint error: %v\n", err)
+			os.Exit(1)
+		}
+		for _, l := range lints {
+			fmt.Fprintln(os.Stderr, l)
+		}
+		if len(lints) > 0 {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
 	// If the user wants the configuration to be printed we do so and then exit.
 	if *showConfigJSON || *showConfigYAML {
 		var outConf interface{}
@@ -381,7 +492,7 @@ func bootstrap() Config {
 		os.Exit(0)
 	}
 
-	return conf
+	return conf, loadedPath
 }
 
 type stoppableStreams interface {
@@ -390,7 +501,7 @@ type stoppableStreams interface {
 
 func main() {
 	// Bootstrap by reading cmd flags and configuration file.
-	config := bootstrap()
+	config, loadedPath := bootstrap()
 
 	// Logging and stats aggregation.
 	var logger log.Modular
@@ -428,32 +539,85 @@ func main() {
 	}
 
 	var dataStream stoppableStreams
+	var streamsDirWatcher *strmmgr.DirectoryWatcher
 	dataStreamClosedChan := make(chan struct{})
 
 	// Create data streams.
 	if *streamsMode {
-		streamMgr := strmmgr.New(
-			strmmgr.OptSetAPITimeout(time.Duration(config.HTTP.ReadTimeoutMS)*time.Millisecond),
+		streamMgrOpts := []func(*strmmgr.Type){
+			strmmgr.OptSetAPITimeout(time.Duration(config.HTTP.ReadTimeoutMS) * time.Millisecond),
 			strmmgr.OptSetLogger(logger),
 			strmmgr.OptSetManager(manager),
 			strmmgr.OptSetStats(stats),
-		)
-		var streamConfs map[string]stream.Config
-		if streamConfs, err = strmmgr.LoadStreamConfigsFromDirectory(true, *streamsDir); err != nil {
-			logger.Errorf("Failed to load stream configs: %v\n", err)
+		}
+		if len(*streamsStoreDir) > 0 && len(*streamsStoreCache) > 0 {
+			logger.Errorln("Only one of --streams-store-dir and --streams-store-cache can be set")
 			os.Exit(1)
 		}
-		dataStream = streamMgr
-		for id, conf := range streamConfs {
-			if err = streamMgr.Create(id, conf); err != nil {
-				logger.Errorf("Failed to create stream (%v): %v\n", id, err)
+		if len(*streamsStoreDir) > 0 {
+			var store *strmmgr.DirectoryStore
+			if store, err = strmmgr.NewDirectoryStore(*streamsStoreDir); err != nil {
+				logger.Errorf("Failed to create streams store: %v\n", err)
 				os.Exit(1)
 			}
+			streamMgrOpts = append(streamMgrOpts, strmmgr.OptSetStore(store))
+		}
+		if len(*streamsStoreCache) > 0 {
+			var storeCache types.Cache
+			if storeCache, err = manager.GetCache(*streamsStoreCache); err != nil {
+				logger.Errorf("Failed to obtain streams store cache '%v': %v\n", *streamsStoreCache, err)
+				os.Exit(1)
+			}
+			streamMgrOpts = append(streamMgrOpts, strmmgr.OptSetStore(
+				strmmgr.NewCacheStore(storeCache, "benthos_streams_"),
+			))
+		}
+		streamMgr := strmmgr.New(streamMgrOpts...)
+		dataStream = streamMgr
+
+		// The directory is watched before it is loaded so that no change made
+		// in between is missed.
+		if *watchConfig {
+			streamsDirWatcher = strmmgr.NewDirectoryWatcher(streamMgr, *swapEnvs, *streamsDir)
+		}
+
+		// Streams from the directory are not persisted to the store, as their
+		// configs already live within the directory.
+		var lStreams int
+		if lStreams, err = streamMgr.CreateFromDirectory(true, *streamsDir); err != nil {
+			logger.Errorf("Failed to load streams: %v\n", err)
+			os.Exit(1)
 		}
 		logger.Infoln("Launching benthos in streams mode, use CTRL+C to close.")
-		if lStreams := len(streamConfs); lStreams > 0 {
+		if lStreams > 0 {
 			logger.Infof("Created %v streams from directory: %v\n", lStreams, *streamsDir)
 		}
+		var restored int
+		if restored, err = streamMgr.Restore(); err != nil {
+			logger.Errorf("Failed to restore persisted streams: %v\n", err)
+			os.Exit(1)
+		}
+		if restored > 0 {
+			logger.Infof("Restored %v persisted streams\n", restored)
+		}
+		if *watchConfig {
+			logger.Infof("Watching directory for stream changes: %v\n", *streamsDir)
+		}
+	} else if *watchConfig {
+		if len(loadedPath) == 0 {
+			logger.Errorln("A configuration file must be specified in order to watch it")
+			os.Exit(1)
+		}
+		if dataStream, err = newReloadingStream(
+			loadedPath, config, manager, logger, stats,
+			func() {
+				close(dataStreamClosedChan)
+			},
+		); err != nil {
+			logger.Errorf("Service closing due to: %v\n", err)
+			os.Exit(1)
+		}
+		logger.Infof("Launching a benthos instance watching config file %v for changes, use CTRL+C to close.\n", loadedPath)
 	} else {
 		if dataStream, err = stream.New(
 			config.Config,
@@ -507,6 +671,12 @@ func main() {
 			os.Exit(1)
 		}()
 
+		if streamsDirWatcher != nil {
+			streamsDirWatcher.CloseAsync()
+			if err := streamsDirWatcher.WaitForClose(tout / 2); err != nil {
+				logger.Warnln("Service failed to close the streams directory watcher in time.")
+			}
+		}
 		if err := dataStream.Stop(tout); err != nil {
 			os.Exit(1)
 		}
@@ -0,0 +1,222 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package main
+
+import (
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/stream"
+	strmmgr "github.com/Jeffail/benthos/lib/stream/manager"
+	"github.com/Jeffail/benthos/lib/types"
+	uconfig "github.com/Jeffail/benthos/lib/util/config"
+	"github.com/Jeffail/benthos/lib/util/watch"
+)
+
+//------------------------------------------------------------------------------
+
+// reloadingStream runs a single stream and watches the config file it was
+// created from. When the file changes the stream is stopped and replaced with a
+// new stream built from the updated config. The HTTP server, resources, logger
+// and metrics are kept alive throughout.
+type reloadingStream struct {
+	path    string
+	conf    Config
+	timeout time.Duration
+
+	manager types.Manager
+	logger  log.Modular
+	stats   metrics.Type
+	onClose func()
+
+	strmMut    sync.Mutex
+	strm       *stream.Type
+	generation int
+
+	watcher    *watch.File
+	closeChan  chan struct{}
+	closedChan chan struct{}
+}
+
+// newReloadingStream creates a stream from a config and begins watching the
+// file at path for changes. The onClose closure is called if the running stream
+// closes by itself, but not when a stream is stopped in order to be replaced.
+func newReloadingStream(
+	path string,
+	conf Config,
+	manager types.Manager,
+	logger log.Modular,
+	stats metrics.Type,
+	onClose func(),
+) (*reloadingStream, error) {
+	r := &reloadingStream{
+		path:       path,
+		conf:       conf,
+		timeout:    time.Millisecond * time.Duration(conf.SystemCloseTimeoutMS),
+		manager:    manager,
+		logger:     logger,
+		stats:      stats,
+		onClose:    onClose,
+		closeChan:  make(chan struct{}),
+		closedChan: make(chan struct{}),
+	}
+
+	var err error
+	if r.strm, err = r.newStream(conf.Config); err != nil {
+		return nil, err
+	}
+
+	r.watcher = watch.NewFile(path)
+	go r.loop()
+	return r, nil
+}
+
+//------------------------------------------------------------------------------
+
+// newStream creates a stream from a config. Must be called with strmMut held
+// or before the watcher is started.
+func (r *reloadingStream) newStream(conf stream.Config) (*stream.Type, error) {
+	r.generation++
+	gen := r.generation
+	return stream.New(
+		conf,
+		stream.OptSetLogger(r.logger),
+		stream.OptSetStats(r.stats),
+		stream.OptSetManager(r.manager),
+		stream.OptOnClose(func() {
+			r.strmMut.Lock()
+			current := gen == r.generation
+			r.strmMut.Unlock()
+			if current {
+				r.onClose()
+			}
+		}),
+	)
+}
+
+// reload reads the config file and, if it is valid and the stream sections
+// have changed, replaces the running stream with one built from it. The new
+// stream is validated with a dry run before the running stream is stopped, and
+// failed reloads leave the previous stream running. If neither the new nor the
+// previous stream can be created after stopping, no stream runs until the
+// config file changes again.
+func (r *reloadingStream) reload() {
+	r.logger.Infof("Config file changed, reloading: %v\n", r.path)
+
+	conf := NewConfig()
+	if err := uconfig.Read(r.path, *swapEnvs, &conf); err != nil {
+		r.logger.Errorf("Failed to read updated config, keeping the current stream: %v\n", err)
+		return
+	}
+	if _, err := conf.Sanitised(); err != nil {
+		r.logger.Errorf("Failed to validate updated config, keeping the current stream: %v\n", err)
+		return
+	}
+	if lints, err := lintFile(r.path); err == nil {
+		for _, l := range lints {
+			r.logger.Warnf("Updated config lint: %v\n", l)
+		}
+	}
+
+	if !reflect.DeepEqual(conf.HTTP, r.conf.HTTP) ||
+		!reflect.DeepEqual(conf.Manager, r.conf.Manager) ||
+		!reflect.DeepEqual(conf.Logger, r.conf.Logger) ||
+		!reflect.DeepEqual(conf.Metrics, r.conf.Metrics) ||
+		conf.SystemCloseTimeoutMS != r.conf.SystemCloseTimeoutMS {
+		r.logger.Warnln(
+			"Changes to the http, resources, logger, metrics and" +
+				" sys_exit_timeout_ms fields are ignored until the service is" +
+				" restarted.",
+		)
+	}
+
+	r.strmMut.Lock()
+	defer r.strmMut.Unlock()
+
+	if r.strm != nil && reflect.DeepEqual(conf.Config, r.conf.Config) {
+		r.logger.Infoln("Stream config is unchanged, skipping reload.")
+		return
+	}
+
+	// Validate the new stream before touching the running one so that a bad
+	// config never causes downtime.
+	errs := strmmgr.DryRunConfig(conf.Config, r.manager, r.logger, r.timeout)
+	if len(errs) > 0 {
+		for _, err := range errs {
+			r.logger.Errorf("Updated stream config is invalid: %v\n", err)
+		}
+		r.logger.Errorln("Keeping the current stream.")
+		return
+	}
+
+	if r.strm != nil {
+		if err := r.strm.Stop(r.timeout); err != nil {
+			r.logger.Errorf("Failed to stop the current stream cleanly: %v\n", err)
+		}
+		r.strm = nil
+	}
+
+	strm, err := r.newStream(conf.Config)
+	if err != nil {
+		r.logger.Errorf("Failed to create stream from updated config, restoring the previous stream: %v\n", err)
+		if strm, err = r.newStream(r.conf.Config); err != nil {
+			r.logger.Errorf("Failed to restore the previous stream, waiting for the config to change: %v\n", err)
+			return
+		}
+		r.strm = strm
+		return
+	}
+
+	r.strm = strm
+	r.conf.Config = conf.Config
+	r.logger.Infoln("Stream reloaded successfully.")
+}
+
+func (r *reloadingStream) loop() {
+	defer close(r.closedChan)
+	for {
+		select {
+		case <-r.watcher.ChangeChan():
+			r.reload()
+		case <-r.closeChan:
+			return
+		}
+	}
+}
+
+// Stop stops watching the config file and then stops the running stream.
+func (r *reloadingStream) Stop(timeout time.Duration) error {
+	r.watcher.CloseAsync()
+	close(r.closeChan)
+	<-r.closedChan
+
+	r.strmMut.Lock()
+	defer r.strmMut.Unlock()
+	if r.strm == nil {
+		return nil
+	}
+	return r.strm.Stop(timeout)
+}
+
+//------------------------------------------------------------------------------
@@ -43,6 +43,11 @@ type TypeSpec struct {
 	description string
 }
 
+// Description returns the documentation of the buffer type.
+func (t TypeSpec) Description() string {
+	return t.description
+}
+
 // Constructors is a map of all buffer types with their specs.
 var Constructors = map[string]TypeSpec{}
 
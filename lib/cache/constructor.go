@@ -41,6 +41,11 @@ type TypeSpec struct {
 	description string
 }
 
+// Description returns the documentation of the cache type.
+func (t TypeSpec) Description() string {
+	return t.description
+}
+
 // Constructors is a map of all cache types with their specs.
 var Constructors = map[string]TypeSpec{}
 
@@ -48,8 +53,10 @@ var Constructors = map[string]TypeSpec{}
 
 // String constants representing each cache type.
 const (
+	TypeFile      = "file"
 	TypeMemcached = "memcached"
 	TypeMemory    = "memory"
+	TypeRedis     = "redis"
 )
 
 //------------------------------------------------------------------------------
@@ -57,16 +64,20 @@ const (
 // Config is the all encompassing configuration struct for all cache types.
 type Config struct {
 	Type      string          `json:"type" yaml:"type"`
+	File      FileConfig      `json:"file" yaml:"file"`
 	Memcached MemcachedConfig `json:"memcached" yaml:"memcached"`
 	Memory    MemoryConfig    `json:"memory" yaml:"memory"`
+	Redis     RedisConfig     `json:"redis" yaml:"redis"`
 }
 
 // NewConfig returns a configuration struct fully populated with default values.
 func NewConfig() Config {
 	return Config{
 		Type:      "memory",
+		File:      NewFileConfig(),
 		Memcached: NewMemcachedConfig(),
 		Memory:    NewMemoryConfig(),
+		Redis:     NewRedisConfig(),
 	}
 }
 
@@ -0,0 +1,337 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cache
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeFile] = TypeSpec{
+		constructor: NewFile,
+		description: `
+The file cache stores key/value pairs in memory and persists them to a log file
+within a local directory, allowing the contents of the cache to survive service
+restarts. Each item in the cache has a TTL set from the moment it was last
+edited, after which it is no longer returned and will be removed during the next
+compaction.
+
+Every write is appended to the log, and a compaction rewrites the log with only
+the items that have not expired. A compaction occurs when the cache is opened
+and during a write where the time since the last compaction is above the
+compaction interval.
+
+The directory is created if it does not already exist, and must not be shared
+with other file caches.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// FileConfig contains config fields for the File cache type.
+type FileConfig struct {
+	Directory           string `json:"directory" yaml:"directory"`
+	TTL                 int    `json:"ttl" yaml:"ttl"`
+	CompactionIntervalS int    `json:"compaction_interval_s" yaml:"compaction_interval_s"`
+}
+
+// NewFileConfig creates a FileConfig populated with default values.
+func NewFileConfig() FileConfig {
+	return FileConfig{
+		Directory:           "",
+		TTL:                 300, // 5 Mins
+		CompactionIntervalS: 60,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+const (
+	fileLogName    = "cache.log"
+	fileRecordSet  = byte('s')
+	fileRecordDel  = byte('d')
+	fileHeaderSize = 1 + 8 + 4 + 4
+)
+
+// File is a cache implementation that persists items to a log file.
+type File struct {
+	items          map[string]item
+	ttl            time.Duration
+	compInterval   time.Duration
+	lastCompaction time.Time
+
+	path string
+	file *os.File
+
+	log   log.Modular
+	stats metrics.Type
+
+	mCompaction metrics.StatCounter
+	mErr        metrics.StatCounter
+
+	sync.RWMutex
+}
+
+// NewFile creates a new File cache type.
+func NewFile(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (types.Cache, error) {
+	if len(conf.File.Directory) == 0 {
+		return nil, errors.New("a directory must be specified")
+	}
+	if err := os.MkdirAll(conf.File.Directory, 0755); err != nil {
+		return nil, err
+	}
+
+	f := &File{
+		items:        map[string]item{},
+		ttl:          time.Second * time.Duration(conf.File.TTL),
+		compInterval: time.Second * time.Duration(conf.File.CompactionIntervalS),
+		path:         filepath.Join(conf.File.Directory, fileLogName),
+		log:          log.NewModule(".cache.file"),
+		stats:        stats,
+
+		mCompaction: stats.GetCounter("cache.file.compaction"),
+		mErr:        stats.GetCounter("cache.file.error"),
+	}
+
+	if err := f.replay(); err != nil {
+		return nil, err
+	}
+	if err := f.compact(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (f *File) expired(i item) bool {
+	return time.Since(i.ts) >= f.ttl
+}
+
+// replay reads the log file into memory, truncating any trailing record that
+// was only partially written.
+func (f *File) replay() error {
+	file, err := os.Open(f.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	remaining := info.Size()
+
+	r := bufio.NewReader(file)
+	header := make([]byte, fileHeaderSize)
+	for {
+		if _, err = io.ReadFull(r, header); err != nil {
+			break
+		}
+		remaining -= fileHeaderSize
+		ts := time.Unix(0, int64(binary.BigEndian.Uint64(header[1:9])))
+		keyLen := binary.BigEndian.Uint32(header[9:13])
+		valueLen := binary.BigEndian.Uint32(header[13:17])
+
+		// Lengths are checked against the rest of the file before allocating
+		// so that a corrupted header is treated as a torn record.
+		bodyLen := int64(keyLen) + int64(valueLen)
+		if bodyLen > remaining {
+			err = errors.New("record length exceeds the remaining size of the log")
+			break
+		}
+		remaining -= bodyLen
+
+		body := make([]byte, bodyLen)
+		if _, err = io.ReadFull(r, body); err != nil {
+			break
+		}
+		key := string(body[:keyLen])
+
+		switch header[0] {
+		case fileRecordSet:
+			f.items[key] = item{value: body[keyLen:], ts: ts}
+		case fileRecordDel:
+			delete(f.items, key)
+		default:
+			err = errors.New("unrecognised record type")
+		}
+		if err != nil {
+			break
+		}
+	}
+	if err != io.EOF {
+		f.log.Warnf("Discarding corrupted tail of cache log: %v\n", err)
+	}
+	return nil
+}
+
+// compact rewrites the log file with only the items that have not expired, and
+// opens the new file for appending.
+func (f *File) compact() error {
+	for k, v := range f.items {
+		if f.expired(v) {
+			delete(f.items, k)
+		}
+	}
+
+	tmpPath := f.path + ".tmp"
+	tmpFile, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(tmpFile)
+	for k, v := range f.items {
+		if err = writeFileRecord(w, fileRecordSet, k, v); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, f.path)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if f.file != nil {
+		f.file.Close()
+	}
+	if f.file, err = os.OpenFile(f.path, os.O_APPEND|os.O_WRONLY, 0644); err != nil {
+		return err
+	}
+
+	f.lastCompaction = time.Now()
+	f.mCompaction.Incr(1)
+	return nil
+}
+
+func (f *File) compaction() {
+	if time.Since(f.lastCompaction) < f.compInterval {
+		return
+	}
+	if err := f.compact(); err != nil {
+		f.mErr.Incr(1)
+		f.log.Errorf("Failed to compact cache log: %v\n", err)
+	}
+}
+
+func writeFileRecord(w io.Writer, op byte, key string, i item) error {
+	b := make([]byte, fileHeaderSize, fileHeaderSize+len(key)+len(i.value))
+	b[0] = op
+	binary.BigEndian.PutUint64(b[1:9], uint64(i.ts.UnixNano()))
+	binary.BigEndian.PutUint32(b[9:13], uint32(len(key)))
+	binary.BigEndian.PutUint32(b[13:17], uint32(len(i.value)))
+	b = append(b, key...)
+	b = append(b, i.value...)
+	_, err := w.Write(b)
+	return err
+}
+
+// write appends a record to the log file and applies it to the items in
+// memory.
+func (f *File) write(op byte, key string, value []byte) error {
+	f.compaction()
+	if f.file == nil {
+		return types.ErrNotConnected
+	}
+
+	i := item{value: value, ts: time.Now()}
+	if err := writeFileRecord(f.file, op, key, i); err != nil {
+		f.mErr.Incr(1)
+		return err
+	}
+	if op == fileRecordDel {
+		delete(f.items, key)
+	} else {
+		f.items[key] = i
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// Get attempts to locate and return a cached value by its key, returns an error
+// if the key does not exist.
+func (f *File) Get(key string) ([]byte, error) {
+	f.RLock()
+	k, exists := f.items[key]
+	f.RUnlock()
+	if !exists || f.expired(k) {
+		return nil, types.ErrKeyNotFound
+	}
+	return k.value, nil
+}
+
+// Set attempts to set the value of a key.
+func (f *File) Set(key string, value []byte) error {
+	f.Lock()
+	err := f.write(fileRecordSet, key, value)
+	f.Unlock()
+	return err
+}
+
+// Add attempts to set the value of a key only if the key does not already exist
+// and returns an error if the key already exists.
+func (f *File) Add(key string, value []byte) error {
+	f.Lock()
+	defer f.Unlock()
+	if k, exists := f.items[key]; exists && !f.expired(k) {
+		return types.ErrKeyAlreadyExists
+	}
+	return f.write(fileRecordSet, key, value)
+}
+
+// Delete attempts to remove a key.
+func (f *File) Delete(key string) error {
+	f.Lock()
+	defer f.Unlock()
+	if _, exists := f.items[key]; !exists {
+		return nil
+	}
+	return f.write(fileRecordDel, key, nil)
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,342 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, expErrRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cache
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func TestFileCache(t *testing.T) {
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	dir, err := ioutil.TempDir("", "benthos_file_cache_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	conf := NewConfig()
+	conf.Type = "file"
+	conf.File.Directory = dir
+
+	c, err := New(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expErr := types.ErrKeyNotFound
+	if _, act := c.Get("foo"); act != expErr {
+		t.Errorf("Wrong error returned: %v != %v", act, expErr)
+	}
+
+	if err = c.Set("foo", []byte("1")); err != nil {
+		t.Error(err)
+	}
+
+	exp := "1"
+	if act, err := c.Get("foo"); err != nil {
+		t.Error(err)
+	} else if string(act) != exp {
+		t.Errorf("Wrong result: %v != %v", string(act), exp)
+	}
+
+	if err = c.Add("bar", []byte("2")); err != nil {
+		t.Error(err)
+	}
+
+	exp = "2"
+	if act, err := c.Get("bar"); err != nil {
+		t.Error(err)
+	} else if string(act) != exp {
+		t.Errorf("Wrong result: %v != %v", string(act), exp)
+	}
+
+	expErr = types.ErrKeyAlreadyExists
+	if act := c.Add("foo", []byte("2")); expErr != act {
+		t.Errorf("Wrong error returned: %v != %v", act, expErr)
+	}
+
+	if err = c.Set("foo", []byte("3")); err != nil {
+		t.Error(err)
+	}
+
+	exp = "3"
+	if act, err := c.Get("foo"); err != nil {
+		t.Error(err)
+	} else if string(act) != exp {
+		t.Errorf("Wrong result: %v != %v", string(act), exp)
+	}
+
+	if err = c.Delete("foo"); err != nil {
+		t.Error(err)
+	}
+
+	expErr = types.ErrKeyNotFound
+	if _, act := c.Get("foo"); act != expErr {
+		t.Errorf("Wrong error returned: %v != %v", act, expErr)
+	}
+}
+
+func TestFileCachePersistence(t *testing.T) {
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	dir, err := ioutil.TempDir("", "benthos_file_cache_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	conf := NewConfig()
+	conf.Type = "file"
+	conf.File.Directory = dir
+
+	c, err := New(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = c.Set("foo", []byte("1")); err != nil {
+		t.Error(err)
+	}
+	if err = c.Set("bar", []byte("2")); err != nil {
+		t.Error(err)
+	}
+	if err = c.Set("foo", []byte("3")); err != nil {
+		t.Error(err)
+	}
+	if err = c.Delete("bar"); err != nil {
+		t.Error(err)
+	}
+	if err = c.Set("baz", []byte("4")); err != nil {
+		t.Error(err)
+	}
+
+	if c, err = New(conf, nil, testLog, metrics.DudType{}); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := "3"
+	if act, err := c.Get("foo"); err != nil {
+		t.Error(err)
+	} else if string(act) != exp {
+		t.Errorf("Wrong result: %v != %v", string(act), exp)
+	}
+
+	expErr := types.ErrKeyNotFound
+	if _, act := c.Get("bar"); act != expErr {
+		t.Errorf("Wrong error returned: %v != %v", act, expErr)
+	}
+
+	exp = "4"
+	if act, err := c.Get("baz"); err != nil {
+		t.Error(err)
+	} else if string(act) != exp {
+		t.Errorf("Wrong result: %v != %v", string(act), exp)
+	}
+
+	expErr = types.ErrKeyAlreadyExists
+	if act := c.Add("baz", []byte("5")); expErr != act {
+		t.Errorf("Wrong error returned: %v != %v", act, expErr)
+	}
+}
+
+func TestFileCacheCorruptedTail(t *testing.T) {
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	dir, err := ioutil.TempDir("", "benthos_file_cache_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	conf := NewConfig()
+	conf.Type = "file"
+	conf.File.Directory = dir
+
+	c, err := New(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = c.Set("foo", []byte("1")); err != nil {
+		t.Error(err)
+	}
+	if err = c.Set("bar", []byte("2")); err != nil {
+		t.Error(err)
+	}
+
+	logPath := filepath.Join(dir, fileLogName)
+	info, err := os.Stat(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Truncate(logPath, info.Size()-1); err != nil {
+		t.Fatal(err)
+	}
+
+	if c, err = New(conf, nil, testLog, metrics.DudType{}); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := "1"
+	if act, err := c.Get("foo"); err != nil {
+		t.Error(err)
+	} else if string(act) != exp {
+		t.Errorf("Wrong result: %v != %v", string(act), exp)
+	}
+
+	expErr := types.ErrKeyNotFound
+	if _, act := c.Get("bar"); act != expErr {
+		t.Errorf("Wrong error returned: %v != %v", act, expErr)
+	}
+
+	if err = c.Set("bar", []byte("3")); err != nil {
+		t.Error(err)
+	}
+
+	if c, err = New(conf, nil, testLog, metrics.DudType{}); err != nil {
+		t.Fatal(err)
+	}
+
+	exp = "3"
+	if act, err := c.Get("bar"); err != nil {
+		t.Error(err)
+	} else if string(act) != exp {
+		t.Errorf("Wrong result: %v != %v", string(act), exp)
+	}
+}
+
+func TestFileCacheCorruptedLengths(t *testing.T) {
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	dir, err := ioutil.TempDir("", "benthos_file_cache_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	conf := NewConfig()
+	conf.Type = "file"
+	conf.File.Directory = dir
+
+	c, err := New(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Set("foo", []byte("1")); err != nil {
+		t.Error(err)
+	}
+
+	// Append a record header claiming lengths far beyond the size of the log.
+	header := make([]byte, fileHeaderSize)
+	header[0] = fileRecordSet
+	for i := 9; i < fileHeaderSize; i++ {
+		header[i] = 0xFF
+	}
+	logFile, err := os.OpenFile(filepath.Join(dir, fileLogName), os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = logFile.Write(append(header, "bar"...)); err != nil {
+		t.Fatal(err)
+	}
+	if err = logFile.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if c, err = New(conf, nil, testLog, metrics.DudType{}); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := "1"
+	if act, err := c.Get("foo"); err != nil {
+		t.Error(err)
+	} else if string(act) != exp {
+		t.Errorf("Wrong result: %v != %v", string(act), exp)
+	}
+	if _, act := c.Get("bar"); act != types.ErrKeyNotFound {
+		t.Errorf("Wrong error returned: %v != %v", act, types.ErrKeyNotFound)
+	}
+}
+
+func TestFileCacheCompaction(t *testing.T) {
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	dir, err := ioutil.TempDir("", "benthos_file_cache_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	conf := NewConfig()
+	conf.Type = "file"
+	conf.File.Directory = dir
+	conf.File.TTL = 0
+	conf.File.CompactionIntervalS = 0
+
+	c, err := New(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = c.Set("foo", []byte("1")); err != nil {
+		t.Error(err)
+	}
+
+	expErr := types.ErrKeyNotFound
+	if _, act := c.Get("foo"); act != expErr {
+		t.Errorf("Wrong error returned: %v != %v", act, expErr)
+	}
+
+	// This write should trigger compaction.
+	if err = c.Set("bar", []byte("2")); err != nil {
+		t.Error(err)
+	}
+
+	if _, exists := c.(*File).items["foo"]; exists {
+		t.Error("Expected expired item to be removed")
+	}
+
+	<-time.After(time.Millisecond)
+	if c, err = New(conf, nil, testLog, metrics.DudType{}); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(filepath.Join(dir, fileLogName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != 0 {
+		t.Errorf("Expected empty log after compaction, size: %v", info.Size())
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,190 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+//------------------------------------------------------------------------------
+
+// lintError is a single linting error found within a config document.
+type lintError struct {
+	line int
+	path string
+	what string
+}
+
+func (l lintError) String() string {
+	if l.line > 0 {
+		return fmt.Sprintf("line %v: %v: %v", l.line, l.path, l.what)
+	}
+	return fmt.Sprintf("%v: %v", l.path, l.what)
+}
+
+// linter walks a generic config document alongside the struct type that it
+// would be parsed into.
+type linter struct {
+	sections map[reflect.Type]typedSection
+	locator  *lineLocator
+	errs     []lintError
+}
+
+func (l *linter) report(path []interface{}, what string) {
+	l.errs = append(l.errs, lintError{
+		line: l.locator.find(path),
+		path: pathString(path),
+		what: what,
+	})
+}
+
+func (l *linter) walk(path []interface{}, raw interface{}, t reflect.Type) {
+	t = unwrapType(t)
+
+	switch t.Kind() {
+	case reflect.Struct:
+		rawMap, ok := raw.(map[interface{}]interface{})
+		if !ok {
+			return
+		}
+
+		fields := map[string]reflect.Type{}
+		for _, f := range structFields(t) {
+			fields[f.name] = f.t
+		}
+
+		section, isTyped := l.sections[t]
+		selectedType := section.defaultType
+		if tStr, ok := rawMap["type"].(string); ok {
+			selectedType = tStr
+		}
+
+		// When the selected type is not recognised we cannot know which of the
+		// type fields are ignored, and so only the type itself is reported.
+		// Ditto types copy the type of the previous config within a broker and
+		// are therefore not reported either.
+		knownType := true
+		if isTyped {
+			_, knownType = section.types[selectedType]
+			if !knownType && !strings.HasPrefix(selectedType, "ditto") {
+				l.report(
+					append(append([]interface{}{}, path...), "type"),
+					fmt.Sprintf("type '%v' not recognised", selectedType),
+				)
+			}
+		}
+
+		for k, v := range rawMap {
+			key := fmt.Sprintf("%v", k)
+			fieldPath := append(append([]interface{}{}, path...), key)
+
+			fType, exists := fields[key]
+			if !exists {
+				l.report(fieldPath, "field not recognised")
+				continue
+			}
+			if isTyped && knownType && key != selectedType {
+				if _, isType := section.types[key]; isType {
+					l.report(fieldPath, fmt.Sprintf(
+						"field is ignored by the selected type '%v'", selectedType,
+					))
+					continue
+				}
+			}
+			l.walk(fieldPath, v, fType)
+		}
+	case reflect.Slice, reflect.Array:
+		rawSlice, ok := raw.([]interface{})
+		if !ok {
+			return
+		}
+		for i, v := range rawSlice {
+			l.walk(append(append([]interface{}{}, path...), i), v, t.Elem())
+		}
+	case reflect.Map:
+		rawMap, ok := raw.(map[interface{}]interface{})
+		if !ok {
+			return
+		}
+		for k, v := range rawMap {
+			l.walk(append(append([]interface{}{}, path...), fmt.Sprintf("%v", k)), v, t.Elem())
+		}
+	}
+}
+
+func pathString(path []interface{}) string {
+	var buf bytes.Buffer
+	for _, p := range path {
+		if i, ok := p.(int); ok {
+			fmt.Fprintf(&buf, "[%v]", i)
+			continue
+		}
+		if buf.Len() > 0 {
+			buf.WriteByte('.')
+		}
+		fmt.Fprintf(&buf, "%v", p)
+	}
+	return buf.String()
+}
+
+//------------------------------------------------------------------------------
+
+// Lint walks a raw YAML or JSON config document against the structure of a
+// config type and returns a message for each field that is not recognised, for
+// each component type that is not recognised, and for each field that belongs
+// to a component type other than the one selected.
+// Messages are prefixed with the line number of the field where it can be
+// determined.
+//
+// An error is returned if the document could not be parsed.
+func Lint(rawBytes []byte, conf interface{}) ([]string, error) {
+	var raw interface{}
+	if err := yaml.Unmarshal(rawBytes, &raw); err != nil {
+		return nil, err
+	}
+
+	l := linter{
+		sections: getTypedSections(),
+		locator:  newLineLocator(rawBytes),
+	}
+	l.walk(nil, raw, reflect.TypeOf(conf))
+
+	sort.Slice(l.errs, func(i, j int) bool {
+		if l.errs[i].line == l.errs[j].line {
+			return l.errs[i].path < l.errs[j].path
+		}
+		return l.errs[i].line < l.errs[j].line
+	})
+
+	lints := make([]string, len(l.errs))
+	for i, e := range l.errs {
+		lints[i] = e.String()
+	}
+	return lints, nil
+}
+
+//------------------------------------------------------------------------------
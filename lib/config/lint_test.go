@@ -0,0 +1,233 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/manager"
+	"github.com/Jeffail/benthos/lib/stream"
+)
+
+//------------------------------------------------------------------------------
+
+type testConfig struct {
+	stream.Config `json:",inline" yaml:",inline"`
+	Manager       manager.Config `json:"resources" yaml:"resources"`
+}
+
+func TestLintYAML(t *testing.T) {
+	conf := `
+input:
+  type: kafka
+  kafak:
+    topic: foo
+  stdin:
+    delimiter: ""
+  processors:
+  - type: jmespath
+    jmespath:
+      query: foo
+  - type: text
+    text:
+      operator: trim
+    jmespath:
+      query: bar
+buffer:
+  type: memory
+output:
+  type: stdout
+  stdout:
+    delimiter: ""
+    nope: true
+resources:
+  caches:
+    foo:
+      type: memory
+      memcached:
+        prefix: foo
+  conditions:
+    bar:
+      type: text
+      text:
+        arg: baz
+        argg: baz
+`
+
+	exp := []string{
+		"line 4: input.kafak: field not recognised",
+		"line 6: input.stdin: field is ignored by the selected type 'kafka'",
+		"line 15: input.processors[1].jmespath: field is ignored by the selected type 'text'",
+		"line 23: output.stdout.nope: field not recognised",
+		"line 28: resources.caches.foo.memcached: field is ignored by the selected type 'memory'",
+		"line 35: resources.conditions.bar.text.argg: field not recognised",
+	}
+
+	act, err := Lint([]byte(conf), testConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong lint results: %v != %v", act, exp)
+	}
+}
+
+func TestLintDefaultType(t *testing.T) {
+	conf := `
+input:
+  kafka:
+    topic: foo
+output:
+  stdout:
+    delimiter: ""
+`
+
+	exp := []string{
+		"line 3: input.kafka: field is ignored by the selected type 'stdin'",
+	}
+
+	act, err := Lint([]byte(conf), testConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong lint results: %v != %v", act, exp)
+	}
+}
+
+func TestLintJSON(t *testing.T) {
+	conf := `{
+	"input": {
+		"type": "stdin",
+		"processors": [
+			{
+				"type": "noop"
+			},
+			{
+				"type": "jmespath",
+				"jmespath": {
+					"querry": "foo"
+				}
+			}
+		]
+	},
+	"output": {
+		"type": "stdout",
+		"kafka": {}
+	}
+}`
+
+	exp := []string{
+		"line 11: input.processors[1].jmespath.querry: field not recognised",
+		"line 18: output.kafka: field is ignored by the selected type 'stdout'",
+	}
+
+	act, err := Lint([]byte(conf), testConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong lint results: %v != %v", act, exp)
+	}
+}
+
+func TestLintUnknownType(t *testing.T) {
+	conf := `
+input:
+  type: stdin
+  processors:
+  - type: jsn
+    json:
+      operator: get
+    text:
+      operator: trim
+      nope: true
+output:
+  type: broker
+  broker:
+    outputs:
+    - type: stdout
+    - type: ditto_2
+      stdout:
+        delimiter: ""
+`
+
+	exp := []string{
+		"line 5: input.processors[0].type: type 'jsn' not recognised",
+		"line 10: input.processors[0].text.nope: field not recognised",
+	}
+
+	act, err := Lint([]byte(conf), testConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong lint results: %v != %v", act, exp)
+	}
+}
+
+func TestLintParseError(t *testing.T) {
+	if _, err := Lint([]byte("input: [\n"), testConfig{}); err == nil {
+		t.Error("Expected error")
+	}
+}
+
+func TestLineLocator(t *testing.T) {
+	doc := `
+# a comment
+a:
+  b:
+  - c: 1
+    d: 2
+  - - e: 3
+    - f: 4
+  g: 5
+h:
+  - i: 6
+  -
+    j: 7
+`
+
+	tests := []struct {
+		path []interface{}
+		line int
+	}{
+		{[]interface{}{"a"}, 3},
+		{[]interface{}{"a", "b"}, 4},
+		{[]interface{}{"a", "b", 0}, 5},
+		{[]interface{}{"a", "b", 0, "d"}, 6},
+		{[]interface{}{"a", "b", 1, 1, "f"}, 8},
+		{[]interface{}{"a", "g"}, 9},
+		{[]interface{}{"h", 1, "j"}, 13},
+		{[]interface{}{"h", 2, "j"}, 10},
+		{[]interface{}{"nope"}, 0},
+	}
+
+	l := newLineLocator([]byte(doc))
+	for _, test := range tests {
+		if act := l.find(test.path); act != test.line {
+			t.Errorf("Wrong line for path %v: %v != %v", test.path, act, test.line)
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
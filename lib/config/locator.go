@@ -0,0 +1,196 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package config
+
+import (
+	"bytes"
+	"strings"
+)
+
+//------------------------------------------------------------------------------
+
+// locatorLine is a meaningful line of a config document. Lines beginning with
+// a YAML sequence indicator are split into an indicator line and a line
+// containing the remaining content.
+type locatorLine struct {
+	number  int
+	indent  int
+	content string
+}
+
+// lineLocator attempts to find the line numbers of fields within a YAML or
+// pretty printed JSON document by following indentation.
+type lineLocator struct {
+	lines []locatorLine
+}
+
+func newLineLocator(rawBytes []byte) *lineLocator {
+	l := &lineLocator{}
+	for i, line := range bytes.Split(rawBytes, []byte("\n")) {
+		content := strings.TrimRight(string(line), " \t\r")
+		trimmed := strings.TrimLeft(content, " \t")
+		if len(trimmed) == 0 || trimmed[0] == '#' || trimmed == "---" {
+			continue
+		}
+		indent := len(content) - len(trimmed)
+		for trimmed == "-" || strings.HasPrefix(trimmed, "- ") {
+			l.lines = append(l.lines, locatorLine{
+				number:  i + 1,
+				indent:  indent,
+				content: "-",
+			})
+			rest := strings.TrimLeft(trimmed[1:], " \t")
+			indent += len(trimmed) - len(rest)
+			if trimmed = rest; len(trimmed) == 0 {
+				break
+			}
+		}
+		if len(trimmed) > 0 {
+			l.lines = append(l.lines, locatorLine{
+				number:  i + 1,
+				indent:  indent,
+				content: trimmed,
+			})
+		}
+	}
+	return l
+}
+
+// blockEnd returns the index of the first line after start that is indented at
+// or below the indentation of the line at start. YAML allows the sequence
+// elements of a field to share its indentation, and so these are included in
+// the block of a field.
+func (l *lineLocator) blockEnd(start, end int) int {
+	isField := l.lines[start].content != "-"
+	for i := start + 1; i < end; i++ {
+		if l.lines[i].indent < l.lines[start].indent {
+			return i
+		}
+		if l.lines[i].indent == l.lines[start].indent {
+			if !isField || l.lines[i].content != "-" {
+				return i
+			}
+		}
+	}
+	return end
+}
+
+func isKeyLine(content, key string) bool {
+	for _, prefix := range []string{key, `"` + key + `"`, `'` + key + `'`} {
+		if strings.HasPrefix(content, prefix) {
+			rest := strings.TrimLeft(content[len(prefix):], " ")
+			if strings.HasPrefix(rest, ":") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isBracketLine(content string) bool {
+	switch strings.TrimRight(content, ",") {
+	case "{", "}", "[", "]":
+		return true
+	}
+	return false
+}
+
+func isSequenceElement(content string) bool {
+	if content == "-" {
+		return true
+	}
+	switch content[0] {
+	case '}', ']':
+		return false
+	}
+	return true
+}
+
+// find returns the line number of the field at a path of keys and sequence
+// indexes. If the full path cannot be found the line number of the deepest
+// parent found is returned, or zero if no part of the path is found.
+func (l *lineLocator) find(path []interface{}) int {
+	start, end, number := 0, len(l.lines), 0
+
+	for _, p := range path {
+		if start >= end {
+			break
+		}
+
+		_, isIndex := p.(int)
+
+		// Lines that only open or close a JSON object are ignored when finding
+		// the indentation of fields.
+		indent := -1
+		for i := start; i < end; i++ {
+			if !isIndex && isBracketLine(l.lines[i].content) {
+				continue
+			}
+			if indent < 0 || l.lines[i].indent < indent {
+				indent = l.lines[i].indent
+			}
+		}
+		isYAMLSequence := false
+		for i := start; i < end; i++ {
+			if l.lines[i].indent == indent && l.lines[i].content == "-" {
+				isYAMLSequence = true
+				break
+			}
+		}
+
+		found := -1
+		if isIndex {
+			index, count := p.(int), 0
+			for i := start; i < end && found < 0; i++ {
+				if l.lines[i].indent != indent {
+					continue
+				}
+				if isYAMLSequence && l.lines[i].content != "-" {
+					continue
+				}
+				if !isSequenceElement(l.lines[i].content) {
+					continue
+				}
+				if count == index {
+					found = i
+				}
+				count++
+			}
+		} else {
+			key, _ := p.(string)
+			for i := start; i < end && found < 0; i++ {
+				if l.lines[i].indent == indent && isKeyLine(l.lines[i].content, key) {
+					found = i
+				}
+			}
+		}
+		if found < 0 {
+			break
+		}
+
+		number = l.lines[found].number
+		start, end = found+1, l.blockEnd(found, end)
+	}
+
+	return number
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,188 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+//------------------------------------------------------------------------------
+
+// schemaGenerator builds JSON Schema objects from config structs, placing the
+// schemas of typed config sections within a definitions map.
+type schemaGenerator struct {
+	sections    map[reflect.Type]typedSection
+	definitions map[string]interface{}
+}
+
+// unwrapValue dereferences pointer values, using the zero value of the
+// underlying type when nil, and follows the same embedded structs as
+// unwrapType.
+func unwrapValue(v reflect.Value) reflect.Value {
+	for {
+		for v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v = reflect.Zero(v.Type().Elem())
+			} else {
+				v = v.Elem()
+			}
+		}
+		if unwrapType(v.Type()) == v.Type() {
+			return v
+		}
+		v = v.Field(0)
+	}
+}
+
+func withDefault(schema map[string]interface{}, v reflect.Value) map[string]interface{} {
+	if v.CanInterface() {
+		schema["default"] = v.Interface()
+	}
+	return schema
+}
+
+// define adds the schema of a typed config section to the definitions map if
+// it has not already been added.
+func (g *schemaGenerator) define(section typedSection) {
+	if _, exists := g.definitions[section.name]; exists {
+		return
+	}
+
+	// Reserve the definition first as typed sections can contain themselves.
+	g.definitions[section.name] = nil
+
+	schema := g.structSchema(reflect.ValueOf(section.defaultConf))
+	props := schema["properties"].(map[string]interface{})
+
+	var names []string
+	for name, desc := range section.types {
+		names = append(names, name)
+		if prop, ok := props[name].(map[string]interface{}); ok {
+			prop["description"] = strings.TrimSpace(desc)
+		}
+	}
+	sort.Strings(names)
+
+	props["type"] = map[string]interface{}{
+		"type":        "string",
+		"description": "The type of " + section.name + ".",
+		"enum":        names,
+		"default":     section.defaultType,
+	}
+
+	g.definitions[section.name] = schema
+}
+
+func (g *schemaGenerator) structSchema(v reflect.Value) map[string]interface{} {
+	props := map[string]interface{}{}
+	for _, f := range structFields(v.Type()) {
+		fv := v
+		for _, i := range f.index {
+			fv = unwrapValue(fv).Field(i)
+		}
+		props[f.name] = g.schema(fv)
+	}
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           props,
+		"additionalProperties": false,
+	}
+}
+
+func (g *schemaGenerator) schema(v reflect.Value) map[string]interface{} {
+	v = unwrapValue(v)
+	t := v.Type()
+
+	if section, exists := g.sections[t]; exists {
+		g.define(section)
+		return map[string]interface{}{
+			"$ref": "#/definitions/" + section.name,
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		return g.structSchema(v)
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// Byte slices are used for raw values of any type.
+			return map[string]interface{}{}
+		}
+		items := g.schema(reflect.Zero(t.Elem()))
+		delete(items, "default")
+		schema := map[string]interface{}{
+			"type":  "array",
+			"items": items,
+		}
+		if unwrapType(t.Elem()).Kind() != reflect.Struct && v.Len() > 0 {
+			withDefault(schema, v)
+		}
+		return schema
+	case reflect.Map:
+		values := g.schema(reflect.Zero(t.Elem()))
+		delete(values, "default")
+		schema := map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": values,
+		}
+		if unwrapType(t.Elem()).Kind() != reflect.Struct && v.Len() > 0 {
+			withDefault(schema, v)
+		}
+		return schema
+	case reflect.String:
+		return withDefault(map[string]interface{}{"type": "string"}, v)
+	case reflect.Bool:
+		return withDefault(map[string]interface{}{"type": "boolean"}, v)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return withDefault(map[string]interface{}{"type": "integer"}, v)
+	case reflect.Float32, reflect.Float64:
+		return withDefault(map[string]interface{}{"type": "number"}, v)
+	}
+
+	// Interface values can be anything.
+	return map[string]interface{}{}
+}
+
+//------------------------------------------------------------------------------
+
+// JSONSchema returns a JSON Schema document describing a config type, where
+// conf is a config populated with default values. The fields of each
+// registered component type are documented with the description of that type,
+// and the config of each component is placed within the definitions of the
+// schema.
+func JSONSchema(conf interface{}) ([]byte, error) {
+	g := schemaGenerator{
+		sections:    getTypedSections(),
+		definitions: map[string]interface{}{},
+	}
+
+	schema := g.schema(reflect.ValueOf(conf))
+	schema["$schema"] = "http://json-schema.org/draft-07/schema#"
+	schema["definitions"] = g.definitions
+
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+//------------------------------------------------------------------------------
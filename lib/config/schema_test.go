@@ -0,0 +1,125 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/buffer"
+	"github.com/Jeffail/benthos/lib/input"
+	"github.com/Jeffail/benthos/lib/manager"
+	"github.com/Jeffail/benthos/lib/output"
+	"github.com/Jeffail/benthos/lib/stream"
+)
+
+//------------------------------------------------------------------------------
+
+func getSchemaField(t *testing.T, schema interface{}, path ...string) interface{} {
+	t.Helper()
+	for _, p := range path {
+		obj, ok := schema.(map[string]interface{})
+		if !ok {
+			t.Fatalf("Expected object at %v", p)
+		}
+		if schema, ok = obj[p]; !ok {
+			t.Fatalf("Field %v not found in schema path %v", p, path)
+		}
+	}
+	return schema
+}
+
+func TestJSONSchema(t *testing.T) {
+	conf := testConfig{
+		Config:  stream.NewConfig(),
+		Manager: manager.NewConfig(),
+	}
+
+	schemaBytes, err := JSONSchema(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var schema interface{}
+	if err = json.Unmarshal(schemaBytes, &schema); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path []string
+		exp  interface{}
+	}{
+		{
+			path: []string{"properties", "input", "$ref"},
+			exp:  "#/definitions/input",
+		},
+		{
+			path: []string{"properties", "pipeline", "properties", "processors", "items", "$ref"},
+			exp:  "#/definitions/processor",
+		},
+		{
+			path: []string{"properties", "resources", "properties", "caches", "additionalProperties", "$ref"},
+			exp:  "#/definitions/cache",
+		},
+		{
+			path: []string{"definitions", "input", "properties", "type", "default"},
+			exp:  input.NewConfig().Type,
+		},
+		{
+			path: []string{"definitions", "input", "properties", "kafka", "description"},
+			exp:  strings.TrimSpace(input.Constructors["kafka"].Description()),
+		},
+		{
+			path: []string{"definitions", "input", "properties", "kafka", "properties", "topic", "default"},
+			exp:  input.NewConfig().Kafka.Topic,
+		},
+		{
+			path: []string{"definitions", "buffer", "properties", "memory", "properties", "limit", "default"},
+			exp:  float64(buffer.NewConfig().Memory.Limit),
+		},
+		{
+			path: []string{"definitions", "condition", "properties", "not", "$ref"},
+			exp:  "#/definitions/condition",
+		},
+		{
+			path: []string{"definitions", "condition", "properties", "and", "items", "$ref"},
+			exp:  "#/definitions/condition",
+		},
+	}
+
+	for _, test := range tests {
+		if act := getSchemaField(t, schema, test.path...); !reflect.DeepEqual(act, test.exp) {
+			t.Errorf("Wrong value at %v: %v != %v", test.path, act, test.exp)
+		}
+	}
+
+	enum, ok := getSchemaField(t, schema, "definitions", "output", "properties", "type", "enum").([]interface{})
+	if !ok {
+		t.Fatal("Expected output type enum")
+	}
+	if exp, act := len(output.Constructors), len(enum); exp != act {
+		t.Errorf("Wrong count of output types: %v != %v", act, exp)
+	}
+}
+
+//------------------------------------------------------------------------------
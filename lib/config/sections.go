@@ -0,0 +1,178 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package config
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/Jeffail/benthos/lib/buffer"
+	"github.com/Jeffail/benthos/lib/cache"
+	"github.com/Jeffail/benthos/lib/input"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/output"
+	"github.com/Jeffail/benthos/lib/processor"
+	"github.com/Jeffail/benthos/lib/processor/condition"
+)
+
+//------------------------------------------------------------------------------
+
+// typedSection describes a config struct where a `type` field selects which of
+// its sibling fields are used.
+type typedSection struct {
+	name        string
+	defaultConf interface{}
+	defaultType string
+
+	// types maps the name of each registered type to its description.
+	types map[string]string
+}
+
+// getTypedSections returns the typed config sections of all registered
+// component types keyed by their config struct type.
+func getTypedSections() map[reflect.Type]typedSection {
+	sections := map[reflect.Type]typedSection{}
+
+	add := func(name string, conf interface{}, defaultType string, types map[string]string) {
+		sections[reflect.TypeOf(conf)] = typedSection{
+			name:        name,
+			defaultConf: conf,
+			defaultType: defaultType,
+			types:       types,
+		}
+	}
+
+	types := map[string]string{}
+	for k, v := range input.Constructors {
+		types[k] = v.Description()
+	}
+	add("input", input.NewConfig(), input.NewConfig().Type, types)
+
+	types = map[string]string{}
+	for k, v := range buffer.Constructors {
+		types[k] = v.Description()
+	}
+	add("buffer", buffer.NewConfig(), buffer.NewConfig().Type, types)
+
+	types = map[string]string{}
+	for k, v := range processor.Constructors {
+		types[k] = v.Description()
+	}
+	add("processor", processor.NewConfig(), processor.NewConfig().Type, types)
+
+	types = map[string]string{}
+	for k, v := range condition.Constructors {
+		types[k] = v.Description()
+	}
+	add("condition", condition.NewConfig(), condition.NewConfig().Type, types)
+
+	types = map[string]string{}
+	for k, v := range output.Constructors {
+		types[k] = v.Description()
+	}
+	add("output", output.NewConfig(), output.NewConfig().Type, types)
+
+	types = map[string]string{}
+	for k, v := range cache.Constructors {
+		types[k] = v.Description()
+	}
+	add("cache", cache.NewConfig(), cache.NewConfig().Type, types)
+
+	types = map[string]string{}
+	for k, v := range metrics.Constructors {
+		types[k] = v.Description()
+	}
+	types["none"] = "Disables metrics."
+	add("metrics", metrics.NewConfig(), metrics.NewConfig().Type, types)
+
+	return sections
+}
+
+//------------------------------------------------------------------------------
+
+// configField is a field of a config struct, where index is the index sequence
+// of the field for reflect.Value.FieldByIndex.
+type configField struct {
+	name  string
+	index []int
+	t     reflect.Type
+}
+
+// unwrapType dereferences pointer types and returns the embedded type of
+// structs that do nothing but embed a single config without a tag, such as the
+// config of the not condition.
+func unwrapType(t reflect.Type) reflect.Type {
+	for {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct || t.NumField() != 1 {
+			return t
+		}
+		if f := t.Field(0); !f.Anonymous || len(f.Tag) > 0 {
+			return t
+		}
+		t = t.Field(0).Type
+	}
+}
+
+// structFields returns the fields of a struct type in order of declaration,
+// including the fields of any inlined structs.
+func structFields(t reflect.Type) []configField {
+	var fields []configField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if len(f.PkgPath) > 0 && !f.Anonymous {
+			continue
+		}
+
+		tag := f.Tag.Get("yaml")
+		if tag == "-" {
+			continue
+		}
+		if strings.Contains(tag, ",inline") || (f.Anonymous && len(tag) == 0) {
+			fType := f.Type
+			for fType.Kind() == reflect.Ptr {
+				fType = fType.Elem()
+			}
+			if fType.Kind() == reflect.Struct {
+				for _, inner := range structFields(fType) {
+					inner.index = append([]int{i}, inner.index...)
+					fields = append(fields, inner)
+				}
+			}
+			continue
+		}
+
+		name := strings.Split(tag, ",")[0]
+		if len(name) == 0 {
+			name = strings.ToLower(f.Name)
+		}
+		fields = append(fields, configField{
+			name:  name,
+			index: []int{i},
+			t:     f.Type,
+		})
+	}
+	return fields
+}
+
+//------------------------------------------------------------------------------
@@ -54,6 +54,11 @@ type TypeSpec struct {
 	sanitiseConfigFunc func(conf Config) (interface{}, error)
 }
 
+// Description returns the documentation of the input type.
+func (t TypeSpec) Description() string {
+	return t.description
+}
+
 // Constructors is a map of all input types with their specs.
 var Constructors = map[string]TypeSpec{}
 
@@ -63,6 +68,7 @@ var Constructors = map[string]TypeSpec{}
 const (
 	TypeAMQP          = "amqp"
 	TypeBroker        = "broker"
+	TypeCSV           = "csv"
 	TypeDynamic       = "dynamic"
 	TypeFile          = "file"
 	TypeFiles         = "files"
@@ -95,6 +101,7 @@ type Config struct {
 	Type          string                     `json:"type" yaml:"type"`
 	AMQP          reader.AMQPConfig          `json:"amqp" yaml:"amqp"`
 	Broker        BrokerConfig               `json:"broker" yaml:"broker"`
+	CSV           reader.CSVConfig           `json:"csv" yaml:"csv"`
 	Dynamic       DynamicConfig              `json:"dynamic" yaml:"dynamic"`
 	File          FileConfig                 `json:"file" yaml:"file"`
 	Files         reader.FilesConfig         `json:"files" yaml:"files"`
@@ -125,6 +132,7 @@ func NewConfig() Config {
 		Type:          "stdin",
 		AMQP:          reader.NewAMQPConfig(),
 		Broker:        NewBrokerConfig(),
+		CSV:           reader.NewCSVConfig(),
 		Dynamic:       NewDynamicConfig(),
 		File:          NewFileConfig(),
 		Files:         reader.NewFilesConfig(),
@@ -0,0 +1,87 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package input
+
+import (
+	"github.com/Jeffail/benthos/lib/input/reader"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeCSV] = TypeSpec{
+		constructor: NewCSV,
+		description: `
+Reads CSV files from a path, where each row is consumed as a message payload.
+The path can either point to a single file or a directory, in which case the
+directory will be walked and each file found will be read in turn.
+
+Files are parsed according to [RFC 4180](https://tools.ietf.org/html/rfc4180),
+meaning fields can be quoted in order to contain delimiters, quotes (escaped by
+doubling them) and line breaks. The ` + "`delimiter`" + ` field sets the
+character separating fields, and ` + "`lazy_quotes`" + ` allows quotes to
+appear within unquoted fields and non-doubled quotes within quoted fields.
+
+When ` + "`header_row`" + ` is true the first row of each file is used as the
+keys for the rows that follow, and each row is converted into a JSON object.
+Alternatively, the keys can be set explicitly with ` + "`columns`" + `, which
+is useful for files without a header, in which case ` + "`header_row`" + `
+should be set to false. If ` + "`columns`" + ` is set and ` + "`header_row`" + `
+is true then the header row is discarded. Fields beyond the known columns are
+keyed by their column index. When there are no column names each row is
+converted into a JSON array.
+
+Rows that fail to parse are still sent, but flagged as having failed
+processing, and can therefore be handled with the
+[` + "`catch`" + ` processor](../processors/README.md#catch).
+
+Rows can be read in batches by setting ` + "`batch_size`" + ` greater than one,
+in which case each row is a separate part of the message. Batches never span
+more than one file.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+` + "```" + `
+- path
+` + "```" + `
+
+You can access these metadata fields using
+[function interpolation](../config_interpolation.md#metadata).`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewCSV creates a new CSV input type.
+func NewCSV(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	r, err := reader.NewCSV(conf.CSV)
+	if err != nil {
+		return nil, err
+	}
+	return NewReader("csv", reader.NewPreserver(r), log, stats)
+}
+
+//------------------------------------------------------------------------------
@@ -23,6 +23,7 @@ package input
 import (
 	"io"
 	"os"
+	"time"
 
 	"github.com/Jeffail/benthos/lib/input/reader"
 	"github.com/Jeffail/benthos/lib/log"
@@ -40,7 +41,23 @@ The file type reads input from a file. If multipart is set to false each line
 is read as a separate message. If multipart is set to true each line is read as
 a message part, and an empty line indicates the end of a message.
 
-If the delimiter field is left empty then line feed (\n) is used.`,
+If the delimiter field is left empty then line feed (\n) is used.
+
+### Tail
+
+When ` + "`tail.enabled`" + ` is set to true the input does not stop once the end of the
+file is reached, and instead continues to read data as it is appended, checking
+for new data every ` + "`tail.poll_period_ms`" + ` milliseconds. When the file at
+the path is replaced (detected by a change of inode) or truncated the input
+starts reading the new contents from the beginning, which makes it suitable for
+following log files that are rotated. A replaced file is read to the end before
+moving on to the new file, and a line longer than ` + "`max_buffer`" + ` is skipped.
+
+If ` + "`tail.checkpoint_path`" + ` is set then the position within the file is
+written to that path each time messages are acknowledged, and when the input is
+restarted it resumes from that position unless the file has since been replaced
+or truncated. Data appended to a file that was rotated whilst the input was
+stopped is not read.`,
 	}
 }
 
@@ -48,10 +65,19 @@ If the delimiter field is left empty then line feed (\n) is used.`,
 
 // FileConfig contains configuration values for the File input type.
 type FileConfig struct {
-	Path      string `json:"path" yaml:"path"`
-	Multipart bool   `json:"multipart" yaml:"multipart"`
-	MaxBuffer int    `json:"max_buffer" yaml:"max_buffer"`
-	Delim     string `json:"delimiter" yaml:"delimiter"`
+	Path      string         `json:"path" yaml:"path"`
+	Multipart bool           `json:"multipart" yaml:"multipart"`
+	MaxBuffer int            `json:"max_buffer" yaml:"max_buffer"`
+	Delim     string         `json:"delimiter" yaml:"delimiter"`
+	Tail      FileTailConfig `json:"tail" yaml:"tail"`
+}
+
+// FileTailConfig contains configuration values for following a file as it is
+// written to.
+type FileTailConfig struct {
+	Enabled        bool   `json:"enabled" yaml:"enabled"`
+	PollPeriodMS   int    `json:"poll_period_ms" yaml:"poll_period_ms"`
+	CheckpointPath string `json:"checkpoint_path" yaml:"checkpoint_path"`
 }
 
 // NewFileConfig creates a new FileConfig with default values.
@@ -61,6 +87,11 @@ func NewFileConfig() FileConfig {
 		Multipart: false,
 		MaxBuffer: 1000000,
 		Delim:     "",
+		Tail: FileTailConfig{
+			Enabled:        false,
+			PollPeriodMS:   1000,
+			CheckpointPath: "",
+		},
 	}
 }
 
@@ -68,15 +99,35 @@ func NewFileConfig() FileConfig {
 
 // NewFile creates a new File input type.
 func NewFile(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
-	file, err := os.Open(conf.File.Path)
-	if err != nil {
-		return nil, err
-	}
 	delim := conf.File.Delim
 	if len(delim) == 0 {
 		delim = "\n"
 	}
 
+	if conf.File.Tail.Enabled {
+		rdr, err := reader.NewFileTail(
+			conf.File.Path,
+			reader.OptFileTailSetDelimiter(delim),
+			reader.OptFileTailSetMaxBuffer(conf.File.MaxBuffer),
+			reader.OptFileTailSetMultipart(conf.File.Multipart),
+			reader.OptFileTailSetPollPeriod(time.Duration(conf.File.Tail.PollPeriodMS)*time.Millisecond),
+			reader.OptFileTailSetCheckpointPath(conf.File.Tail.CheckpointPath),
+		)
+		if err != nil {
+			return nil, err
+		}
+		return NewReader(
+			"file",
+			reader.NewPreserver(rdr),
+			log, stats,
+		)
+	}
+
+	file, err := os.Open(conf.File.Path)
+	if err != nil {
+		return nil, err
+	}
+
 	rdr, err := reader.NewLines(
 		func() (io.Reader, error) {
 			// Swap so this only works once since we don't want to read the file
@@ -38,6 +38,32 @@ message payload. The path can either point to a single file (resulting in only a
 single message) or a directory, in which case the directory will be walked and
 each file found will become a message.
 
+### Watching
+
+When ` + "`watch.enabled`" + ` is set to true the input does not stop once every file
+has been read, and instead walks the path again every ` + "`watch.poll_period_ms`" + `
+milliseconds in order to find new files, which makes it suitable for drop
+folder integrations. Files that have already been read are skipped unless they
+have been modified since. When ` + "`watch.pattern`" + ` is set only files with a
+name matching the glob pattern are read, e.g. ` + "`*.json`" + `.
+
+Read files are tracked in memory, and therefore would be read again after a
+restart. In order to avoid this a [cache resource](../caches/README.md) can be
+specified with ` + "`watch.cache`" + `, where the modification time of each file is
+stored under its path once it has been acknowledged, in which case a persistent
+cache such as ` + "`file`" + ` should be used.
+
+Files should be moved into the watched path once fully written (rather than
+written in place) in order to avoid them being read whilst incomplete.
+
+### Acknowledgements
+
+The field ` + "`on_ack`" + ` determines what happens to a file once its message has
+been successfully sent. With ` + "`none`" + ` (the default) the file is left as it
+is, with ` + "`delete`" + ` it is removed, and with ` + "`move`" + ` it is moved to the
+directory ` + "`move_to`" + `, keeping its path relative to the input path. Files
+within ` + "`move_to`" + ` are ignored when watching.
+
 ### Metadata
 
 This input adds the following metadata fields to each message:
@@ -55,7 +81,7 @@ You can access these metadata fields using
 
 // NewFiles creates a new Files input type.
 func NewFiles(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
-	f, err := reader.NewFiles(conf.Files)
+	f, err := reader.NewFiles(conf.Files, mgr)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,183 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/csv"
+)
+
+//------------------------------------------------------------------------------
+
+// CSVConfig contains configuration for the CSV input type.
+type CSVConfig struct {
+	Path       string   `json:"path" yaml:"path"`
+	Delim      string   `json:"delimiter" yaml:"delimiter"`
+	LazyQuotes bool     `json:"lazy_quotes" yaml:"lazy_quotes"`
+	HeaderRow  bool     `json:"header_row" yaml:"header_row"`
+	Columns    []string `json:"columns" yaml:"columns"`
+	BatchSize  int      `json:"batch_size" yaml:"batch_size"`
+}
+
+// NewCSVConfig creates a new CSVConfig with default values.
+func NewCSVConfig() CSVConfig {
+	return CSVConfig{
+		Path:       "",
+		Delim:      ",",
+		LazyQuotes: false,
+		HeaderRow:  true,
+		Columns:    []string{},
+		BatchSize:  1,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// CSV is an input type that reads the rows of CSV files at a path as JSON
+// documents.
+type CSV struct {
+	conf  CSVConfig
+	delim rune
+
+	targets []string
+
+	path    string
+	file    *os.File
+	scanner *csv.Reader
+}
+
+// NewCSV creates a new CSV input type.
+func NewCSV(conf CSVConfig) (*CSV, error) {
+	delim, err := csv.ParseDelimiter(conf.Delim)
+	if err != nil {
+		return nil, err
+	}
+
+	if conf.BatchSize < 1 {
+		return nil, errors.New("batch_size must be greater than zero")
+	}
+
+	targets, err := walkFilePaths(conf.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CSV{
+		conf:    conf,
+		delim:   delim,
+		targets: targets,
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (r *CSV) closeFile() {
+	if r.file != nil {
+		r.file.Close()
+		r.file = nil
+	}
+	r.scanner = nil
+}
+
+// Connect opens the next CSV file to be read, returning types.ErrTypeClosed
+// once all files have been consumed.
+func (r *CSV) Connect() error {
+	for r.scanner == nil {
+		if len(r.targets) == 0 {
+			return types.ErrTypeClosed
+		}
+
+		path := r.targets[0]
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		r.targets = r.targets[1:]
+
+		r.path = path
+		r.file = file
+		if r.scanner, err = csv.NewReader(
+			file, r.delim, r.conf.LazyQuotes, r.conf.HeaderRow, r.conf.Columns,
+		); err != nil {
+			r.closeFile()
+			if err == io.EOF {
+				// Empty file, move onto the next.
+				continue
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// Read attempts to read a batch of rows from the current CSV file. Rows that
+// fail to parse are flagged as having failed processing.
+func (r *CSV) Read() (types.Message, error) {
+	if r.scanner == nil {
+		return nil, types.ErrNotConnected
+	}
+
+	msg := message.New(nil)
+	msg.SetMetadata("path", r.path)
+
+	rows, err := r.scanner.ReadRows(r.conf.BatchSize)
+	if err == io.EOF {
+		r.closeFile()
+	} else if err != nil {
+		r.closeFile()
+		return nil, err
+	}
+	for _, row := range rows {
+		index := msg.Append(row.Doc)
+		if row.Err != nil {
+			msg.SetError(index, row.Err)
+		}
+	}
+
+	if msg.Len() == 0 {
+		return nil, types.ErrNotConnected
+	}
+	return msg, nil
+}
+
+// Acknowledge instructs whether unacknowledged messages have been successfully
+// propagated.
+func (r *CSV) Acknowledge(err error) error {
+	return nil
+}
+
+// CloseAsync shuts down the CSV input and stops processing requests.
+func (r *CSV) CloseAsync() {
+}
+
+// WaitForClose blocks until the CSV input has closed down.
+func (r *CSV) WaitForClose(timeout time.Duration) error {
+	r.closeFile()
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,233 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func TestCSVHeaderRow(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "benthos_csv_input_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if err = ioutil.WriteFile(
+		filepath.Join(tmpDir, "a.csv"),
+		[]byte("id,name\n1,foo\n2,\"bar, \"\"baz\"\"\"\n3,\"multi\nline\"\n"),
+		0644,
+	); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(filepath.Join(tmpDir, "b.csv"), []byte{}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(
+		filepath.Join(tmpDir, "c.csv"),
+		[]byte("id,name\n4,qux\n"),
+		0644,
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	conf := NewCSVConfig()
+	conf.Path = tmpDir
+
+	r, err := NewCSV(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		r.CloseAsync()
+		if err := r.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	exp := []string{
+		`{"id":"1","name":"foo"}`,
+		`{"id":"2","name":"bar, \"baz\""}`,
+		`{"id":"3","name":"multi\nline"}`,
+		`{"id":"4","name":"qux"}`,
+	}
+	act := []string{}
+
+	for {
+		if err = r.Connect(); err != nil {
+			break
+		}
+		var msg types.Message
+		for {
+			if msg, err = r.Read(); err != nil {
+				break
+			}
+			act = append(act, string(msg.Get(0)))
+			if msg.GetError(0) != nil {
+				t.Errorf("Unexpected row error: %v", msg.GetError(0))
+			}
+			if exp, act := tmpDir, filepath.Dir(msg.GetMetadata("path")); exp != act {
+				t.Errorf("Wrong path metadata: %v != %v", act, exp)
+			}
+		}
+		if err != types.ErrNotConnected {
+			t.Fatal(err)
+		}
+	}
+	if err != types.ErrTypeClosed {
+		t.Errorf("Wrong error: %v", err)
+	}
+
+	if !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+}
+
+func TestCSVNoHeaderBatched(t *testing.T) {
+	tmpFile, err := ioutil.TempFile("", "benthos_csv_input_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpFile.Name())
+	if _, err = tmpFile.Write([]byte("a|b\nc|\"d\ne|f\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err = tmpFile.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	conf := NewCSVConfig()
+	conf.Path = tmpFile.Name()
+	conf.Delim = "|"
+	conf.HeaderRow = false
+	conf.BatchSize = 2
+
+	r, err := NewCSV(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = r.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := 2, msg.Len(); exp != act {
+		t.Fatalf("Wrong batch size: %v != %v", act, exp)
+	}
+	if exp, act := `["a","b"]`, string(msg.Get(0)); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+	if msg.GetError(0) != nil {
+		t.Errorf("Unexpected row error: %v", msg.GetError(0))
+	}
+	if msg.GetError(1) == nil {
+		t.Error("Expected parse error to be flagged on row")
+	}
+
+	if _, err = r.Read(); err != types.ErrNotConnected {
+		t.Errorf("Wrong error: %v", err)
+	}
+	if err = r.Connect(); err != types.ErrTypeClosed {
+		t.Errorf("Wrong error: %v", err)
+	}
+}
+
+func TestCSVColumns(t *testing.T) {
+	tmpFile, err := ioutil.TempFile("", "benthos_csv_input_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpFile.Name())
+	if _, err = tmpFile.Write([]byte("id,name\n1,foo\n2,bar,baz\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err = tmpFile.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	conf := NewCSVConfig()
+	conf.Path = tmpFile.Name()
+	conf.Columns = []string{"a", "b"}
+	conf.BatchSize = 10
+
+	r, err := NewCSV(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = r.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := [][]byte{
+		[]byte(`{"a":"1","b":"foo"}`),
+		[]byte(`{"2":"baz","a":"2","b":"bar"}`),
+	}
+	if act := msg.GetAll(); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+	if msg.GetError(1) == nil {
+		t.Error("Expected field count error to be flagged on row")
+	}
+
+	if _, err = r.Read(); err != types.ErrNotConnected {
+		t.Errorf("Wrong error: %v", err)
+	}
+}
+
+func TestCSVBadConfig(t *testing.T) {
+	conf := NewCSVConfig()
+	conf.Path = "fdgdfkte34%#@$%#$%KL@#K$@:L#$23k;32l;23"
+	if _, err := NewCSV(conf); err == nil {
+		t.Error("Expected error from bad path")
+	}
+
+	conf = NewCSVConfig()
+	conf.Path = os.TempDir()
+	conf.Delim = "ab"
+	if _, err := NewCSV(conf); err == nil {
+		t.Error("Expected error from bad delimiter")
+	}
+
+	conf = NewCSVConfig()
+	conf.Path = os.TempDir()
+	conf.BatchSize = 0
+	if _, err := NewCSV(conf); err == nil {
+		t.Error("Expected error from bad batch size")
+	}
+}
+
+//------------------------------------------------------------------------------
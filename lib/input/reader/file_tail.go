@@ -0,0 +1,385 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// fileTailState is the position of a FileTail within a file, which is
+// persisted to a checkpoint file once the messages before it are acknowledged.
+type fileTailState struct {
+	Inode  uint64 `json:"inode"`
+	Offset int64  `json:"offset"`
+}
+
+// FileTail is a reader implementation that continuously reads line delimited
+// messages appended to a file, following the path across rotations and
+// truncations.
+type FileTail struct {
+	path           string
+	checkpointPath string
+	pollPeriod     time.Duration
+
+	maxBuffer int
+	multipart bool
+	delimiter []byte
+
+	file       *os.File
+	inode      uint64
+	fileOffset int64
+	buf        []byte
+	readBuf    []byte
+	skipping   bool
+	draining   bool
+
+	// parts holds the parts of a multipart message that were read before an
+	// error interrupted it, as the file has already moved past them.
+	parts types.Message
+
+	pending fileTailState
+	acked   fileTailState
+
+	closeOnce sync.Once
+	closeChan chan struct{}
+}
+
+// NewFileTail creates a new FileTail reader for a file path. If a checkpoint
+// path has been set then the previously acknowledged position is loaded from
+// it, allowing the reader to resume from where it left off.
+func NewFileTail(path string, options ...func(r *FileTail)) (*FileTail, error) {
+	r := FileTail{
+		path:       path,
+		pollPeriod: time.Second,
+		maxBuffer:  bufio.MaxScanTokenSize,
+		multipart:  false,
+		delimiter:  []byte("\n"),
+		readBuf:    make([]byte, 32*1024),
+		closeChan:  make(chan struct{}),
+	}
+
+	for _, opt := range options {
+		opt(&r)
+	}
+
+	if len(r.checkpointPath) > 0 {
+		stateBytes, err := ioutil.ReadFile(r.checkpointPath)
+		if err == nil {
+			if err = json.Unmarshal(stateBytes, &r.acked); err != nil {
+				return nil, err
+			}
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+		r.pending = r.acked
+	}
+
+	return &r, nil
+}
+
+//------------------------------------------------------------------------------
+
+// OptFileTailSetMaxBuffer is a option func that sets the maximum size of a
+// single line.
+func OptFileTailSetMaxBuffer(maxBuffer int) func(r *FileTail) {
+	return func(r *FileTail) {
+		r.maxBuffer = maxBuffer
+	}
+}
+
+// OptFileTailSetMultipart is a option func that sets the boolean flag
+// indicating whether lines should be parsed as multipart or not.
+func OptFileTailSetMultipart(multipart bool) func(r *FileTail) {
+	return func(r *FileTail) {
+		r.multipart = multipart
+	}
+}
+
+// OptFileTailSetDelimiter is a option func that sets the delimiter (default
+// '\n') used to divide lines (message parts) in the file.
+func OptFileTailSetDelimiter(delimiter string) func(r *FileTail) {
+	return func(r *FileTail) {
+		r.delimiter = []byte(delimiter)
+	}
+}
+
+// OptFileTailSetPollPeriod is a option func that sets the period to wait
+// before checking a file for new data once the end has been reached.
+func OptFileTailSetPollPeriod(period time.Duration) func(r *FileTail) {
+	return func(r *FileTail) {
+		r.pollPeriod = period
+	}
+}
+
+// OptFileTailSetCheckpointPath is a option func that sets a path where the
+// position of the last acknowledged message is stored.
+func OptFileTailSetCheckpointPath(path string) func(r *FileTail) {
+	return func(r *FileTail) {
+		r.checkpointPath = path
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func (r *FileTail) closeFile() {
+	if r.file != nil {
+		r.file.Close()
+		r.file = nil
+	}
+	r.buf = nil
+	r.skipping = false
+	r.draining = false
+}
+
+// open opens the file at our path, seeking to the offset given when the file
+// matches the inode and is at least as large as the offset, and otherwise
+// starting at the beginning.
+func (r *FileTail) open(state fileTailState) error {
+	file, err := os.Open(r.path)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	offset := int64(0)
+	inode := fileInode(info)
+	if inode == state.Inode && state.Offset <= info.Size() {
+		if offset, err = file.Seek(state.Offset, io.SeekStart); err != nil {
+			file.Close()
+			return err
+		}
+	}
+
+	r.closeFile()
+	r.file = file
+	r.inode = inode
+	r.fileOffset = offset
+	return nil
+}
+
+// Connect opens the file being tailed, resuming from the last acknowledged
+// position when it is still valid.
+func (r *FileTail) Connect() error {
+	select {
+	case <-r.closeChan:
+		return types.ErrTypeClosed
+	default:
+	}
+	if r.file != nil {
+		return nil
+	}
+	if err := r.open(r.acked); err != nil {
+		return err
+	}
+	r.pending = fileTailState{
+		Inode:  r.inode,
+		Offset: r.fileOffset,
+	}
+	return nil
+}
+
+// checkFile compares the file at our path with the file being read, and
+// returns whether the file has been replaced (rotated) or truncated.
+func (r *FileTail) checkFile() (rotated, truncated bool) {
+	info, err := os.Stat(r.path)
+	if err != nil {
+		// The file might be mid-rotation, in which case we try again later.
+		return false, false
+	}
+	if fileInode(info) != r.inode {
+		return true, false
+	}
+	return false, info.Size() < r.fileOffset
+}
+
+// nextLine blocks until a full line has been read from the file, or the reader
+// is closed. A line that exceeds the max buffer results in bufio.ErrTooLong,
+// after which the rest of that line is discarded.
+func (r *FileTail) nextLine() ([]byte, error) {
+	for {
+		if r.skipping {
+			if i := bytes.Index(r.buf, r.delimiter); i >= 0 {
+				r.buf = r.buf[i+len(r.delimiter):]
+				r.skipping = false
+				continue
+			}
+			// Keep enough to match a delimiter split across reads.
+			if keep := len(r.delimiter) - 1; len(r.buf) > keep {
+				r.buf = append(r.buf[:0], r.buf[len(r.buf)-keep:]...)
+			}
+		} else if i := bytes.Index(r.buf, r.delimiter); i >= 0 {
+			line := make([]byte, i)
+			copy(line, r.buf)
+			r.buf = r.buf[i+len(r.delimiter):]
+			return line, nil
+		} else if len(r.buf) >= r.maxBuffer {
+			r.skipping = true
+			return nil, bufio.ErrTooLong
+		}
+
+		n, err := r.file.Read(r.readBuf)
+		if n > 0 {
+			r.buf = append(r.buf, r.readBuf[:n]...)
+			r.fileOffset += int64(n)
+			continue
+		}
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+
+		rotated, truncated := r.checkFile()
+		if rotated && !r.draining {
+			// Data might have been written to the old file after we reached
+			// its end and before it was rotated, so we read it to the end
+			// once more before switching to the new file.
+			r.draining = true
+			continue
+		}
+		if rotated {
+			// The old file will not be written to again, so any remaining
+			// data is a final line without a delimiter.
+			remaining, skipped := r.buf, r.skipping
+			if err = r.open(fileTailState{}); err != nil {
+				return nil, err
+			}
+			if len(remaining) > 0 && !skipped {
+				return remaining, nil
+			}
+			continue
+		}
+		if truncated {
+			if _, err = r.file.Seek(0, io.SeekStart); err != nil {
+				return nil, err
+			}
+			r.fileOffset = 0
+			r.buf = nil
+			r.skipping = false
+			continue
+		}
+
+		select {
+		case <-time.After(r.pollPeriod):
+		case <-r.closeChan:
+			return nil, types.ErrTypeClosed
+		}
+	}
+}
+
+// Read attempts to read a new message from the file, blocking until one is
+// available.
+func (r *FileTail) Read() (types.Message, error) {
+	if r.file == nil {
+		return nil, types.ErrNotConnected
+	}
+
+	msg := r.parts
+	if msg == nil {
+		msg = message.New(nil)
+	}
+	r.parts = nil
+	for {
+		line, err := r.nextLine()
+		if err != nil {
+			if err != types.ErrTypeClosed && err != bufio.ErrTooLong {
+				// Reconnecting resumes from the last acknowledged position,
+				// where the parts read so far are read again.
+				r.closeFile()
+				return nil, types.ErrNotConnected
+			}
+			if msg.Len() > 0 {
+				r.parts = msg
+			}
+			return nil, err
+		}
+
+		if len(line) > 0 {
+			msg.Append(line)
+			if !r.multipart {
+				break
+			}
+		} else if r.multipart && msg.Len() > 0 {
+			// Empty line means we're finished reading parts for this
+			// message.
+			break
+		}
+	}
+
+	r.pending = fileTailState{
+		Inode:  r.inode,
+		Offset: r.fileOffset - int64(len(r.buf)),
+	}
+	return msg, nil
+}
+
+// Acknowledge confirms whether or not our unacknowledged messages have been
+// successfully propagated or not. Once propagated the position after the
+// messages is written to the checkpoint file.
+func (r *FileTail) Acknowledge(err error) error {
+	if err != nil || r.pending == r.acked {
+		return nil
+	}
+	if len(r.checkpointPath) > 0 {
+		stateBytes, err := json.Marshal(r.pending)
+		if err != nil {
+			return err
+		}
+		tmpPath := r.checkpointPath + ".tmp"
+		if err = ioutil.WriteFile(tmpPath, stateBytes, 0644); err != nil {
+			return err
+		}
+		if err = os.Rename(tmpPath, r.checkpointPath); err != nil {
+			return err
+		}
+	}
+	r.acked = r.pending
+	return nil
+}
+
+// CloseAsync shuts down the reader input and stops processing requests.
+func (r *FileTail) CloseAsync() {
+	r.closeOnce.Do(func() {
+		close(r.closeChan)
+	})
+}
+
+// WaitForClose blocks until the reader input has closed down.
+func (r *FileTail) WaitForClose(timeout time.Duration) error {
+	r.closeFile()
+	return nil
+}
+
+//------------------------------------------------------------------------------
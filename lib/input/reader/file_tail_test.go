@@ -0,0 +1,419 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func appendTailFile(t *testing.T, path, data string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = f.WriteString(data); err != nil {
+		t.Fatal(err)
+	}
+	if err = f.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type tailResult struct {
+	msg types.Message
+	err error
+}
+
+func readTail(r *FileTail) <-chan tailResult {
+	resChan := make(chan tailResult, 1)
+	go func() {
+		msg, err := r.Read()
+		resChan <- tailResult{msg: msg, err: err}
+	}()
+	return resChan
+}
+
+func expectTailMessage(t *testing.T, r *FileTail, exp ...string) {
+	t.Helper()
+	select {
+	case res := <-readTail(r):
+		if res.err != nil {
+			t.Fatal(res.err)
+		}
+		act := []string{}
+		for _, p := range res.msg.GetAll() {
+			act = append(act, string(p))
+		}
+		if !reflect.DeepEqual(exp, act) {
+			t.Errorf("Wrong message: %q != %q", act, exp)
+		}
+		if err := r.Acknowledge(nil); err != nil {
+			t.Error(err)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatalf("Timed out waiting for message: %q", exp)
+	}
+}
+
+func newTestFileTail(t *testing.T, path string, options ...func(r *FileTail)) *FileTail {
+	t.Helper()
+	options = append([]func(r *FileTail){
+		OptFileTailSetPollPeriod(time.Millisecond * 10),
+	}, options...)
+	r, err := NewFileTail(path, options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = r.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func closeTestFileTail(t *testing.T, r *FileTail) {
+	t.Helper()
+	r.CloseAsync()
+	if err := r.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func TestFileTailAppends(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_file_tail_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.log")
+	appendTailFile(t, path, "foo\n\nbar\n")
+
+	r := newTestFileTail(t, path)
+	defer closeTestFileTail(t, r)
+
+	expectTailMessage(t, r, "foo")
+	expectTailMessage(t, r, "bar")
+
+	resChan := readTail(r)
+	appendTailFile(t, path, "ba")
+	select {
+	case res := <-resChan:
+		t.Fatalf("Unexpected result from partial line: %v", res)
+	case <-time.After(time.Millisecond * 50):
+	}
+	appendTailFile(t, path, "z\n")
+
+	select {
+	case res := <-resChan:
+		if res.err != nil {
+			t.Fatal(res.err)
+		}
+		if act, exp := string(res.msg.Get(0)), "baz"; act != exp {
+			t.Errorf("Wrong message: %v != %v", act, exp)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("Timed out")
+	}
+}
+
+func TestFileTailMultipart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_file_tail_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.log")
+	appendTailFile(t, path, "foo|bar||baz|")
+
+	r := newTestFileTail(
+		t, path,
+		OptFileTailSetMultipart(true),
+		OptFileTailSetDelimiter("|"),
+	)
+	defer closeTestFileTail(t, r)
+
+	expectTailMessage(t, r, "foo", "bar")
+
+	appendTailFile(t, path, "qux||")
+	expectTailMessage(t, r, "baz", "qux")
+}
+
+func TestFileTailTruncated(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_file_tail_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.log")
+	appendTailFile(t, path, "first line\nsecond line\n")
+
+	r := newTestFileTail(t, path)
+	defer closeTestFileTail(t, r)
+
+	expectTailMessage(t, r, "first line")
+	expectTailMessage(t, r, "second line")
+
+	if err = os.Truncate(path, 0); err != nil {
+		t.Fatal(err)
+	}
+	appendTailFile(t, path, "foo\n")
+
+	expectTailMessage(t, r, "foo")
+}
+
+func TestFileTailRotated(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_file_tail_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.log")
+	appendTailFile(t, path, "foo\nbar")
+
+	r := newTestFileTail(t, path)
+	defer closeTestFileTail(t, r)
+
+	expectTailMessage(t, r, "foo")
+
+	if err = os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	appendTailFile(t, path, "baz\n")
+
+	expectTailMessage(t, r, "bar")
+	expectTailMessage(t, r, "baz")
+}
+
+func TestFileTailRotatedLateWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_file_tail_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.log")
+	appendTailFile(t, path, "foo\n")
+
+	// A writer that still holds the old file after it is rotated.
+	oldFile, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer oldFile.Close()
+
+	r := newTestFileTail(t, path)
+	defer closeTestFileTail(t, r)
+
+	expectTailMessage(t, r, "foo")
+
+	resChan := readTail(r)
+	if err = os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	appendTailFile(t, path, "baz\n")
+	if _, err = oldFile.WriteString("bar\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case res := <-resChan:
+		if res.err != nil {
+			t.Fatal(res.err)
+		}
+		if act, exp := string(res.msg.Get(0)), "bar"; act != exp {
+			t.Errorf("Wrong message: %v != %v", act, exp)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("Timed out")
+	}
+	expectTailMessage(t, r, "baz")
+}
+
+func TestFileTailTooLong(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_file_tail_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.log")
+	appendTailFile(t, path, "foo\r\naaaaaaaaaaaa\r\nbar\r\n")
+
+	r := newTestFileTail(
+		t, path,
+		OptFileTailSetMaxBuffer(5),
+		OptFileTailSetDelimiter("\r\n"),
+	)
+	defer closeTestFileTail(t, r)
+
+	// Small reads split the delimiter after the long line.
+	r.readBuf = make([]byte, 3)
+
+	expectTailMessage(t, r, "foo")
+
+	select {
+	case res := <-readTail(r):
+		if res.err != bufio.ErrTooLong {
+			t.Errorf("Wrong error: %v != %v", res.err, bufio.ErrTooLong)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("Timed out")
+	}
+
+	expectTailMessage(t, r, "bar")
+
+	appendTailFile(t, path, "bbbbbbbbbbbb\r\nbaz\r\n")
+
+	select {
+	case res := <-readTail(r):
+		if res.err != bufio.ErrTooLong {
+			t.Errorf("Wrong error: %v != %v", res.err, bufio.ErrTooLong)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("Timed out")
+	}
+
+	expectTailMessage(t, r, "baz")
+}
+
+func TestFileTailTooLongMultipart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_file_tail_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.log")
+	appendTailFile(t, path, "foo\nbar\naaaaaaaaaaaa\nbaz\n\nqux\n\n")
+
+	r := newTestFileTail(
+		t, path,
+		OptFileTailSetMaxBuffer(5),
+		OptFileTailSetMultipart(true),
+	)
+	defer closeTestFileTail(t, r)
+
+	r.readBuf = make([]byte, 3)
+
+	select {
+	case res := <-readTail(r):
+		if res.err != bufio.ErrTooLong {
+			t.Errorf("Wrong error: %v != %v", res.err, bufio.ErrTooLong)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("Timed out")
+	}
+
+	// The parts before the long line must not be lost.
+	expectTailMessage(t, r, "foo", "bar", "baz")
+	expectTailMessage(t, r, "qux")
+}
+
+func TestFileTailCheckpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_file_tail_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.log")
+	checkpointPath := filepath.Join(dir, "app.log.offset")
+	appendTailFile(t, path, "foo\nbar\nbaz\n")
+
+	r := newTestFileTail(t, path, OptFileTailSetCheckpointPath(checkpointPath))
+	expectTailMessage(t, r, "foo")
+	expectTailMessage(t, r, "bar")
+
+	// Unacknowledged messages are read again after a restart.
+	select {
+	case res := <-readTail(r):
+		if res.err != nil {
+			t.Fatal(res.err)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("Timed out")
+	}
+	closeTestFileTail(t, r)
+
+	appendTailFile(t, path, "qux\n")
+
+	r = newTestFileTail(t, path, OptFileTailSetCheckpointPath(checkpointPath))
+	expectTailMessage(t, r, "baz")
+	expectTailMessage(t, r, "qux")
+	closeTestFileTail(t, r)
+
+	// A replaced file is read from the beginning.
+	if err = os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	appendTailFile(t, path, "first\n")
+
+	r = newTestFileTail(t, path, OptFileTailSetCheckpointPath(checkpointPath))
+	expectTailMessage(t, r, "first")
+	closeTestFileTail(t, r)
+}
+
+func TestFileTailClose(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_file_tail_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.log")
+	appendTailFile(t, path, "")
+
+	r := newTestFileTail(t, path)
+
+	resChan := readTail(r)
+	r.CloseAsync()
+
+	select {
+	case res := <-resChan:
+		if res.err != types.ErrTypeClosed {
+			t.Errorf("Wrong error: %v != %v", res.err, types.ErrTypeClosed)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("Timed out")
+	}
+	if err = r.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+	if err = r.Connect(); err != types.ErrTypeClosed {
+		t.Errorf("Wrong error: %v != %v", err, types.ErrTypeClosed)
+	}
+}
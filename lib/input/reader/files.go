@@ -21,10 +21,13 @@
 package reader
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/Jeffail/benthos/lib/message"
@@ -33,15 +36,35 @@ import (
 
 //------------------------------------------------------------------------------
 
+// FilesWatchConfig contains configuration for polling a directory for new
+// files.
+type FilesWatchConfig struct {
+	Enabled      bool   `json:"enabled" yaml:"enabled"`
+	Pattern      string `json:"pattern" yaml:"pattern"`
+	PollPeriodMS int    `json:"poll_period_ms" yaml:"poll_period_ms"`
+	Cache        string `json:"cache" yaml:"cache"`
+}
+
 // FilesConfig contains configuration for the Files input type.
 type FilesConfig struct {
-	Path string `json:"path" yaml:"path"`
+	Path   string           `json:"path" yaml:"path"`
+	OnAck  string           `json:"on_ack" yaml:"on_ack"`
+	MoveTo string           `json:"move_to" yaml:"move_to"`
+	Watch  FilesWatchConfig `json:"watch" yaml:"watch"`
 }
 
 // NewFilesConfig creates a new FilesConfig with default values.
 func NewFilesConfig() FilesConfig {
 	return FilesConfig{
-		Path: "",
+		Path:   "",
+		OnAck:  "none",
+		MoveTo: "",
+		Watch: FilesWatchConfig{
+			Enabled:      false,
+			Pattern:      "",
+			PollPeriodMS: 1000,
+			Cache:        "",
+		},
 	}
 }
 
@@ -49,47 +72,168 @@ func NewFilesConfig() FilesConfig {
 
 // Files is an input type that reads file contents at a path as messages.
 type Files struct {
+	conf       FilesConfig
+	pollPeriod time.Duration
+	cache      types.Cache
+
 	targets []string
+	pending []string
+
+	// seen contains the modification times of files that have been read, or
+	// are waiting to be read, when watching a path.
+	seen map[string]string
+
+	closeOnce sync.Once
+	closeChan chan struct{}
 }
 
-// NewFiles creates a new Files input type.
-func NewFiles(conf FilesConfig) (Type, error) {
-	f := Files{}
+// NewFiles creates a new Files input type. A manager is only required when
+// the config refers to a cache resource.
+func NewFiles(conf FilesConfig, mgr types.Manager) (Type, error) {
+	switch conf.OnAck {
+	case "none":
+	case "delete":
+	case "move":
+		if len(conf.MoveTo) == 0 {
+			return nil, errors.New("a move_to path must be specified when on_ack is move")
+		}
+	default:
+		return nil, fmt.Errorf("on_ack value not recognised: %v", conf.OnAck)
+	}
+
+	f := &Files{
+		conf:       conf,
+		pollPeriod: time.Duration(conf.Watch.PollPeriodMS) * time.Millisecond,
+		seen:       map[string]string{},
+		closeChan:  make(chan struct{}),
+	}
+
+	if !conf.Watch.Enabled {
+		targets, err := walkFilePaths(conf.Path)
+		if err != nil {
+			return nil, err
+		}
+		f.targets = targets
+		return f, nil
+	}
+
+	if _, err := filepath.Match(conf.Watch.Pattern, ""); err != nil {
+		return nil, fmt.Errorf("failed to parse pattern: %v", err)
+	}
+	if len(conf.Watch.Cache) > 0 {
+		if mgr == nil {
+			return nil, errors.New("a manager is required in order to use a cache")
+		}
+		var err error
+		if f.cache, err = mgr.GetCache(conf.Watch.Cache); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
 
-	if info, err := os.Stat(conf.Path); err != nil {
+// walkFilePaths returns the path if it points to a file, or every file found
+// by walking the path if it points to a directory.
+func walkFilePaths(root string) ([]string, error) {
+	if info, err := os.Stat(root); err != nil {
 		return nil, err
 	} else if !info.IsDir() {
-		f.targets = append(f.targets, conf.Path)
-		return &f, nil
+		return []string{root}, nil
 	}
 
-	err := filepath.Walk(conf.Path, func(path string, info os.FileInfo, werr error) error {
+	var targets []string
+	err := filepath.Walk(root, func(path string, info os.FileInfo, werr error) error {
 		if werr != nil {
 			return werr
 		}
 		if info.IsDir() {
 			return nil
 		}
-		f.targets = append(f.targets, path)
+		targets = append(targets, path)
 		return nil
 	})
-
-	return &f, err
+	return targets, err
 }
 
 //------------------------------------------------------------------------------
 
-// Connect establishes a connection.
+// Connect establishes a connection, which when watching a path means checking
+// that the path exists.
 func (f *Files) Connect() (err error) {
-	return nil
+	if f.conf.Watch.Enabled {
+		_, err = os.Stat(f.conf.Path)
+	}
+	return
 }
 
-//------------------------------------------------------------------------------
+// scan walks the watched path and adds any files that match the pattern and
+// have not yet been read (or have been modified since) to the targets.
+func (f *Files) scan() error {
+	paths, err := walkFilePaths(f.conf.Path)
+	if err != nil {
+		return err
+	}
+
+	var moveTo string
+	if f.conf.OnAck == "move" {
+		if moveTo, err = filepath.Abs(f.conf.MoveTo); err != nil {
+			return err
+		}
+	}
+
+	for _, path := range paths {
+		if len(f.conf.Watch.Pattern) > 0 {
+			if matched, _ := filepath.Match(f.conf.Watch.Pattern, filepath.Base(path)); !matched {
+				continue
+			}
+		}
+		if len(moveTo) > 0 {
+			// Ignore files that we have already moved.
+			if absPath, err := filepath.Abs(path); err == nil &&
+				strings.HasPrefix(absPath, moveTo+string(filepath.Separator)) {
+				continue
+			}
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			// The file might have been removed since walking.
+			continue
+		}
+		modTime := info.ModTime().UTC().Format(time.RFC3339Nano)
+		if f.seen[path] == modTime {
+			continue
+		}
+		if f.cache != nil {
+			if cached, err := f.cache.Get(path); err == nil && string(cached) == modTime {
+				f.seen[path] = modTime
+				continue
+			}
+		}
+
+		f.seen[path] = modTime
+		f.targets = append(f.targets, path)
+	}
+	return nil
+}
 
 // Read a new Files message.
 func (f *Files) Read() (types.Message, error) {
-	if len(f.targets) == 0 {
-		return nil, types.ErrTypeClosed
+	for len(f.targets) == 0 {
+		if !f.conf.Watch.Enabled {
+			return nil, types.ErrTypeClosed
+		}
+		if err := f.scan(); err != nil {
+			return nil, types.ErrNotConnected
+		}
+		if len(f.targets) > 0 {
+			break
+		}
+		select {
+		case <-time.After(f.pollPeriod):
+		case <-f.closeChan:
+			return nil, types.ErrTypeClosed
+		}
 	}
 
 	path := f.targets[0]
@@ -97,28 +241,75 @@ func (f *Files) Read() (types.Message, error) {
 
 	file, openerr := os.Open(path)
 	if openerr != nil {
+		delete(f.seen, path)
 		return nil, fmt.Errorf("failed to read file '%v': %v", path, openerr)
 	}
 	defer file.Close()
 
 	msgBytes, readerr := ioutil.ReadAll(file)
 	if readerr != nil {
+		delete(f.seen, path)
 		return nil, readerr
 	}
 
+	f.pending = append(f.pending, path)
+
 	msg := message.New([][]byte{msgBytes})
 	msg.SetMetadata("path", path)
 	return msg, nil
 }
 
+// moveFile moves a file into the move_to directory, preserving its path
+// relative to the input path.
+func (f *Files) moveFile(path string) error {
+	rel, err := filepath.Rel(f.conf.Path, path)
+	if err != nil || rel == "." {
+		rel = filepath.Base(path)
+	}
+	dest := filepath.Join(f.conf.MoveTo, rel)
+	if err = os.MkdirAll(filepath.Dir(dest), os.FileMode(0777)); err != nil {
+		return err
+	}
+	return os.Rename(path, dest)
+}
+
 // Acknowledge instructs whether unacknowledged messages have been successfully
-// propagated.
+// propagated. Once propagated the files read are deleted, moved, or recorded
+// as read in the cache, depending on the config.
 func (f *Files) Acknowledge(err error) error {
-	return nil
+	if err != nil {
+		return nil
+	}
+
+	var ackErr error
+	for _, path := range f.pending {
+		switch f.conf.OnAck {
+		case "delete":
+			if err = os.Remove(path); err == nil {
+				delete(f.seen, path)
+			}
+		case "move":
+			if err = f.moveFile(path); err == nil {
+				delete(f.seen, path)
+			}
+		default:
+			if f.cache != nil {
+				err = f.cache.Set(path, []byte(f.seen[path]))
+			}
+		}
+		if err != nil {
+			ackErr = err
+		}
+	}
+	f.pending = nil
+	return ackErr
 }
 
 // CloseAsync shuts down the Files input and stops processing requests.
 func (f *Files) CloseAsync() {
+	f.closeOnce.Do(func() {
+		close(f.closeChan)
+	})
 }
 
 // WaitForClose blocks until the Files input has closed down.
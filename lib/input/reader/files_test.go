@@ -22,9 +22,12 @@ package reader
 
 import (
 	"io/ioutil"
+	"net/http"
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/Jeffail/benthos/lib/types"
 )
@@ -74,7 +77,7 @@ func TestFilesDirectory(t *testing.T) {
 	conf.Path = tmpDir
 
 	var f Type
-	if f, err = NewFiles(conf); err != nil {
+	if f, err = NewFiles(conf, nil); err != nil {
 		t.Fatal(err)
 	}
 
@@ -132,7 +135,7 @@ func TestFilesFile(t *testing.T) {
 	conf.Path = tmpFile.Name()
 
 	var f Type
-	if f, err = NewFiles(conf); err != nil {
+	if f, err = NewFiles(conf, nil); err != nil {
 		t.Fatal(err)
 	}
 
@@ -163,9 +166,343 @@ func TestFilesBadPath(t *testing.T) {
 	conf := NewFilesConfig()
 	conf.Path = "fdgdfkte34%#@$%#$%KL@#K$@:L#$23k;32l;23"
 
-	if _, err := NewFiles(conf); err == nil {
+	if _, err := NewFiles(conf, nil); err == nil {
 		t.Error("Expected error from bad path")
 	}
 }
 
+func TestFilesBadOnAck(t *testing.T) {
+	conf := NewFilesConfig()
+	conf.Path = os.TempDir()
+	conf.OnAck = "nope"
+	if _, err := NewFiles(conf, nil); err == nil {
+		t.Error("Expected error from bad on_ack")
+	}
+
+	conf.OnAck = "move"
+	if _, err := NewFiles(conf, nil); err == nil {
+		t.Error("Expected error from missing move_to")
+	}
+
+	conf.OnAck = "none"
+	conf.Watch.Enabled = true
+	conf.Watch.Pattern = "[foo"
+	if _, err := NewFiles(conf, nil); err == nil {
+		t.Error("Expected error from bad pattern")
+	}
+
+	conf.Watch.Pattern = ""
+	conf.Watch.Cache = "foo"
+	if _, err := NewFiles(conf, &fakeFilesMgr{}); err == nil {
+		t.Error("Expected error from missing cache")
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type fakeFilesCache struct {
+	values map[string][]byte
+}
+
+func (c *fakeFilesCache) Get(key string) ([]byte, error) {
+	if v, exists := c.values[key]; exists {
+		return v, nil
+	}
+	return nil, types.ErrKeyNotFound
+}
+func (c *fakeFilesCache) Set(key string, value []byte) error {
+	c.values[key] = value
+	return nil
+}
+func (c *fakeFilesCache) Add(key string, value []byte) error {
+	if _, exists := c.values[key]; exists {
+		return types.ErrKeyAlreadyExists
+	}
+	c.values[key] = value
+	return nil
+}
+func (c *fakeFilesCache) Delete(key string) error {
+	delete(c.values, key)
+	return nil
+}
+
+type fakeFilesMgr struct {
+	caches map[string]types.Cache
+}
+
+func (f *fakeFilesMgr) RegisterEndpoint(path, desc string, h http.HandlerFunc) {
+}
+func (f *fakeFilesMgr) GetCache(name string) (types.Cache, error) {
+	if c, exists := f.caches[name]; exists {
+		return c, nil
+	}
+	return nil, types.ErrCacheNotFound
+}
+func (f *fakeFilesMgr) GetCondition(name string) (types.Condition, error) {
+	return nil, types.ErrConditionNotFound
+}
+func (f *fakeFilesMgr) GetPipe(name string) (<-chan types.Transaction, error) {
+	return nil, types.ErrPipeNotFound
+}
+func (f *fakeFilesMgr) SetPipe(name string, prod <-chan types.Transaction)   {}
+func (f *fakeFilesMgr) UnsetPipe(name string, prod <-chan types.Transaction) {}
+
+func writeWatchFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(content), 0666); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readWatchFile(t *testing.T, f Type) (string, string) {
+	t.Helper()
+
+	type result struct {
+		msg types.Message
+		err error
+	}
+	resChan := make(chan result, 1)
+	go func() {
+		msg, err := f.Read()
+		resChan <- result{msg: msg, err: err}
+	}()
+
+	select {
+	case res := <-resChan:
+		if res.err != nil {
+			t.Fatal(res.err)
+		}
+		if err := f.Acknowledge(nil); err != nil {
+			t.Error(err)
+		}
+		return string(res.msg.Get(0)), res.msg.GetMetadata("path")
+	case <-time.After(time.Second * 5):
+		t.Fatal("Timed out")
+	}
+	return "", ""
+}
+
+func expectNoWatchFile(t *testing.T, f Type) {
+	t.Helper()
+
+	resChan := make(chan error, 1)
+	go func() {
+		_, err := f.Read()
+		resChan <- err
+	}()
+
+	select {
+	case err := <-resChan:
+		t.Fatalf("Unexpected read result: %v", err)
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	f.CloseAsync()
+	select {
+	case err := <-resChan:
+		if err != types.ErrTypeClosed {
+			t.Errorf("Wrong error: %v != %v", err, types.ErrTypeClosed)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("Timed out")
+	}
+}
+
+func newWatchFiles(t *testing.T, conf FilesConfig, mgr types.Manager) Type {
+	t.Helper()
+	conf.Watch.Enabled = true
+	conf.Watch.PollPeriodMS = 10
+	f, err := NewFiles(conf, mgr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = f.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestFilesWatch(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "benthos_file_input_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	dropDir := filepath.Join(tmpDir, "drop")
+
+	conf := NewFilesConfig()
+	conf.Path = dropDir
+	conf.Watch.Enabled = true
+	conf.Watch.Pattern = "*.json"
+
+	f, err := NewFiles(conf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = f.Connect(); err == nil {
+		t.Error("Expected error from missing directory")
+	}
+
+	writeWatchFile(t, filepath.Join(dropDir, "a.json"), "foo")
+	writeWatchFile(t, filepath.Join(dropDir, "b.txt"), "ignored")
+
+	f = newWatchFiles(t, conf, nil)
+
+	if content, path := readWatchFile(t, f); content != "foo" {
+		t.Errorf("Wrong content: %v != %v", content, "foo")
+	} else if exp := filepath.Join(dropDir, "a.json"); path != exp {
+		t.Errorf("Wrong path: %v != %v", path, exp)
+	}
+
+	writeWatchFile(t, filepath.Join(dropDir, "inner", "c.json"), "bar")
+	if content, _ := readWatchFile(t, f); content != "bar" {
+		t.Errorf("Wrong content: %v != %v", content, "bar")
+	}
+
+	// Modified files are read again.
+	later := time.Now().Add(time.Minute)
+	writeWatchFile(t, filepath.Join(dropDir, "a.json"), "baz")
+	if err = os.Chtimes(filepath.Join(dropDir, "a.json"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := readWatchFile(t, f); content != "baz" {
+		t.Errorf("Wrong content: %v != %v", content, "baz")
+	}
+
+	expectNoWatchFile(t, f)
+}
+
+func TestFilesWatchCache(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "benthos_file_input_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	mgr := &fakeFilesMgr{
+		caches: map[string]types.Cache{
+			"foocache": &fakeFilesCache{values: map[string][]byte{}},
+		},
+	}
+
+	conf := NewFilesConfig()
+	conf.Path = tmpDir
+	conf.Watch.Cache = "foocache"
+
+	writeWatchFile(t, filepath.Join(tmpDir, "a.txt"), "foo")
+
+	f := newWatchFiles(t, conf, mgr)
+	if content, _ := readWatchFile(t, f); content != "foo" {
+		t.Errorf("Wrong content: %v != %v", content, "foo")
+	}
+	f.CloseAsync()
+
+	// A new reader skips files recorded in the cache.
+	writeWatchFile(t, filepath.Join(tmpDir, "b.txt"), "bar")
+
+	f = newWatchFiles(t, conf, mgr)
+	if content, _ := readWatchFile(t, f); content != "bar" {
+		t.Errorf("Wrong content: %v != %v", content, "bar")
+	}
+	expectNoWatchFile(t, f)
+}
+
+func TestFilesWatchDelete(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "benthos_file_input_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	conf := NewFilesConfig()
+	conf.Path = tmpDir
+	conf.OnAck = "delete"
+
+	path := filepath.Join(tmpDir, "a.txt")
+	writeWatchFile(t, path, "foo")
+
+	f := newWatchFiles(t, conf, nil)
+	if content, _ := readWatchFile(t, f); content != "foo" {
+		t.Errorf("Wrong content: %v != %v", content, "foo")
+	}
+	if _, err = os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected file to be deleted: %v", err)
+	}
+
+	// A new file of the same name is read.
+	writeWatchFile(t, path, "bar")
+	if content, _ := readWatchFile(t, f); content != "bar" {
+		t.Errorf("Wrong content: %v != %v", content, "bar")
+	}
+	expectNoWatchFile(t, f)
+}
+
+func TestFilesWatchMove(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "benthos_file_input_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	conf := NewFilesConfig()
+	conf.Path = tmpDir
+	conf.OnAck = "move"
+	conf.MoveTo = filepath.Join(tmpDir, "done")
+
+	writeWatchFile(t, filepath.Join(tmpDir, "inner", "a.txt"), "foo")
+
+	f := newWatchFiles(t, conf, nil)
+	if content, _ := readWatchFile(t, f); content != "foo" {
+		t.Errorf("Wrong content: %v != %v", content, "foo")
+	}
+
+	moved, err := ioutil.ReadFile(filepath.Join(tmpDir, "done", "inner", "a.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(moved) != "foo" {
+		t.Errorf("Wrong moved content: %s != %v", moved, "foo")
+	}
+	if _, err = os.Stat(filepath.Join(tmpDir, "inner", "a.txt")); !os.IsNotExist(err) {
+		t.Errorf("Expected file to be moved: %v", err)
+	}
+
+	// Moved files within the watched path are not read again.
+	expectNoWatchFile(t, f)
+}
+
+func TestFilesMoveNoWatch(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "benthos_file_input_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	path := filepath.Join(tmpDir, "a.txt")
+	writeWatchFile(t, path, "foo")
+
+	conf := NewFilesConfig()
+	conf.Path = path
+	conf.OnAck = "move"
+	conf.MoveTo = filepath.Join(tmpDir, "done")
+
+	f, err := NewFiles(conf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := readWatchFile(t, f); content != "foo" {
+		t.Errorf("Wrong content: %v != %v", content, "foo")
+	}
+	if _, err = f.Read(); err != types.ErrTypeClosed {
+		t.Errorf("Wrong error: %v != %v", err, types.ErrTypeClosed)
+	}
+	if _, err = os.Stat(filepath.Join(tmpDir, "done", "a.txt")); err != nil {
+		t.Errorf("Expected file to be moved: %v", err)
+	}
+}
+
 //------------------------------------------------------------------------------
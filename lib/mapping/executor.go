@@ -0,0 +1,203 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mapping
+
+import (
+	"fmt"
+
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+type targetType int
+
+const (
+	targetRoot targetType = iota
+	targetMeta
+)
+
+// statement is a single assignment of a mapping.
+type statement struct {
+	line    int
+	target  targetType
+	path    []string
+	metaKey string
+	value   query
+}
+
+//------------------------------------------------------------------------------
+
+// Executor is a compiled mapping that can be executed against message parts.
+type Executor struct {
+	statements []statement
+}
+
+// Parse compiles a mapping into an Executor. If the mapping is invalid the
+// returned error will be of type *ErrParse, which contains the line and
+// character where the problem was found.
+func Parse(mapping string) (*Executor, error) {
+	tokens, err := lex(mapping)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	statements, err := p.parseStatements()
+	if err != nil {
+		return nil, err
+	}
+	return &Executor{statements: statements}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// MapPart executes the mapping against a message part of src and writes the
+// result to the same part of dst, which can be the same message as src.
+// Metadata is always read from src, and metadata assignments are written to
+// dst, which applies them to the message as a whole.
+//
+// Returns false if the mapping deleted the message part, in which case it is
+// the responsibility of the caller to remove it. If an error is returned then
+// dst has not been modified.
+func (e *Executor) MapPart(index int, src, dst types.Message) (bool, error) {
+	ctx := &context{
+		index: index,
+		msg:   src,
+	}
+
+	var root interface{}
+	var rootSet, rootDeleted bool
+
+	type metaChange struct {
+		key     string
+		value   string
+		deleted bool
+	}
+	var metaChanges []metaChange
+
+	for _, s := range e.statements {
+		v, err := s.value(ctx)
+		if err != nil {
+			return false, fmt.Errorf("line %v: %v", s.line, err)
+		}
+		if _, isNothing := v.(nothingValue); isNothing {
+			continue
+		}
+		_, isDeleted := v.(deletedValue)
+
+		if s.target == targetMeta {
+			if isDeleted {
+				metaChanges = append(metaChanges, metaChange{key: s.metaKey, deleted: true})
+			} else {
+				metaChanges = append(metaChanges, metaChange{key: s.metaKey, value: toString(v)})
+			}
+			continue
+		}
+
+		if isDeleted {
+			if len(s.path) == 0 {
+				root, rootSet, rootDeleted = nil, false, true
+			} else {
+				deletePath(root, s.path)
+			}
+			continue
+		}
+
+		// Structured values resolved from the message part are shared with its
+		// cached JSON structure and therefore need copying before being
+		// modified.
+		switch v.(type) {
+		case map[string]interface{}, []interface{}:
+			if v, err = message.CloneGeneric(v); err != nil {
+				return false, fmt.Errorf("line %v: %v", s.line, err)
+			}
+		}
+		if root, err = setPath(root, s.path, v); err != nil {
+			return false, fmt.Errorf("line %v: %v", s.line, err)
+		}
+		rootSet, rootDeleted = true, false
+	}
+
+	if rootSet {
+		switch t := root.(type) {
+		case string:
+			dst.Set(index, []byte(t))
+		case []byte:
+			dst.Set(index, t)
+		default:
+			if err := dst.SetJSON(index, t); err != nil {
+				return false, fmt.Errorf("failed to set mapping result as JSON: %v", err)
+			}
+		}
+	}
+
+	for _, c := range metaChanges {
+		if c.deleted {
+			dst.DeleteMetadata(c.key)
+		} else {
+			dst.SetMetadata(c.key, c.value)
+		}
+	}
+	return !rootDeleted, nil
+}
+
+//------------------------------------------------------------------------------
+
+func setPath(root interface{}, path []string, v interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return v, nil
+	}
+	if root == nil {
+		root = map[string]interface{}{}
+	}
+	obj, ok := root.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("cannot set field '%v' of %v root", path[0], typeName(root))
+	}
+	for i, seg := range path[:len(path)-1] {
+		next, exists := obj[seg]
+		if !exists || next == nil {
+			next = map[string]interface{}{}
+			obj[seg] = next
+		}
+		if obj, ok = next.(map[string]interface{}); !ok {
+			return nil, fmt.Errorf("cannot set field '%v' of %v", path[i+1], typeName(next))
+		}
+	}
+	obj[path[len(path)-1]] = v
+	return root, nil
+}
+
+func deletePath(root interface{}, path []string) {
+	obj, ok := root.(map[string]interface{})
+	if !ok {
+		return
+	}
+	for _, seg := range path[:len(path)-1] {
+		if obj, ok = obj[seg].(map[string]interface{}); !ok {
+			return
+		}
+	}
+	delete(obj, path[len(path)-1])
+}
+
+//------------------------------------------------------------------------------
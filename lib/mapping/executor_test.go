@@ -0,0 +1,359 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mapping
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/message"
+)
+
+//------------------------------------------------------------------------------
+
+func TestMappingParts(t *testing.T) {
+	type part struct {
+		Content string
+		Meta    map[string]string
+	}
+
+	tests := map[string]struct {
+		mapping string
+		input   part
+		output  part
+		deleted bool
+	}{
+		"copy fields": {
+			mapping: `root.id = this.user.id
+root.name = this.user.name`,
+			input: part{
+				Content: `{"user":{"id":"foo","name":"bar","age":10}}`,
+			},
+			output: part{
+				Content: `{"id":"foo","name":"bar"}`,
+			},
+		},
+		"arithmetic": {
+			mapping: `root.sum = this.a + this.b * 2
+root.diff = (this.a - this.b) / 2
+root.mod = this.a % 3
+root.neg = -this.a`,
+			input: part{
+				Content: `{"a":10,"b":4}`,
+			},
+			output: part{
+				Content: `{"diff":3,"mod":1,"neg":-10,"sum":18}`,
+			},
+		},
+		"string functions": {
+			mapping: `root.name = this.first.uppercase() + " " + this.last.lowercase()
+root.parts = this.tags.split(",")
+root.joined = this.tags.split(",").join("-")
+root.has = this.tags.contains("bar")
+root.len = this.first.length()`,
+			input: part{
+				Content: `{"first":"foo","last":"BAR","tags":"foo,bar,baz"}`,
+			},
+			output: part{
+				Content: `{"has":true,"joined":"foo-bar-baz","len":3,"name":"FOO bar","parts":["foo","bar","baz"]}`,
+			},
+		},
+		"conditionals": {
+			mapping: `root.tier = if this.spend > 1000 {
+  "gold"
+} else if this.spend > 100 {
+  "silver"
+} else {
+  "standard"
+}
+root.big = if this.spend > 1000 { true }`,
+			input: part{
+				Content: `{"spend":500}`,
+			},
+			output: part{
+				Content: `{"tier":"silver"}`,
+			},
+		},
+		"delete fields": {
+			mapping: `root = this
+root.user.age = deleted()
+root.other = deleted()`,
+			input: part{
+				Content: `{"user":{"id":"foo","age":10},"other":"bar"}`,
+			},
+			output: part{
+				Content: `{"user":{"id":"foo"}}`,
+			},
+		},
+		"delete part": {
+			mapping: `root = deleted()`,
+			input: part{
+				Content: `{"foo":"bar"}`,
+			},
+			output: part{
+				Content: `{"foo":"bar"}`,
+			},
+			deleted: true,
+		},
+		"metadata": {
+			mapping: `root.topic = meta("topic")
+root.missing = meta("nope")
+meta topic = meta("topic").uppercase()
+meta "partition" = this.partition
+meta removed = deleted()`,
+			input: part{
+				Content: `{"partition":5}`,
+				Meta: map[string]string{
+					"topic":   "foo",
+					"removed": "bar",
+				},
+			},
+			output: part{
+				Content: `{"missing":null,"topic":"foo"}`,
+				Meta: map[string]string{
+					"topic":     "FOO",
+					"partition": "5",
+				},
+			},
+		},
+		"raw content": {
+			mapping: `root = content().uppercase()`,
+			input: part{
+				Content: `hello world`,
+			},
+			output: part{
+				Content: `HELLO WORLD`,
+			},
+		},
+		"no root assignment": {
+			mapping: `meta foo = "bar"`,
+			input: part{
+				Content: `not json`,
+			},
+			output: part{
+				Content: `not json`,
+				Meta: map[string]string{
+					"foo": "bar",
+				},
+			},
+		},
+		"literals": {
+			mapping: `root.arr = [this.a, "b", 3, null, deleted()]
+root.obj = {"a": this.a, b: true}
+root.quoted."foo.bar" = this.a.or("default")`,
+			input: part{
+				Content: `{"a":"foo"}`,
+			},
+			output: part{
+				Content: `{"arr":["foo","b",3,null],"obj":{"a":"foo","b":true},"quoted":{"foo.bar":"foo"}}`,
+			},
+		},
+		"array indexes": {
+			mapping: `root.first = this.items.0.name
+root.last = this.items.-1.name
+root.count = this.items.length()`,
+			input: part{
+				Content: `{"items":[{"name":"foo"},{"name":"bar"},{"name":"baz"}]}`,
+			},
+			output: part{
+				Content: `{"count":3,"first":"foo","last":"baz"}`,
+			},
+		},
+		"or fallback": {
+			mapping: `root.a = this.nope.or("default")
+root.b = this.nope.foo.bar.or(this.a)
+root.c = (this.a.number() + 1).or(0)`,
+			input: part{
+				Content: `{"a":"5"}`,
+			},
+			output: part{
+				Content: `{"a":"default","b":"5","c":6}`,
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(tt *testing.T) {
+			e, err := Parse(test.mapping)
+			if err != nil {
+				tt.Fatal(err)
+			}
+			msg := message.New([][]byte{[]byte(test.input.Content)})
+			for k, v := range test.input.Meta {
+				msg.SetMetadata(k, v)
+			}
+
+			res, err := e.MapPart(0, msg, msg)
+			if err != nil {
+				tt.Fatal(err)
+			}
+			if exp, act := !test.deleted, res; exp != act {
+				tt.Errorf("Wrong deleted result: %v != %v", act, exp)
+			}
+			if exp, act := test.output.Content, string(msg.Get(0)); exp != act {
+				tt.Errorf("Wrong result: %v != %v", act, exp)
+			}
+			actMeta := map[string]string{}
+			msg.IterMetadata(func(k, v string) error {
+				actMeta[k] = v
+				return nil
+			})
+			expMeta := test.output.Meta
+			if expMeta == nil {
+				expMeta = map[string]string{}
+			}
+			if !reflect.DeepEqual(expMeta, actMeta) {
+				tt.Errorf("Wrong metadata result: %v != %v", actMeta, expMeta)
+			}
+		})
+	}
+}
+
+func TestMappingSeparateMessages(t *testing.T) {
+	e, err := Parse(`root.doubled = this.value * 2
+root.index = batch_index()
+root.size = batch_size()
+meta foo = "new"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src := message.New([][]byte{
+		[]byte(`{"value":1}`),
+		[]byte(`{"value":2}`),
+	})
+	src.SetMetadata("foo", "old")
+	dst := src.ShallowCopy()
+
+	for i := 0; i < src.Len(); i++ {
+		if _, err = e.MapPart(i, src, dst); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if exp, act := `{"value":1}`, string(src.Get(0)); exp != act {
+		t.Errorf("Source was modified: %v != %v", act, exp)
+	}
+	if exp, act := "old", src.GetMetadata("foo"); exp != act {
+		t.Errorf("Source metadata was modified: %v != %v", act, exp)
+	}
+	if exp, act := `{"doubled":2,"index":0,"size":2}`, string(dst.Get(0)); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+	if exp, act := `{"doubled":4,"index":1,"size":2}`, string(dst.Get(1)); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+	if exp, act := "new", dst.GetMetadata("foo"); exp != act {
+		t.Errorf("Wrong metadata: %v != %v", act, exp)
+	}
+}
+
+func TestMappingDoesNotMutateSource(t *testing.T) {
+	e, err := Parse(`root = this
+root.user.name = "changed"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src := message.New([][]byte{[]byte(`{"user":{"name":"original"}}`)})
+	dst := src.ShallowCopy()
+	if _, err = e.MapPart(0, src, dst); err != nil {
+		t.Fatal(err)
+	}
+
+	srcJSON, err := src.GetJSON(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := map[string]interface{}{
+		"user": map[string]interface{}{"name": "original"},
+	}
+	if !reflect.DeepEqual(exp, srcJSON) {
+		t.Errorf("Source was modified: %v != %v", srcJSON, exp)
+	}
+	if exp, act := `{"user":{"name":"changed"}}`, string(dst.Get(0)); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+}
+
+func TestMappingExecErrors(t *testing.T) {
+	tests := map[string]struct {
+		mapping string
+		input   string
+		err     string
+	}{
+		"not json": {
+			mapping: `root.foo = this.foo`,
+			input:   `not json`,
+			err:     "line 1: failed to parse message part as JSON: invalid character 'o' in literal null (expecting 'u')",
+		},
+		"divide by zero": {
+			mapping: `root.foo = "bar"
+root.bar = this.a / 0`,
+			input: `{"a":5}`,
+			err:   "line 2: attempted to divide by zero",
+		},
+		"bad arithmetic": {
+			mapping: `root.foo = this.a - "bar"`,
+			input:   `{"a":5}`,
+			err:     "line 1: cannot apply '-' to number and string values",
+		},
+		"bad method target": {
+			mapping: `root.foo = this.a.uppercase()`,
+			input:   `{"a":5}`,
+			err:     "line 1: uppercase expects a string target, found number",
+		},
+		"set field of non object root": {
+			mapping: `root = "foo"
+root.bar = "baz"`,
+			input: `{}`,
+			err:   "line 2: cannot set field 'bar' of string root",
+		},
+		"set field of non object value": {
+			mapping: `root.foo = "foo"
+root.foo.bar = "baz"`,
+			input: `{}`,
+			err:   "line 2: cannot set field 'bar' of string",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(tt *testing.T) {
+			e, err := Parse(test.mapping)
+			if err != nil {
+				tt.Fatal(err)
+			}
+			msg := message.New([][]byte{[]byte(test.input)})
+			_, err = e.MapPart(0, msg, msg)
+			if err == nil {
+				tt.Fatal("Expected error")
+			}
+			if exp, act := test.err, err.Error(); exp != act {
+				tt.Errorf("Wrong error: %v != %v", act, exp)
+			}
+			if exp, act := test.input, string(msg.Get(0)); exp != act {
+				tt.Errorf("Message was modified: %v != %v", act, exp)
+			}
+		})
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,361 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mapping
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//------------------------------------------------------------------------------
+
+// functionCtor creates a query from a list of argument queries.
+type functionCtor func(args []query) (query, error)
+
+// methodCtor creates a query that applies a method to the result of a target
+// query.
+type methodCtor func(target query, args []query) (query, error)
+
+func checkArgs(name string, args []query, count int) error {
+	if len(args) != count {
+		return fmt.Errorf("%v expects %v arguments, received %v", name, count, len(args))
+	}
+	return nil
+}
+
+func resolveArgs(ctx *context, args []query) ([]interface{}, error) {
+	values := make([]interface{}, len(args))
+	for i, a := range args {
+		var err error
+		if values[i], err = a(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+func stringArgs(name string, values []interface{}) ([]string, error) {
+	strs := make([]string, len(values))
+	for i, v := range values {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%v expects string arguments, found %v", name, typeName(v))
+		}
+		strs[i] = s
+	}
+	return strs, nil
+}
+
+//------------------------------------------------------------------------------
+
+var functions = map[string]functionCtor{
+	"batch_index": func(args []query) (query, error) {
+		if err := checkArgs("batch_index", args, 0); err != nil {
+			return nil, err
+		}
+		return func(ctx *context) (interface{}, error) {
+			return float64(ctx.index), nil
+		}, nil
+	},
+	"batch_size": func(args []query) (query, error) {
+		if err := checkArgs("batch_size", args, 0); err != nil {
+			return nil, err
+		}
+		return func(ctx *context) (interface{}, error) {
+			return float64(ctx.msg.Len()), nil
+		}, nil
+	},
+	"content": func(args []query) (query, error) {
+		if err := checkArgs("content", args, 0); err != nil {
+			return nil, err
+		}
+		return func(ctx *context) (interface{}, error) {
+			return string(ctx.msg.Get(ctx.index)), nil
+		}, nil
+	},
+	"deleted": func(args []query) (query, error) {
+		if err := checkArgs("deleted", args, 0); err != nil {
+			return nil, err
+		}
+		return literalQuery(deletedValue{}), nil
+	},
+	"meta": func(args []query) (query, error) {
+		if err := checkArgs("meta", args, 1); err != nil {
+			return nil, err
+		}
+		return func(ctx *context) (interface{}, error) {
+			values, err := resolveArgs(ctx, args)
+			if err != nil {
+				return nil, err
+			}
+			keys, err := stringArgs("meta", values)
+			if err != nil {
+				return nil, err
+			}
+			var value interface{}
+			errFound := errors.New("found")
+			ctx.msg.IterMetadata(func(k, v string) error {
+				if k == keys[0] {
+					value = v
+					return errFound
+				}
+				return nil
+			})
+			return value, nil
+		}, nil
+	},
+	"now": func(args []query) (query, error) {
+		if err := checkArgs("now", args, 0); err != nil {
+			return nil, err
+		}
+		return func(ctx *context) (interface{}, error) {
+			return time.Now().Format(time.RFC3339Nano), nil
+		}, nil
+	},
+}
+
+//------------------------------------------------------------------------------
+
+// stringMethod creates a method that applies a function to a string target
+// with string arguments.
+func stringMethod(name string, argCount int, fn func(s string, args []string) (interface{}, error)) methodCtor {
+	return func(target query, args []query) (query, error) {
+		if err := checkArgs(name, args, argCount); err != nil {
+			return nil, err
+		}
+		return func(ctx *context) (interface{}, error) {
+			v, err := target(ctx)
+			if err != nil {
+				return nil, err
+			}
+			s, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("%v expects a string target, found %v", name, typeName(v))
+			}
+			values, err := resolveArgs(ctx, args)
+			if err != nil {
+				return nil, err
+			}
+			strs, err := stringArgs(name, values)
+			if err != nil {
+				return nil, err
+			}
+			return fn(s, strs)
+		}, nil
+	}
+}
+
+var methods = map[string]methodCtor{
+	"contains": func(target query, args []query) (query, error) {
+		if err := checkArgs("contains", args, 1); err != nil {
+			return nil, err
+		}
+		return func(ctx *context) (interface{}, error) {
+			v, err := target(ctx)
+			if err != nil {
+				return nil, err
+			}
+			arg, err := args[0](ctx)
+			if err != nil {
+				return nil, err
+			}
+			switch t := v.(type) {
+			case string:
+				argStr, ok := arg.(string)
+				if !ok {
+					return nil, fmt.Errorf("contains expects a string argument, found %v", typeName(arg))
+				}
+				return strings.Contains(t, argStr), nil
+			case []interface{}:
+				for _, e := range t {
+					if eq, _ := compare(tokEq, e, arg); eq.(bool) {
+						return true, nil
+					}
+				}
+				return false, nil
+			}
+			return nil, fmt.Errorf("contains expects a string or array target, found %v", typeName(v))
+		}, nil
+	},
+	"has_prefix": stringMethod("has_prefix", 1, func(s string, args []string) (interface{}, error) {
+		return strings.HasPrefix(s, args[0]), nil
+	}),
+	"has_suffix": stringMethod("has_suffix", 1, func(s string, args []string) (interface{}, error) {
+		return strings.HasSuffix(s, args[0]), nil
+	}),
+	"join": func(target query, args []query) (query, error) {
+		if err := checkArgs("join", args, 1); err != nil {
+			return nil, err
+		}
+		return func(ctx *context) (interface{}, error) {
+			v, err := target(ctx)
+			if err != nil {
+				return nil, err
+			}
+			arr, ok := v.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("join expects an array target, found %v", typeName(v))
+			}
+			values, err := resolveArgs(ctx, args)
+			if err != nil {
+				return nil, err
+			}
+			sep, err := stringArgs("join", values)
+			if err != nil {
+				return nil, err
+			}
+			strs := make([]string, len(arr))
+			for i, e := range arr {
+				strs[i] = toString(e)
+			}
+			return strings.Join(strs, sep[0]), nil
+		}, nil
+	},
+	"keys": func(target query, args []query) (query, error) {
+		if err := checkArgs("keys", args, 0); err != nil {
+			return nil, err
+		}
+		return func(ctx *context) (interface{}, error) {
+			v, err := target(ctx)
+			if err != nil {
+				return nil, err
+			}
+			obj, ok := v.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("keys expects an object target, found %v", typeName(v))
+			}
+			keys := make([]string, 0, len(obj))
+			for k := range obj {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			result := make([]interface{}, len(keys))
+			for i, k := range keys {
+				result[i] = k
+			}
+			return result, nil
+		}, nil
+	},
+	"length": func(target query, args []query) (query, error) {
+		if err := checkArgs("length", args, 0); err != nil {
+			return nil, err
+		}
+		return func(ctx *context) (interface{}, error) {
+			v, err := target(ctx)
+			if err != nil {
+				return nil, err
+			}
+			switch t := v.(type) {
+			case string:
+				return float64(len(t)), nil
+			case []interface{}:
+				return float64(len(t)), nil
+			case map[string]interface{}:
+				return float64(len(t)), nil
+			}
+			return nil, fmt.Errorf("length expects a string, array or object target, found %v", typeName(v))
+		}, nil
+	},
+	"lowercase": stringMethod("lowercase", 0, func(s string, args []string) (interface{}, error) {
+		return strings.ToLower(s), nil
+	}),
+	"number": func(target query, args []query) (query, error) {
+		if err := checkArgs("number", args, 0); err != nil {
+			return nil, err
+		}
+		return func(ctx *context) (interface{}, error) {
+			v, err := target(ctx)
+			if err != nil {
+				return nil, err
+			}
+			if f, ok := toNumber(v); ok {
+				return f, nil
+			}
+			s, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("number expects a string or number target, found %v", typeName(v))
+			}
+			f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse '%v' as a number", s)
+			}
+			return f, nil
+		}, nil
+	},
+	"or": func(target query, args []query) (query, error) {
+		if err := checkArgs("or", args, 1); err != nil {
+			return nil, err
+		}
+		return func(ctx *context) (interface{}, error) {
+			if v, err := target(ctx); err == nil && v != nil {
+				return v, nil
+			}
+			return args[0](ctx)
+		}, nil
+	},
+	"replace": stringMethod("replace", 2, func(s string, args []string) (interface{}, error) {
+		return strings.Replace(s, args[0], args[1], -1), nil
+	}),
+	"split": stringMethod("split", 1, func(s string, args []string) (interface{}, error) {
+		parts := strings.Split(s, args[0])
+		result := make([]interface{}, len(parts))
+		for i, p := range parts {
+			result[i] = p
+		}
+		return result, nil
+	}),
+	"string": func(target query, args []query) (query, error) {
+		if err := checkArgs("string", args, 0); err != nil {
+			return nil, err
+		}
+		return func(ctx *context) (interface{}, error) {
+			v, err := target(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return toString(v), nil
+		}, nil
+	},
+	"trim": stringMethod("trim", 0, func(s string, args []string) (interface{}, error) {
+		return strings.TrimSpace(s), nil
+	}),
+	"type": func(target query, args []query) (query, error) {
+		if err := checkArgs("type", args, 0); err != nil {
+			return nil, err
+		}
+		return func(ctx *context) (interface{}, error) {
+			v, err := target(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return typeName(v), nil
+		}, nil
+	},
+	"uppercase": stringMethod("uppercase", 0, func(s string, args []string) (interface{}, error) {
+		return strings.ToUpper(s), nil
+	}),
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,283 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mapping
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+//------------------------------------------------------------------------------
+
+type tokenType int
+
+const (
+	tokEOF tokenType = iota
+	tokNewline
+	tokIdent
+	tokNumber
+	tokString
+	tokAssign
+	tokDot
+	tokComma
+	tokColon
+	tokLParen
+	tokRParen
+	tokLBracket
+	tokRBracket
+	tokLBrace
+	tokRBrace
+	tokPlus
+	tokMinus
+	tokStar
+	tokSlash
+	tokPercent
+	tokBang
+	tokAnd
+	tokOr
+	tokEq
+	tokNeq
+	tokLt
+	tokLte
+	tokGt
+	tokGte
+)
+
+var tokenNames = map[tokenType]string{
+	tokEOF:      "end of input",
+	tokNewline:  "line break",
+	tokIdent:    "identifier",
+	tokNumber:   "number",
+	tokString:   "string",
+	tokAssign:   "'='",
+	tokDot:      "'.'",
+	tokComma:    "','",
+	tokColon:    "':'",
+	tokLParen:   "'('",
+	tokRParen:   "')'",
+	tokLBracket: "'['",
+	tokRBracket: "']'",
+	tokLBrace:   "'{'",
+	tokRBrace:   "'}'",
+	tokPlus:     "'+'",
+	tokMinus:    "'-'",
+	tokStar:     "'*'",
+	tokSlash:    "'/'",
+	tokPercent:  "'%'",
+	tokBang:     "'!'",
+	tokAnd:      "'&&'",
+	tokOr:       "'||'",
+	tokEq:       "'=='",
+	tokNeq:      "'!='",
+	tokLt:       "'<'",
+	tokLte:      "'<='",
+	tokGt:       "'>'",
+	tokGte:      "'>='",
+}
+
+func (t tokenType) String() string {
+	if n, exists := tokenNames[t]; exists {
+		return n
+	}
+	return "unknown token"
+}
+
+// token is a lexical unit of a mapping along with its position.
+type token struct {
+	typ   tokenType
+	value string
+	line  int
+	col   int
+}
+
+func (t token) String() string {
+	switch t.typ {
+	case tokIdent, tokNumber:
+		return fmt.Sprintf("'%v'", t.value)
+	case tokString:
+		return strconv.Quote(t.value)
+	}
+	return t.typ.String()
+}
+
+// ErrParse is an error that occurred whilst parsing a mapping, containing the
+// position of the error.
+type ErrParse struct {
+	Line int
+	Col  int
+	Err  string
+}
+
+// Error returns a human readable error string.
+func (e *ErrParse) Error() string {
+	return fmt.Sprintf("line %v char %v: %v", e.Line, e.Col, e.Err)
+}
+
+//------------------------------------------------------------------------------
+
+var punctuation = map[string]tokenType{
+	"&&": tokAnd,
+	"||": tokOr,
+	"==": tokEq,
+	"!=": tokNeq,
+	"<=": tokLte,
+	">=": tokGte,
+	"=":  tokAssign,
+	".":  tokDot,
+	",":  tokComma,
+	":":  tokColon,
+	"(":  tokLParen,
+	")":  tokRParen,
+	"[":  tokLBracket,
+	"]":  tokRBracket,
+	"{":  tokLBrace,
+	"}":  tokRBrace,
+	"+":  tokPlus,
+	"-":  tokMinus,
+	"*":  tokStar,
+	"/":  tokSlash,
+	"%":  tokPercent,
+	"!":  tokBang,
+	"<":  tokLt,
+	">":  tokGt,
+}
+
+// lex breaks a mapping down into a slice of tokens. Line breaks are only
+// emitted when they are not nested within brackets, braces or parentheses.
+func lex(input string) ([]token, error) {
+	var tokens []token
+	runes := []rune(input)
+	line, col := 1, 1
+	depth := 0
+
+	advance := func(n int) {
+		for i := 0; i < n; i++ {
+			if runes[i] == '\n' {
+				line++
+				col = 1
+			} else {
+				col++
+			}
+		}
+		runes = runes[n:]
+	}
+
+	for len(runes) > 0 {
+		r := runes[0]
+		switch {
+		case r == '\n':
+			if depth == 0 && len(tokens) > 0 && tokens[len(tokens)-1].typ != tokNewline {
+				tokens = append(tokens, token{typ: tokNewline, line: line, col: col})
+			}
+			advance(1)
+			continue
+		case unicode.IsSpace(r):
+			advance(1)
+			continue
+		case r == '#':
+			i := 0
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			advance(i)
+			continue
+		case r == '_' || unicode.IsLetter(r):
+			i := 1
+			for i < len(runes) && (runes[i] == '_' || unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i])) {
+				i++
+			}
+			tokens = append(tokens, token{typ: tokIdent, value: string(runes[:i]), line: line, col: col})
+			advance(i)
+			continue
+		case unicode.IsDigit(r):
+			i := 1
+			seenDot := false
+			for i < len(runes) {
+				if unicode.IsDigit(runes[i]) {
+					i++
+				} else if runes[i] == '.' && !seenDot && i+1 < len(runes) && unicode.IsDigit(runes[i+1]) {
+					seenDot = true
+					i++
+				} else {
+					break
+				}
+			}
+			tokens = append(tokens, token{typ: tokNumber, value: string(runes[:i]), line: line, col: col})
+			advance(i)
+			continue
+		case r == '"':
+			i := 1
+			escaped := false
+			for ; i < len(runes); i++ {
+				if escaped {
+					escaped = false
+				} else if runes[i] == '\\' {
+					escaped = true
+				} else if runes[i] == '"' || runes[i] == '\n' {
+					break
+				}
+			}
+			if i >= len(runes) || runes[i] != '"' {
+				return nil, &ErrParse{Line: line, Col: col, Err: "unterminated string literal"}
+			}
+			str, err := strconv.Unquote(string(runes[:i+1]))
+			if err != nil {
+				return nil, &ErrParse{Line: line, Col: col, Err: fmt.Sprintf("invalid string literal: %v", err)}
+			}
+			tokens = append(tokens, token{typ: tokString, value: str, line: line, col: col})
+			advance(i + 1)
+			continue
+		}
+
+		matched := false
+		for _, l := range []int{2, 1} {
+			if len(runes) < l {
+				continue
+			}
+			if typ, exists := punctuation[string(runes[:l])]; exists {
+				switch typ {
+				case tokLParen, tokLBracket, tokLBrace:
+					depth++
+				case tokRParen, tokRBracket, tokRBrace:
+					if depth > 0 {
+						depth--
+					}
+				}
+				tokens = append(tokens, token{typ: typ, value: string(runes[:l]), line: line, col: col})
+				advance(l)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return nil, &ErrParse{
+				Line: line, Col: col,
+				Err: fmt.Sprintf("unexpected character '%v'", strings.TrimSpace(string(r))),
+			}
+		}
+	}
+	tokens = append(tokens, token{typ: tokEOF, line: line, col: col})
+	return tokens, nil
+}
+
+//------------------------------------------------------------------------------
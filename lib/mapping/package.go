@@ -0,0 +1,34 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package mapping implements a small language for mapping the contents and
+// metadata of message parts into new documents. A mapping is a list of
+// assignments, each of which sets either a path of the resulting document or
+// a metadata key to the result of an expression:
+//
+//	root.id = this.user.id
+//	root.name = this.user.first_name + " " + this.user.last_name
+//	root.tier = if this.spend > 1000 { "gold" } else { "standard" }
+//	root.user = deleted()
+//	meta source = meta("kafka_topic").or("none").uppercase()
+//
+// Mappings are parsed once and can then be executed against any number of
+// message parts.
+package mapping
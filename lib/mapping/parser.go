@@ -0,0 +1,479 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mapping
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//------------------------------------------------------------------------------
+
+// parser is a recursive descent parser that compiles a list of tokens into
+// queries.
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.typ != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) errorAt(t token, format string, args ...interface{}) error {
+	return &ErrParse{
+		Line: t.line,
+		Col:  t.col,
+		Err:  fmt.Sprintf(format, args...),
+	}
+}
+
+func (p *parser) expect(typ tokenType) (token, error) {
+	t := p.next()
+	if t.typ != typ {
+		return t, p.errorAt(t, "expected %v, found %v", typ, t)
+	}
+	return t, nil
+}
+
+func (p *parser) skipNewlines() {
+	for p.peek().typ == tokNewline {
+		p.next()
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func (p *parser) parseStatements() ([]statement, error) {
+	var statements []statement
+	p.skipNewlines()
+	for p.peek().typ != tokEOF {
+		s, err := p.parseStatement()
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, s)
+		if t := p.next(); t.typ != tokNewline && t.typ != tokEOF {
+			return nil, p.errorAt(t, "expected line break, found %v", t)
+		}
+		p.skipNewlines()
+	}
+	return statements, nil
+}
+
+func (p *parser) parseStatement() (statement, error) {
+	var s statement
+
+	t := p.next()
+	s.line = t.line
+	if t.typ != tokIdent {
+		return s, p.errorAt(t, "expected assignment target, found %v", t)
+	}
+
+	switch t.value {
+	case "root":
+		s.target = targetRoot
+		for p.peek().typ == tokDot {
+			p.next()
+			segs, err := p.parseSegment()
+			if err != nil {
+				return s, err
+			}
+			s.path = append(s.path, segs...)
+		}
+	case "meta":
+		s.target = targetMeta
+		k := p.next()
+		if k.typ != tokIdent && k.typ != tokString {
+			return s, p.errorAt(k, "expected metadata key, found %v", k)
+		}
+		s.metaKey = k.value
+	default:
+		return s, p.errorAt(t, "expected assignment target 'root' or 'meta', found %v", t)
+	}
+
+	if _, err := p.expect(tokAssign); err != nil {
+		return s, err
+	}
+
+	var err error
+	s.value, err = p.parseExpression()
+	return s, err
+}
+
+// parseSegment parses a path segment following a dot, which can be an
+// identifier, a quoted string or an array index.
+func (p *parser) parseSegment() ([]string, error) {
+	t := p.next()
+	switch t.typ {
+	case tokIdent, tokString:
+		return []string{t.value}, nil
+	case tokNumber:
+		// Numbers such as `0.1` are lexed as a single token but represent two
+		// array indexes when used within a path.
+		return strings.Split(t.value, "."), nil
+	case tokMinus:
+		// Negative array indexes count backwards from the end of the array.
+		if n := p.peek(); n.typ == tokNumber && !strings.Contains(n.value, ".") {
+			p.next()
+			return []string{"-" + n.value}, nil
+		}
+	}
+	return nil, p.errorAt(t, "expected path segment, found %v", t)
+}
+
+//------------------------------------------------------------------------------
+
+func (p *parser) parseExpression() (query, error) {
+	return p.parseOr()
+}
+
+func (p *parser) parseOr() (query, error) {
+	lhs, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().typ == tokOr {
+		p.next()
+		rhs, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		lhs = logicalQuery(tokOr, lhs, rhs)
+	}
+	return lhs, nil
+}
+
+func (p *parser) parseAnd() (query, error) {
+	lhs, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().typ == tokAnd {
+		p.next()
+		rhs, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		lhs = logicalQuery(tokAnd, lhs, rhs)
+	}
+	return lhs, nil
+}
+
+func (p *parser) parseComparison() (query, error) {
+	lhs, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	switch op := p.peek().typ; op {
+	case tokEq, tokNeq, tokLt, tokLte, tokGt, tokGte:
+		p.next()
+		rhs, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		return binaryQuery(op, lhs, rhs), nil
+	}
+	return lhs, nil
+}
+
+func (p *parser) parseAdditive() (query, error) {
+	lhs, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek().typ
+		if op != tokPlus && op != tokMinus {
+			return lhs, nil
+		}
+		p.next()
+		rhs, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		lhs = binaryQuery(op, lhs, rhs)
+	}
+}
+
+func (p *parser) parseMultiplicative() (query, error) {
+	lhs, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek().typ
+		if op != tokStar && op != tokSlash && op != tokPercent {
+			return lhs, nil
+		}
+		p.next()
+		rhs, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		lhs = binaryQuery(op, lhs, rhs)
+	}
+}
+
+func (p *parser) parseUnary() (query, error) {
+	switch p.peek().typ {
+	case tokBang:
+		p.next()
+		target, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notQuery(target), nil
+	case tokMinus:
+		p.next()
+		target, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return negateQuery(target), nil
+	}
+	return p.parsePostfix()
+}
+
+func (p *parser) parsePostfix() (query, error) {
+	q, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().typ == tokDot {
+		p.next()
+		t := p.peek()
+		if t.typ == tokIdent && p.tokens[p.pos+1].typ == tokLParen {
+			p.next()
+			ctor, exists := methods[t.value]
+			if !exists {
+				return nil, p.errorAt(t, "unrecognised method '%v'", t.value)
+			}
+			args, err := p.parseArgs()
+			if err != nil {
+				return nil, err
+			}
+			if q, err = ctor(q, args); err != nil {
+				return nil, p.errorAt(t, "%v", err)
+			}
+			continue
+		}
+		segs, err := p.parseSegment()
+		if err != nil {
+			return nil, err
+		}
+		for _, seg := range segs {
+			q = fieldQuery(q, seg)
+		}
+	}
+	return q, nil
+}
+
+func (p *parser) parseArgs() ([]query, error) {
+	if _, err := p.expect(tokLParen); err != nil {
+		return nil, err
+	}
+	var args []query
+	for p.peek().typ != tokRParen {
+		arg, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if p.peek().typ != tokComma {
+			break
+		}
+		p.next()
+	}
+	if _, err := p.expect(tokRParen); err != nil {
+		return nil, err
+	}
+	return args, nil
+}
+
+func (p *parser) parsePrimary() (query, error) {
+	t := p.next()
+	switch t.typ {
+	case tokNumber:
+		f, err := strconv.ParseFloat(t.value, 64)
+		if err != nil {
+			return nil, p.errorAt(t, "invalid number: %v", err)
+		}
+		return literalQuery(f), nil
+	case tokString:
+		return literalQuery(t.value), nil
+	case tokLParen:
+		q, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		if _, err = p.expect(tokRParen); err != nil {
+			return nil, err
+		}
+		return q, nil
+	case tokLBracket:
+		return p.parseArray()
+	case tokLBrace:
+		return p.parseObject()
+	case tokIdent:
+		switch t.value {
+		case "true":
+			return literalQuery(true), nil
+		case "false":
+			return literalQuery(false), nil
+		case "null":
+			return literalQuery(nil), nil
+		case "this":
+			return thisQuery, nil
+		case "if":
+			return p.parseIf()
+		}
+		if p.peek().typ == tokLParen {
+			ctor, exists := functions[t.value]
+			if !exists {
+				return nil, p.errorAt(t, "unrecognised function '%v'", t.value)
+			}
+			args, err := p.parseArgs()
+			if err != nil {
+				return nil, err
+			}
+			q, err := ctor(args)
+			if err != nil {
+				return nil, p.errorAt(t, "%v", err)
+			}
+			return q, nil
+		}
+		return nil, p.errorAt(t, "unrecognised identifier %v, did you mean this.%v?", t, t.value)
+	}
+	return nil, p.errorAt(t, "expected expression, found %v", t)
+}
+
+func (p *parser) parseArray() (query, error) {
+	var elements []query
+	for p.peek().typ != tokRBracket {
+		e, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, e)
+		if p.peek().typ != tokComma {
+			break
+		}
+		p.next()
+	}
+	if _, err := p.expect(tokRBracket); err != nil {
+		return nil, err
+	}
+	return arrayQuery(elements), nil
+}
+
+func (p *parser) parseObject() (query, error) {
+	var keys []string
+	var values []query
+	for p.peek().typ != tokRBrace {
+		k := p.next()
+		if k.typ != tokString && k.typ != tokIdent {
+			return nil, p.errorAt(k, "expected object key, found %v", k)
+		}
+		if _, err := p.expect(tokColon); err != nil {
+			return nil, err
+		}
+		v, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k.value)
+		values = append(values, v)
+		if p.peek().typ != tokComma {
+			break
+		}
+		p.next()
+	}
+	if _, err := p.expect(tokRBrace); err != nil {
+		return nil, err
+	}
+	return objectQuery(keys, values), nil
+}
+
+// parseIf parses a conditional expression of the form:
+//
+// if <cond> { <expr> } else if <cond> { <expr> } else { <expr> }
+//
+// Where the else branches are optional.
+func (p *parser) parseIf() (query, error) {
+	var conditions, branches []query
+	for {
+		cond, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		branch, err := p.parseBlock()
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, cond)
+		branches = append(branches, branch)
+
+		// An else can follow on the next line, so we look past any line
+		// breaks and rewind if there isn't one.
+		pos := p.pos
+		p.skipNewlines()
+		if t := p.peek(); t.typ != tokIdent || t.value != "else" {
+			p.pos = pos
+			return ifQuery(conditions, branches, nil), nil
+		}
+		p.next()
+		if t := p.peek(); t.typ == tokIdent && t.value == "if" {
+			p.next()
+			continue
+		}
+		elseBranch, err := p.parseBlock()
+		if err != nil {
+			return nil, err
+		}
+		return ifQuery(conditions, branches, elseBranch), nil
+	}
+}
+
+func (p *parser) parseBlock() (query, error) {
+	if _, err := p.expect(tokLBrace); err != nil {
+		return nil, err
+	}
+	q, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	if _, err = p.expect(tokRBrace); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+//------------------------------------------------------------------------------
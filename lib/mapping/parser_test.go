@@ -0,0 +1,136 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mapping
+
+import (
+	"testing"
+)
+
+//------------------------------------------------------------------------------
+
+func TestParseErrors(t *testing.T) {
+	tests := map[string]struct {
+		mapping string
+		line    int
+		col     int
+		err     string
+	}{
+		"bad target": {
+			mapping: `root.foo = "bar"
+this.foo = "bar"`,
+			line: 2,
+			col:  1,
+			err:  "line 2 char 1: expected assignment target 'root' or 'meta', found 'this'",
+		},
+		"missing assignment": {
+			mapping: `root.foo "bar"`,
+			line:    1,
+			col:     10,
+			err:     `line 1 char 10: expected '=', found "bar"`,
+		},
+		"unknown function": {
+			mapping: `
+
+root.foo = nope()`,
+			line: 3,
+			col:  12,
+			err:  "line 3 char 12: unrecognised function 'nope'",
+		},
+		"unknown method": {
+			mapping: `root.foo = this.foo.nope()`,
+			line:    1,
+			col:     21,
+			err:     "line 1 char 21: unrecognised method 'nope'",
+		},
+		"wrong arg count": {
+			mapping: `root.foo = meta()`,
+			line:    1,
+			col:     12,
+			err:     "line 1 char 12: meta expects 1 arguments, received 0",
+		},
+		"unterminated string": {
+			mapping: `root.foo = "bar`,
+			line:    1,
+			col:     12,
+			err:     "line 1 char 12: unterminated string literal",
+		},
+		"bare identifier": {
+			mapping: `root.foo = foo`,
+			line:    1,
+			col:     12,
+			err:     "line 1 char 12: unrecognised identifier 'foo', did you mean this.foo?",
+		},
+		"two statements one line": {
+			mapping: `root.foo = "bar" root.bar = "baz"`,
+			line:    1,
+			col:     18,
+			err:     "line 1 char 18: expected line break, found 'root'",
+		},
+		"unclosed if": {
+			mapping: `root.foo = if this.bar { "baz"`,
+			line:    1,
+			col:     31,
+			err:     "line 1 char 31: expected '}', found end of input",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(tt *testing.T) {
+			_, err := Parse(test.mapping)
+			if err == nil {
+				tt.Fatal("Expected error")
+			}
+			perr, ok := err.(*ErrParse)
+			if !ok {
+				tt.Fatalf("Wrong error type: %T", err)
+			}
+			if exp, act := test.line, perr.Line; exp != act {
+				tt.Errorf("Wrong line: %v != %v", act, exp)
+			}
+			if exp, act := test.col, perr.Col; exp != act {
+				tt.Errorf("Wrong char: %v != %v", act, exp)
+			}
+			if exp, act := test.err, err.Error(); exp != act {
+				tt.Errorf("Wrong error: %v != %v", act, exp)
+			}
+		})
+	}
+}
+
+func TestParseComments(t *testing.T) {
+	e, err := Parse(`
+# This is a comment
+root.foo = "bar" # Trailing comment
+
+root.bar = [
+  "baz", # Comment within brackets
+  "qux",
+]
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := 2, len(e.statements); exp != act {
+		t.Errorf("Wrong count of statements: %v != %v", act, exp)
+	}
+}
+
+//------------------------------------------------------------------------------
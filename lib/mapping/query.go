@@ -0,0 +1,407 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mapping
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// context contains the state available to queries whilst a mapping is being
+// executed against a message part.
+type context struct {
+	index int
+	msg   types.Message
+
+	this         interface{}
+	thisErr      error
+	thisResolved bool
+}
+
+// getThis lazily parses the message part being mapped as JSON, using the
+// cached result of the message where possible.
+func (c *context) getThis() (interface{}, error) {
+	if !c.thisResolved {
+		c.thisResolved = true
+		if c.this, c.thisErr = c.msg.GetJSON(c.index); c.thisErr != nil {
+			c.thisErr = fmt.Errorf("failed to parse message part as JSON: %v", c.thisErr)
+		}
+	}
+	return c.this, c.thisErr
+}
+
+// query is a compiled expression that resolves a value from a context.
+type query func(ctx *context) (interface{}, error)
+
+//------------------------------------------------------------------------------
+
+// deletedValue is the result of the deleted function, and when assigned to a
+// target results in the target being removed.
+type deletedValue struct{}
+
+// nothingValue is the result of a conditional expression where no branches
+// matched, and when assigned to a target results in the assignment being
+// skipped.
+type nothingValue struct{}
+
+// ErrDivideByZero is returned when a division or modulo operation has a zero
+// right hand side.
+var ErrDivideByZero = errors.New("attempted to divide by zero")
+
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "bool"
+	case float64, int, int64, json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []byte:
+		return "bytes"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	case deletedValue:
+		return "deleted"
+	case nothingValue:
+		return "nothing"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func toNumber(v interface{}) (float64, bool) {
+	switch t := v.(type) {
+	case float64:
+		return t, true
+	case int:
+		return float64(t), true
+	case int64:
+		return float64(t), true
+	case json.Number:
+		f, err := t.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func toBool(v interface{}) (bool, error) {
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("expected bool value, found %v", typeName(v))
+	}
+	return b, nil
+}
+
+// toString converts a value into a string, where structured values are
+// serialised as JSON.
+func toString(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case []byte:
+		return string(t)
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(t)
+	}
+	if f, ok := toNumber(v); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(b)
+}
+
+// normalise converts numerical values to float64 so that they can be compared
+// regardless of their origin.
+func normalise(v interface{}) interface{} {
+	if f, ok := toNumber(v); ok {
+		return f
+	}
+	return v
+}
+
+//------------------------------------------------------------------------------
+
+func getField(v interface{}, field string) (interface{}, error) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		return t[field], nil
+	case []interface{}:
+		i, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, fmt.Errorf("cannot access field '%v' of array", field)
+		}
+		if i < 0 {
+			i = len(t) + i
+		}
+		if i < 0 || i >= len(t) {
+			return nil, nil
+		}
+		return t[i], nil
+	case nil:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("cannot access field '%v' of %v", field, typeName(v))
+}
+
+func fieldQuery(target query, field string) query {
+	return func(ctx *context) (interface{}, error) {
+		v, err := target(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return getField(v, field)
+	}
+}
+
+func literalQuery(v interface{}) query {
+	return func(ctx *context) (interface{}, error) {
+		return v, nil
+	}
+}
+
+func thisQuery(ctx *context) (interface{}, error) {
+	return ctx.getThis()
+}
+
+func arrayQuery(elements []query) query {
+	return func(ctx *context) (interface{}, error) {
+		arr := make([]interface{}, 0, len(elements))
+		for _, e := range elements {
+			v, err := e(ctx)
+			if err != nil {
+				return nil, err
+			}
+			switch v.(type) {
+			case deletedValue, nothingValue:
+				continue
+			}
+			arr = append(arr, v)
+		}
+		return arr, nil
+	}
+}
+
+func objectQuery(keys []string, values []query) query {
+	return func(ctx *context) (interface{}, error) {
+		obj := make(map[string]interface{}, len(keys))
+		for i, k := range keys {
+			v, err := values[i](ctx)
+			if err != nil {
+				return nil, err
+			}
+			switch v.(type) {
+			case deletedValue, nothingValue:
+				continue
+			}
+			obj[k] = v
+		}
+		return obj, nil
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func notQuery(target query) query {
+	return func(ctx *context) (interface{}, error) {
+		v, err := target(ctx)
+		if err != nil {
+			return nil, err
+		}
+		b, err := toBool(v)
+		if err != nil {
+			return nil, err
+		}
+		return !b, nil
+	}
+}
+
+func negateQuery(target query) query {
+	return func(ctx *context) (interface{}, error) {
+		v, err := target(ctx)
+		if err != nil {
+			return nil, err
+		}
+		f, ok := toNumber(v)
+		if !ok {
+			return nil, fmt.Errorf("cannot negate %v value", typeName(v))
+		}
+		return -f, nil
+	}
+}
+
+func logicalQuery(op tokenType, lhs, rhs query) query {
+	return func(ctx *context) (interface{}, error) {
+		v, err := lhs(ctx)
+		if err != nil {
+			return nil, err
+		}
+		l, err := toBool(v)
+		if err != nil {
+			return nil, err
+		}
+		// Short circuit when the left hand side decides the result.
+		if (op == tokAnd && !l) || (op == tokOr && l) {
+			return l, nil
+		}
+		if v, err = rhs(ctx); err != nil {
+			return nil, err
+		}
+		return toBool(v)
+	}
+}
+
+func arithmetic(op tokenType, l, r interface{}) (interface{}, error) {
+	if op == tokPlus {
+		lStr, lIsStr := l.(string)
+		rStr, rIsStr := r.(string)
+		if lIsStr && rIsStr {
+			return lStr + rStr, nil
+		}
+	}
+	lNum, lOk := toNumber(l)
+	rNum, rOk := toNumber(r)
+	if !lOk || !rOk {
+		return nil, fmt.Errorf(
+			"cannot apply %v to %v and %v values", op, typeName(l), typeName(r),
+		)
+	}
+	switch op {
+	case tokPlus:
+		return lNum + rNum, nil
+	case tokMinus:
+		return lNum - rNum, nil
+	case tokStar:
+		return lNum * rNum, nil
+	case tokSlash:
+		if rNum == 0 {
+			return nil, ErrDivideByZero
+		}
+		return lNum / rNum, nil
+	case tokPercent:
+		if int64(rNum) == 0 {
+			return nil, ErrDivideByZero
+		}
+		return float64(int64(lNum) % int64(rNum)), nil
+	}
+	return nil, fmt.Errorf("unsupported arithmetic operator %v", op)
+}
+
+func compare(op tokenType, l, r interface{}) (interface{}, error) {
+	switch op {
+	case tokEq:
+		return reflect.DeepEqual(normalise(l), normalise(r)), nil
+	case tokNeq:
+		return !reflect.DeepEqual(normalise(l), normalise(r)), nil
+	}
+
+	var cmp int
+	lNum, lOk := toNumber(l)
+	rNum, rOk := toNumber(r)
+	lStr, lIsStr := l.(string)
+	rStr, rIsStr := r.(string)
+	switch {
+	case lOk && rOk:
+		if lNum < rNum {
+			cmp = -1
+		} else if lNum > rNum {
+			cmp = 1
+		}
+	case lIsStr && rIsStr:
+		if lStr < rStr {
+			cmp = -1
+		} else if lStr > rStr {
+			cmp = 1
+		}
+	default:
+		return nil, fmt.Errorf(
+			"cannot compare %v and %v values with %v", typeName(l), typeName(r), op,
+		)
+	}
+
+	switch op {
+	case tokLt:
+		return cmp < 0, nil
+	case tokLte:
+		return cmp <= 0, nil
+	case tokGt:
+		return cmp > 0, nil
+	case tokGte:
+		return cmp >= 0, nil
+	}
+	return nil, fmt.Errorf("unsupported comparison operator %v", op)
+}
+
+func binaryQuery(op tokenType, lhs, rhs query) query {
+	return func(ctx *context) (interface{}, error) {
+		l, err := lhs(ctx)
+		if err != nil {
+			return nil, err
+		}
+		r, err := rhs(ctx)
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case tokPlus, tokMinus, tokStar, tokSlash, tokPercent:
+			return arithmetic(op, l, r)
+		}
+		return compare(op, l, r)
+	}
+}
+
+func ifQuery(conditions []query, branches []query, elseBranch query) query {
+	return func(ctx *context) (interface{}, error) {
+		for i, cond := range conditions {
+			v, err := cond(ctx)
+			if err != nil {
+				return nil, err
+			}
+			b, err := toBool(v)
+			if err != nil {
+				return nil, err
+			}
+			if b {
+				return branches[i](ctx)
+			}
+		}
+		if elseBranch != nil {
+			return elseBranch(ctx)
+		}
+		return nothingValue{}, nil
+	}
+}
+
+//------------------------------------------------------------------------------
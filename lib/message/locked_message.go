@@ -119,6 +119,16 @@ func (m *lockedMessage) SetJSON(part int, jObj interface{}) error {
 	return nil
 }
 
+func (m *lockedMessage) GetError(part int) error {
+	if part != 0 && part != -1 {
+		return nil
+	}
+	return m.m.GetError(m.part)
+}
+
+func (m *lockedMessage) SetError(part int, err error) {
+}
+
 func (m *lockedMessage) LazyCondition(label string, cond types.Condition) bool {
 	return m.m.LazyCondition(label, cond)
 }
@@ -21,6 +21,7 @@
 package message
 
 import (
+	"errors"
 	"reflect"
 	"testing"
 
@@ -179,3 +180,25 @@ func TestLockedMessageConditionCaching(t *testing.T) {
 		t.Errorf("Wrong count of calls for cond 2: %v != %v", act, exp)
 	}
 }
+
+func TestLockedMessageErrors(t *testing.T) {
+	msg := New([][]byte{
+		[]byte(`foo`),
+		[]byte(`bar`),
+	})
+	errTest := errors.New("test err")
+	msg.SetError(1, errTest)
+
+	lMsg := Lock(msg, 1)
+	if exp, act := errTest, lMsg.GetError(0); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+	if exp, act := error(nil), lMsg.GetError(1); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+
+	lMsg.SetError(0, nil)
+	if exp, act := errTest, msg.GetError(1); exp != act {
+		t.Errorf("Locked message modified error: %v != %v", act, exp)
+	}
+}
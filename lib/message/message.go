@@ -95,6 +95,7 @@ type Type struct {
 	createdAt   time.Time
 	parts       [][]byte
 	partCaches  []*partCache
+	partErrors  []error
 	resultCache map[string]bool
 	metadata    map[string]string
 }
@@ -182,6 +183,7 @@ func (m *Type) ShallowCopy() types.Message {
 	return &Type{
 		createdAt:   m.createdAt,
 		parts:       append([][]byte(nil), m.parts...),
+		partErrors:  append([]error(nil), m.partErrors...),
 		resultCache: m.resultCache,
 		partCaches:  newPartCaches,
 		metadata:    metadata,
@@ -205,9 +207,10 @@ func (m *Type) DeepCopy() types.Message {
 		newParts[i] = np
 	}
 	return &Type{
-		createdAt: m.createdAt,
-		parts:     newParts,
-		metadata:  metadata,
+		createdAt:  m.createdAt,
+		parts:      newParts,
+		partErrors: append([]error(nil), m.partErrors...),
+		metadata:   metadata,
 	}
 }
 
@@ -285,6 +288,7 @@ func (m *Type) Set(index int, b []byte) {
 // SetAll changes the entire set of message parts.
 func (m *Type) SetAll(p [][]byte) {
 	m.parts = p
+	m.partErrors = nil
 	m.clearAllCaches()
 }
 
@@ -374,6 +378,39 @@ func (m *Type) SetJSON(part int, jObj interface{}) error {
 	return nil
 }
 
+// GetError returns the error that a message part has been flagged with, or nil
+// if the part has not failed processing. Indexes can be negative.
+func (m *Type) GetError(part int) error {
+	if part < 0 {
+		part = len(m.parts) + part
+	}
+	if part < 0 || part >= len(m.partErrors) {
+		return nil
+	}
+	return m.partErrors[part]
+}
+
+// SetError flags a message part as having failed processing, a nil error
+// clears the flag. Indexes can be negative.
+func (m *Type) SetError(part int, err error) {
+	if part < 0 {
+		part = len(m.parts) + part
+	}
+	if part < 0 || part >= len(m.parts) {
+		return
+	}
+	if len(m.partErrors) <= part {
+		if err == nil {
+			return
+		}
+		errs := make([]error, part+1)
+		copy(errs, m.partErrors)
+		m.partErrors = errs
+	}
+	m.partErrors[part] = err
+	m.clearGeneralCaches()
+}
+
 // LazyCondition resolves a particular condition on the message, if the
 // condition has already been applied to this message the cached result is
 // returned instead. When a message is altered in any way the conditions cache
@@ -21,6 +21,7 @@
 package message
 
 import (
+	"errors"
 	"reflect"
 	"testing"
 
@@ -209,6 +210,65 @@ func TestMessageMetadata(t *testing.T) {
 	}
 }
 
+func TestMessageErrors(t *testing.T) {
+	m := New([][]byte{
+		[]byte(`foo`),
+		[]byte(`bar`),
+		[]byte(`baz`),
+	})
+
+	for i := -3; i < 3; i++ {
+		if err := m.GetError(i); err != nil {
+			t.Errorf("Unexpected error on part %v: %v", i, err)
+		}
+	}
+
+	errA, errB := errors.New("error a"), errors.New("error b")
+	m.SetError(1, errA)
+	m.SetError(-1, errB)
+	m.SetError(5, errA)
+
+	if exp, act := error(nil), m.GetError(0); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+	if exp, act := errA, m.GetError(1); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+	if exp, act := errA, m.GetError(-2); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+	if exp, act := errB, m.GetError(2); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+	if exp, act := error(nil), m.GetError(5); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+
+	m2 := m.ShallowCopy()
+	m2.SetError(1, nil)
+	if exp, act := error(nil), m2.GetError(1); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+	if exp, act := errA, m.GetError(1); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+
+	m3 := m.DeepCopy()
+	if exp, act := errB, m3.GetError(2); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+
+	m.Set(2, []byte(`changed`))
+	if exp, act := errB, m.GetError(2); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+
+	m.SetAll([][]byte{[]byte(`foo`), []byte(`bar`)})
+	if exp, act := error(nil), m.GetError(1); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+}
+
 func TestMessageShallowCopy(t *testing.T) {
 	m := New([][]byte{
 		[]byte(`foo`),
@@ -70,7 +70,7 @@ func CloneGeneric(root interface{}) (interface{}, error) {
 		return cloneCheekyMap(t)
 	case []interface{}:
 		return cloneSlice(t)
-	case string, json.Number, int, int64, float64, bool, json.RawMessage:
+	case string, json.Number, int, int64, float64, bool, json.RawMessage, nil:
 		return t, nil
 	default:
 		return nil, fmt.Errorf("unrecognised generic type: %T", t)
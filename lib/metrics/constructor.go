@@ -39,14 +39,20 @@ var (
 
 //------------------------------------------------------------------------------
 
-// typeSpec is a constructor and a usage description for each metric output
+// TypeSpec is a constructor and a usage description for each metric output
 // type.
-type typeSpec struct {
+type TypeSpec struct {
 	constructor func(conf Config, opts ...func(Type)) (Type, error)
 	description string
 }
 
-var constructors = map[string]typeSpec{}
+// Description returns the documentation of the metric output type.
+func (t TypeSpec) Description() string {
+	return t.description
+}
+
+// Constructors is a map of all metrics types with their specs.
+var Constructors = map[string]TypeSpec{}
 
 //------------------------------------------------------------------------------
 
@@ -116,7 +122,7 @@ func OptSetLogger(log log.Modular) func(Type) {
 func Descriptions() string {
 	// Order our input types alphabetically
 	names := []string{}
-	for name := range constructors {
+	for name := range Constructors {
 		names = append(names, name)
 	}
 	sort.Strings(names)
@@ -133,7 +139,7 @@ func Descriptions() string {
 		buf.WriteString("## ")
 		buf.WriteString("`" + name + "`")
 		buf.WriteString("\n")
-		buf.WriteString(constructors[name].description)
+		buf.WriteString(Constructors[name].description)
 		if i != (len(names) - 1) {
 			buf.WriteString("\n\n")
 		}
@@ -146,7 +152,7 @@ func New(conf Config, opts ...func(Type)) (Type, error) {
 	if conf.Type == "none" {
 		return DudType{}, nil
 	}
-	if c, ok := constructors[conf.Type]; ok {
+	if c, ok := Constructors[conf.Type]; ok {
 		return c.constructor(conf, opts...)
 	}
 	return nil, ErrInvalidMetricOutputType
@@ -34,7 +34,7 @@ import (
 //------------------------------------------------------------------------------
 
 func init() {
-	constructors[TypeHTTPServer] = typeSpec{
+	Constructors[TypeHTTPServer] = TypeSpec{
 		constructor: NewHTTP,
 		description: `
 Benthos can host its own stats endpoint, where a GET request will receive a JSON
@@ -33,7 +33,7 @@ import (
 //------------------------------------------------------------------------------
 
 func init() {
-	constructors[TypePrometheus] = typeSpec{
+	Constructors[TypePrometheus] = TypeSpec{
 		constructor: NewPrometheus,
 		description: `Host endpoints for Prometheus scraping.`,
 	}
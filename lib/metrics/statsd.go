@@ -33,7 +33,7 @@ import (
 //------------------------------------------------------------------------------
 
 func init() {
-	constructors[TypeStatsd] = typeSpec{
+	Constructors[TypeStatsd] = TypeSpec{
 		constructor: NewStatsd,
 		description: `Use the statsd protocol.`,
 	}
@@ -52,6 +52,7 @@ func NewAMQP(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type
 	}
 	return NewWriter(
 		"amqp", a, log, stats,
+		OptWriterSetRetries(conf.AMQP.Retry),
 	)
 }
 
@@ -53,6 +53,11 @@ type TypeSpec struct {
 	sanitiseConfigFunc func(conf Config) (interface{}, error)
 }
 
+// Description returns the documentation of the output type.
+func (t TypeSpec) Description() string {
+	return t.description
+}
+
 // Constructors is a map of all output types with their specs.
 var Constructors = map[string]TypeSpec{}
 
@@ -77,6 +82,7 @@ const (
 	TypeNSQ           = "nsq"
 	TypeRedisList     = "redis_list"
 	TypeRedisPubSub   = "redis_pubsub"
+	TypeRollingFile   = "rolling_file"
 	TypeS3            = "s3"
 	TypeSQS           = "sqs"
 	TypeSTDOUT        = "stdout"
@@ -108,6 +114,7 @@ type Config struct {
 	NSQ           NSQConfig                  `json:"nsq" yaml:"nsq"`
 	RedisList     writer.RedisListConfig     `json:"redis_list" yaml:"redis_list"`
 	RedisPubSub   RedisPubSubConfig          `json:"redis_pubsub" yaml:"redis_pubsub"`
+	RollingFile   RollingFileConfig          `json:"rolling_file" yaml:"rolling_file"`
 	S3            writer.AmazonS3Config      `json:"s3" yaml:"s3"`
 	SQS           writer.AmazonSQSConfig     `json:"sqs" yaml:"sqs"`
 	STDOUT        STDOUTConfig               `json:"stdout" yaml:"stdout"`
@@ -137,6 +144,7 @@ func NewConfig() Config {
 		NSQ:           NewNSQConfig(),
 		RedisList:     writer.NewRedisListConfig(),
 		RedisPubSub:   NewRedisPubSubConfig(),
+		RollingFile:   NewRollingFileConfig(),
 		S3:            writer.NewAmazonS3Config(),
 		SQS:           writer.NewAmazonSQSConfig(),
 		STDOUT:        NewSTDOUTConfig(),
@@ -240,7 +248,26 @@ is a processor that drops messages if the condition does not pass. Conditions
 are content aware logical operators that can be combined using boolean logic.
 
 For more information regarding conditions, including a full list of available
-conditions please [read the docs here](../conditions/README.md)`
+conditions please [read the docs here](../conditions/README.md)
+
+### Retries
+
+Outputs that contain a ` + "`retry`" + ` section will attempt to resend a
+message that failed to send up to ` + "`max_retries`" + ` times before
+giving up, waiting between attempts with an exponential backoff that starts at
+` + "`initial_period_ms`" + ` and is capped at ` + "`max_period_ms`" + `. A
+` + "`jitter`" + ` value above zero adds a random amount (up to that fraction
+of the period) to each wait.
+
+Once the retries are exhausted the ` + "`on_failure`" + ` field determines
+what happens to the message. With ` + "`nack`" + ` (the default) the failure is
+propagated back to the input, and with ` + "`drop`" + ` the message is
+acknowledged and discarded. A ` + "`max_retries`" + ` of zero means the
+failure is propagated immediately.
+
+A message that fails to send because the connection was lost is resent once
+after reconnecting, and each further attempt to reconnect and resend it counts
+as a retry.`
 
 // Descriptions returns a formatted string of collated descriptions of each
 // type.
@@ -48,6 +48,7 @@ func NewElasticsearch(conf Config, mgr types.Manager, log log.Modular, stats met
 	}
 	return NewWriter(
 		"elasticsearch", elasticWriter, log, stats,
+		OptWriterSetRetries(conf.Elasticsearch.Retry),
 	)
 }
 
@@ -38,7 +38,32 @@ Writes each individual part of each message to a new file.
 Message parts only contain raw data, and therefore in order to create a unique
 file for each part you need to generate unique file names. This can be done by
 using function interpolations on the ` + "`path`" + ` field as described
-[here](../config_interpolation.md#functions).`,
+[here](../config_interpolation.md#functions).
+
+### Parquet
+
+When ` + "`encoding`" + ` is set to ` + "`parquet`" + ` each message is
+written as a single file in the [Parquet](https://parquet.apache.org/) format,
+where each part of the message is a JSON object that becomes a row. This should
+be combined with batching, e.g. with the
+[` + "`batch`" + ` processor](../processors/README.md#batch), in order to write
+files with many rows.
+
+Columns can be declared with ` + "`parquet.schema`" + `, where each column has
+a ` + "`name`" + ` and a ` + "`type`" + ` of ` + "`boolean`" + `,
+` + "`int64`" + `, ` + "`double`" + ` or ` + "`utf8`" + `. If the schema is
+empty then it is inferred from the top level fields of the first row written,
+and is reused for all subsequent files. All columns are optional, fields
+missing from a row are written as nulls and nested values are written as JSON
+strings within ` + "`utf8`" + ` columns. The ` + "`parquet.compression`" + `
+field can be one of ` + "`none`" + `, ` + "`snappy`" + ` or ` + "`gzip`" + `.
+
+A message containing a part that cannot be encoded, either because it is not a
+JSON object or because a field does not match the type of its column, fails to
+be written as a whole. Since such a message can never succeed it is retried
+indefinitely by default, and should either be routed elsewhere with a
+[dead letter output](../dead_letter.md) or dropped by setting
+` + "`retry.on_failure`" + ` to ` + "`drop`" + `.`,
 	}
 }
 
@@ -46,8 +71,13 @@ using function interpolations on the ` + "`path`" + ` field as described
 
 // NewFiles creates a new Files output type.
 func NewFiles(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	w, err := writer.NewFiles(conf.Files, log, stats)
+	if err != nil {
+		return nil, err
+	}
 	return NewWriter(
-		"files", writer.NewFiles(conf.Files, log, stats), log, stats,
+		"files", w, log, stats,
+		OptWriterSetRetries(conf.Files.Retry),
 	)
 }
 
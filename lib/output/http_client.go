@@ -61,7 +61,10 @@ func NewHTTPClient(conf Config, mgr types.Manager, log log.Modular, stats metric
 	if err != nil {
 		return nil, err
 	}
-	return NewWriter("http_client", h, log, stats)
+	return NewWriter(
+		"http_client", h, log, stats,
+		OptWriterSetRetries(conf.HTTPClient.Retry),
+	)
 }
 
 //------------------------------------------------------------------------------
@@ -45,10 +45,26 @@ If the field 'key' is not empty then each message will be given its contents as
 a key. This field can be dynamically set using function interpolations described
 [here](../config_interpolation.md#functions).
 
-By default the paritioner will select partitions based on a hash of the key
-value. If the key is empty then a partition is chosen at random. You can
-alternatively force the partitioner to round-robin partitions with the field
-` + "`round_robin_partitions`" + `.`,
+The field ` + "`partitioner`" + ` selects how messages are assigned to
+partitions, and can be one of ` + "`hash`" + `, ` + "`random`" + `,
+` + "`round_robin`" + ` or ` + "`manual`" + `. The ` + "`hash`" + ` partitioner
+selects partitions based on a hash of the key value, and if the key is empty
+then a partition is chosen at random. The ` + "`manual`" + ` partitioner writes
+messages to the partition given by the field ` + "`partition`" + `, which can be
+dynamically set using function interpolations, e.g.
+` + "`${!metadata:kafka_partition}`" + `. The field
+` + "`round_robin_partitions`" + ` is deprecated and when set overrides the
+partitioner with ` + "`round_robin`" + `.
+
+### Metadata
+
+When ` + "`metadata_headers.enabled`" + ` is true the metadata of messages is
+written as Kafka record headers, which requires a
+` + "`target_version`" + ` of at least 0.11.0.0. The headers can be limited to
+specific metadata keys with ` + "`metadata_headers.keys`" + `, and to keys
+beginning with any of ` + "`metadata_headers.prefixes`" + `. When neither are
+set all metadata is written, including the ` + "`kafka_`" + ` prefixed fields
+added by Kafka inputs.`,
 	}
 }
 
@@ -62,6 +78,7 @@ func NewKafka(conf Config, mgr types.Manager, log log.Modular, stats metrics.Typ
 	}
 	return NewWriter(
 		"kafka", k, log, stats,
+		OptWriterSetRetries(conf.Kafka.Retry),
 	)
 }
 
@@ -45,7 +45,10 @@ func NewMQTT(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type
 	if err != nil {
 		return nil, err
 	}
-	return NewWriter("mqtt", w, log, stats)
+	return NewWriter(
+		"mqtt", w, log, stats,
+		OptWriterSetRetries(conf.MQTT.Retry),
+	)
 }
 
 //------------------------------------------------------------------------------
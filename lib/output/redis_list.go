@@ -46,7 +46,10 @@ func NewRedisList(conf Config, mgr types.Manager, log log.Modular, stats metrics
 	if err != nil {
 		return nil, err
 	}
-	return NewWriter("redis_list", w, log, stats)
+	return NewWriter(
+		"redis_list", w, log, stats,
+		OptWriterSetRetries(conf.RedisList.Retry),
+	)
 }
 
 //------------------------------------------------------------------------------
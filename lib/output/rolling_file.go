@@ -0,0 +1,342 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/text"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeRollingFile] = TypeSpec{
+		constructor: NewRollingFile,
+		description: `
+The rolling_file output type appends messages to a file in the same way as the
+` + "[`file`](#file)" + ` output, but rotates that file once it grows beyond
+` + "`max_bytes`" + `, once it has been open longer than ` + "`max_age_ms`" + `, or when
+the resolved ` + "`path`" + ` changes. Setting either of the size or age fields to
+zero disables that trigger.
+
+The ` + "`path`" + ` field supports
+[function interpolations](../config_interpolation.md#functions), which are
+resolved each time a message is written. Since the path is resolved without
+the contents of the message this is mostly useful for time based paths, e.g.
+` + "`./logs/${!timestamp:2006-01-02}.log`" + ` begins a new file each day.
+
+When the file is rotated due to its size or age it is renamed by suffixing the
+path with the time of rotation, e.g.
+` + "`./logs/app.log.20181016T101500.000000000`" + `, and a fresh file is opened at the
+original path. When the path changes the previous file is simply closed.
+
+Old files are pruned after each rotation according to ` + "`max_files`" + `, which
+sets the number of files kept besides the current one, and ` + "`retention_ms`" + `,
+which removes files that were last modified longer ago than the period.
+Candidates for pruning are all files matching the path with each interpolation
+replaced by a wildcard, along with the rotated versions of those files, so make
+sure that the path is specific enough not to match files written by anything
+else. Setting both fields to zero disables pruning.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// RollingFileConfig contains configuration fields for the rolling_file output
+// type.
+type RollingFileConfig struct {
+	Path        string `json:"path" yaml:"path"`
+	Delim       string `json:"delimiter" yaml:"delimiter"`
+	MaxBytes    int64  `json:"max_bytes" yaml:"max_bytes"`
+	MaxAgeMS    int    `json:"max_age_ms" yaml:"max_age_ms"`
+	MaxFiles    int    `json:"max_files" yaml:"max_files"`
+	RetentionMS int    `json:"retention_ms" yaml:"retention_ms"`
+}
+
+// NewRollingFileConfig creates a new RollingFileConfig with default values.
+func NewRollingFileConfig() RollingFileConfig {
+	return RollingFileConfig{
+		Path:        "",
+		Delim:       "",
+		MaxBytes:    100 * 1024 * 1024,
+		MaxAgeMS:    0,
+		MaxFiles:    0,
+		RetentionMS: 0,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewRollingFile creates a new RollingFile output type.
+func NewRollingFile(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	handle, err := newRollingFile(conf.RollingFile, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewLineWriter(handle, true, []byte(conf.RollingFile.Delim), TypeRollingFile, log, stats)
+}
+
+//------------------------------------------------------------------------------
+
+// rollingFileTimeFormat is the format of the suffix added to rotated files.
+const rollingFileTimeFormat = "20060102T150405.000000000"
+
+var rollingFileInterpRegex = regexp.MustCompile(`\${![a-z_]+(:[^}]+)?}`)
+
+// rollingFile is an io.WriteCloser that appends to a file, rotating and
+// pruning files as it goes. Rotations are only checked on calls to Write, and
+// so each call is expected to contain a whole message, which the LineWriter
+// guarantees.
+type rollingFile struct {
+	conf RollingFileConfig
+
+	pathBytes       []byte
+	interpolatePath bool
+	globs           []string
+
+	file   *os.File
+	path   string
+	size   int64
+	opened time.Time
+
+	now func() time.Time
+
+	log log.Modular
+
+	mRotated  metrics.StatCounter
+	mPruned   metrics.StatCounter
+	mPruneErr metrics.StatCounter
+}
+
+func newRollingFile(conf RollingFileConfig, log log.Modular, stats metrics.Type) (*rollingFile, error) {
+	if len(conf.Path) == 0 {
+		return nil, errors.New("a path must be specified")
+	}
+	if conf.MaxBytes < 0 {
+		return nil, fmt.Errorf("max_bytes must not be negative, got: %v", conf.MaxBytes)
+	}
+
+	pathBytes := []byte(conf.Path)
+	pattern := rollingFileInterpRegex.ReplaceAllLiteral(
+		[]byte(rollingFileGlobEscape(conf.Path)), []byte("*"),
+	)
+
+	return &rollingFile{
+		conf:            conf,
+		pathBytes:       pathBytes,
+		interpolatePath: text.ContainsFunctionVariables(pathBytes),
+		globs:           []string{string(pattern), string(pattern) + ".*"},
+		now:             time.Now,
+		log:             log.NewModule(".output.rolling_file"),
+		mRotated:        stats.GetCounter("output.rolling_file.rotated"),
+		mPruned:         stats.GetCounter("output.rolling_file.pruned"),
+		mPruneErr:       stats.GetCounter("output.rolling_file.prune.error"),
+	}, nil
+}
+
+// rollingFileGlobEscape escapes characters of a path that would otherwise be
+// interpreted as part of a glob pattern. Interpolation functions are left
+// intact so that they can be replaced with wildcards.
+func rollingFileGlobEscape(path string) string {
+	var escaped []byte
+	last := 0
+	for _, loc := range rollingFileInterpRegex.FindAllStringIndex(path, -1) {
+		escaped = append(escaped, rollingFileGlobEscapeLiteral(path[last:loc[0]])...)
+		escaped = append(escaped, path[loc[0]:loc[1]]...)
+		last = loc[1]
+	}
+	return string(append(escaped, rollingFileGlobEscapeLiteral(path[last:])...))
+}
+
+func rollingFileGlobEscapeLiteral(literal string) []byte {
+	escaped := make([]byte, 0, len(literal))
+	for i := 0; i < len(literal); i++ {
+		switch literal[i] {
+		case '*', '?', '[', '\\':
+			escaped = append(escaped, '\\')
+		}
+		escaped = append(escaped, literal[i])
+	}
+	return escaped
+}
+
+//------------------------------------------------------------------------------
+
+func (r *rollingFile) resolvePath() string {
+	if !r.interpolatePath {
+		return r.conf.Path
+	}
+	return string(text.ReplaceFunctionVariables(message.New(nil), r.pathBytes))
+}
+
+func (r *rollingFile) open(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.FileMode(0777)); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, os.FileMode(0666))
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	r.file = file
+	r.path = path
+	r.size = info.Size()
+	r.opened = r.now()
+	return nil
+}
+
+// rotate closes the current file and, when the path has not changed, moves it
+// aside so that a fresh file can be opened at the same path.
+func (r *rollingFile) rotate(rename bool) error {
+	err := r.file.Close()
+	r.file = nil
+	if err != nil {
+		return err
+	}
+	if rename {
+		rotated := r.path + "." + r.now().UTC().Format(rollingFileTimeFormat)
+		if err = os.Rename(r.path, rotated); err != nil {
+			return err
+		}
+		r.log.Debugf("Rotated file '%v' to '%v'\n", r.path, rotated)
+	} else {
+		r.log.Debugf("Closed file '%v' due to path change\n", r.path)
+	}
+	r.mRotated.Incr(1)
+	r.prune()
+	return nil
+}
+
+// prune removes old files that exceed either the max_files count or the
+// retention period.
+func (r *rollingFile) prune() {
+	if r.conf.MaxFiles <= 0 && r.conf.RetentionMS <= 0 {
+		return
+	}
+
+	type candidate struct {
+		path    string
+		modTime time.Time
+	}
+
+	seen := map[string]struct{}{}
+	candidates := []candidate{}
+	for _, glob := range r.globs {
+		matches, err := filepath.Glob(glob)
+		if err != nil {
+			r.log.Errorf("Failed to list files for pruning: %v\n", err)
+			r.mPruneErr.Incr(1)
+			return
+		}
+		for _, match := range matches {
+			if _, exists := seen[match]; exists {
+				continue
+			}
+			seen[match] = struct{}{}
+			if r.file != nil && match == r.path {
+				continue
+			}
+			info, err := os.Stat(match)
+			if err != nil || info.IsDir() {
+				continue
+			}
+			candidates = append(candidates, candidate{
+				path:    match,
+				modTime: info.ModTime(),
+			})
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].modTime.After(candidates[j].modTime)
+	})
+
+	cutoff := r.now().Add(-time.Duration(r.conf.RetentionMS) * time.Millisecond)
+	for i, c := range candidates {
+		if (r.conf.MaxFiles <= 0 || i < r.conf.MaxFiles) &&
+			(r.conf.RetentionMS <= 0 || c.modTime.After(cutoff)) {
+			continue
+		}
+		if err := os.Remove(c.path); err != nil {
+			r.log.Errorf("Failed to prune file '%v': %v\n", c.path, err)
+			r.mPruneErr.Incr(1)
+			continue
+		}
+		r.log.Debugf("Pruned file '%v'\n", c.path)
+		r.mPruned.Incr(1)
+	}
+}
+
+// Write appends p to the current file, rotating it first if required.
+func (r *rollingFile) Write(p []byte) (int, error) {
+	path := r.resolvePath()
+
+	if r.file != nil {
+		var err error
+		if path != r.path {
+			err = r.rotate(false)
+		} else if r.conf.MaxBytes > 0 && r.size > 0 && r.size+int64(len(p)) > r.conf.MaxBytes {
+			err = r.rotate(true)
+		} else if r.conf.MaxAgeMS > 0 && r.now().Sub(r.opened) >= time.Duration(r.conf.MaxAgeMS)*time.Millisecond {
+			err = r.rotate(true)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if r.file == nil {
+		if err := r.open(path); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Close closes the current file without rotating it.
+func (r *rollingFile) Close() error {
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,395 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+type rollingFileClock struct {
+	t time.Time
+}
+
+func (c *rollingFileClock) now() time.Time {
+	return c.t
+}
+
+func (c *rollingFileClock) advance(d time.Duration) {
+	c.t = c.t.Add(d)
+}
+
+func newTestRollingFile(t *testing.T, conf RollingFileConfig) (*rollingFile, *rollingFileClock) {
+	t.Helper()
+	r, err := newRollingFile(conf, log.New(os.Stdout, logConfig), metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := &rollingFileClock{t: time.Date(2018, 10, 16, 10, 0, 0, 0, time.UTC)}
+	r.now = clock.now
+	return r, clock
+}
+
+func listRollingFiles(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{}
+	for _, info := range infos {
+		b, err := ioutil.ReadFile(filepath.Join(dir, info.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[info.Name()] = string(b)
+	}
+	return files
+}
+
+func writeRollingFile(t *testing.T, r *rollingFile, data string) {
+	t.Helper()
+	if _, err := r.Write([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func TestRollingFileBadConfig(t *testing.T) {
+	conf := NewRollingFileConfig()
+	if _, err := newRollingFile(conf, log.New(os.Stdout, logConfig), metrics.DudType{}); err == nil {
+		t.Error("Expected error from empty path")
+	}
+
+	conf.Path = "foo.log"
+	conf.MaxBytes = -1
+	if _, err := newRollingFile(conf, log.New(os.Stdout, logConfig), metrics.DudType{}); err == nil {
+		t.Error("Expected error from negative max_bytes")
+	}
+}
+
+func TestRollingFileGlob(t *testing.T) {
+	tests := map[string][]string{
+		"/foo/bar.log":                           {"/foo/bar.log", "/foo/bar.log.*"},
+		"/foo/${!timestamp:2006-01-02}.log":      {"/foo/*.log", "/foo/*.log.*"},
+		"/foo[1]/${!hostname}-${!count:foo}.log": {"/foo\\[1]/*-*.log", "/foo\\[1]/*-*.log.*"},
+	}
+	for path, exp := range tests {
+		conf := NewRollingFileConfig()
+		conf.Path = path
+		r, _ := newTestRollingFile(t, conf)
+		if act := r.globs; act[0] != exp[0] || act[1] != exp[1] {
+			t.Errorf("Wrong globs for '%v': %v != %v", path, act, exp)
+		}
+	}
+}
+
+func TestRollingFileMaxBytes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_rolling_file_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	conf := NewRollingFileConfig()
+	conf.Path = filepath.Join(dir, "app.log")
+	conf.MaxBytes = 10
+
+	r, clock := newTestRollingFile(t, conf)
+
+	writeRollingFile(t, r, "hello\n")
+	writeRollingFile(t, r, "world\n")
+	clock.advance(time.Second)
+	writeRollingFile(t, r, "this is too big\n")
+	clock.advance(time.Second)
+	writeRollingFile(t, r, "foo\n")
+	if err = r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	act := listRollingFiles(t, dir)
+	exp := map[string]string{
+		"app.log.20181016T100000.000000000": "hello\n",
+		"app.log.20181016T100001.000000000": "world\n",
+		"app.log.20181016T100002.000000000": "this is too big\n",
+		"app.log":                           "foo\n",
+	}
+	if !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong files: %v != %v", act, exp)
+	}
+}
+
+func TestRollingFileMaxAge(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_rolling_file_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	conf := NewRollingFileConfig()
+	conf.Path = filepath.Join(dir, "app.log")
+	conf.MaxBytes = 0
+	conf.MaxAgeMS = 1000
+
+	r, clock := newTestRollingFile(t, conf)
+
+	writeRollingFile(t, r, "foo\n")
+	clock.advance(time.Millisecond * 500)
+	writeRollingFile(t, r, "bar\n")
+	clock.advance(time.Millisecond * 500)
+	writeRollingFile(t, r, "baz\n")
+	clock.advance(time.Millisecond * 500)
+	writeRollingFile(t, r, "qux\n")
+	if err = r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := map[string]string{
+		"app.log.20181016T100001.000000000": "foo\nbar\n",
+		"app.log":                           "baz\nqux\n",
+	}
+	if act := listRollingFiles(t, dir); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong files: %v != %v", act, exp)
+	}
+}
+
+func TestRollingFilePathChange(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_rolling_file_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	conf := NewRollingFileConfig()
+	conf.Path = filepath.Join(dir, "${!count:rolling_file_path_test}.log")
+
+	r, _ := newTestRollingFile(t, conf)
+
+	writeRollingFile(t, r, "foo\n")
+	writeRollingFile(t, r, "bar\n")
+	if err = r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := map[string]string{
+		"1.log": "foo\n",
+		"2.log": "bar\n",
+	}
+	if act := listRollingFiles(t, dir); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong files: %v != %v", act, exp)
+	}
+}
+
+func TestRollingFileAppendsExisting(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_rolling_file_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.log")
+	if err = ioutil.WriteFile(path, []byte("12345678\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	conf := NewRollingFileConfig()
+	conf.Path = path
+	conf.MaxBytes = 10
+
+	r, _ := newTestRollingFile(t, conf)
+
+	writeRollingFile(t, r, "a")
+	writeRollingFile(t, r, "b")
+	if err = r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := map[string]string{
+		"app.log.20181016T100000.000000000": "12345678\na",
+		"app.log":                           "b",
+	}
+	if act := listRollingFiles(t, dir); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong files: %v != %v", act, exp)
+	}
+}
+
+func TestRollingFilePruneMaxFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_rolling_file_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	unrelated := filepath.Join(dir, "other.txt")
+	if err = ioutil.WriteFile(unrelated, []byte("keep me"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	conf := NewRollingFileConfig()
+	conf.Path = filepath.Join(dir, "app-${!count:rolling_file_prune_test}.log")
+	conf.MaxFiles = 2
+
+	r, _ := newTestRollingFile(t, conf)
+
+	for i, data := range []string{"a", "b", "c", "d", "e"} {
+		writeRollingFile(t, r, data)
+
+		// Make modification times strictly ordered regardless of filesystem
+		// timestamp granularity.
+		mod := time.Now().Add(time.Duration(i-10) * time.Minute)
+		if err = os.Chtimes(r.path, mod, mod); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := map[string]string{
+		"app-3.log": "c",
+		"app-4.log": "d",
+		"app-5.log": "e",
+		"other.txt": "keep me",
+	}
+	if act := listRollingFiles(t, dir); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong files: %v != %v", act, exp)
+	}
+}
+
+func TestRollingFilePruneRetention(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_rolling_file_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	conf := NewRollingFileConfig()
+	conf.Path = filepath.Join(dir, "app.log")
+	conf.MaxBytes = 1
+	conf.RetentionMS = 60000
+
+	r, clock := newTestRollingFile(t, conf)
+
+	writeRollingFile(t, r, "a")
+	writeRollingFile(t, r, "b")
+
+	rotated := filepath.Join(dir, "app.log.20181016T100000.000000000")
+	old := clock.t.Add(-time.Hour)
+	if err = os.Chtimes(rotated, old, old); err != nil {
+		t.Fatal(err)
+	}
+	recent := clock.t.Add(-time.Second)
+	if err = os.Chtimes(r.path, recent, recent); err != nil {
+		t.Fatal(err)
+	}
+
+	clock.advance(time.Second)
+	writeRollingFile(t, r, "c")
+	if err = r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := map[string]string{
+		"app.log.20181016T100001.000000000": "b",
+		"app.log":                           "c",
+	}
+	if act := listRollingFiles(t, dir); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong files: %v != %v", act, exp)
+	}
+}
+
+func TestRollingFileOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_rolling_file_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	conf := NewConfig()
+	conf.Type = TypeRollingFile
+	conf.RollingFile.Path = filepath.Join(dir, "app.log")
+	conf.RollingFile.MaxBytes = 10
+
+	w, err := NewRollingFile(conf, nil, log.New(os.Stdout, logConfig), metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+	if err = w.Consume(msgChan); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, parts := range [][]string{{"foo"}, {"bar"}, {"baz", "qux"}} {
+		msg := message.New(nil)
+		for _, p := range parts {
+			msg.Append([]byte(p))
+		}
+		select {
+		case msgChan <- types.NewTransaction(msg, resChan):
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+		select {
+		case res := <-resChan:
+			if res.Error() != nil {
+				t.Fatal(res.Error())
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+	}
+
+	w.CloseAsync()
+	if err = w.WaitForClose(time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	act := listRollingFiles(t, dir)
+	if len(act) != 2 {
+		t.Fatalf("Wrong count of files: %v", act)
+	}
+	names := []string{}
+	for k := range act {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	if exp, act := "foo\nbar\n", act[names[1]]; exp != act {
+		t.Errorf("Wrong rotated contents: %q != %q", act, exp)
+	}
+	if exp, act := "baz\nqux\n\n", act["app.log"]; exp != act {
+		t.Errorf("Wrong current contents: %q != %q", act, exp)
+	}
+}
@@ -36,7 +36,39 @@ func init() {
 Sends message parts as objects to an Amazon S3 bucket. Each object is uploaded
 with the path specified with the 'path' field, in order to have a different path
 for each object you should use function interpolations described
-[here](../config_interpolation.md#functions).`,
+[here](../config_interpolation.md#functions).
+
+When ` + "`encoding`" + ` is set to ` + "`parquet`" + ` each message is uploaded
+as a single [Parquet](https://parquet.apache.org/) object with a row for each
+message part. The ` + "`parquet`" + ` fields behave the same as in the
+[` + "`files`" + ` output](#files).
+
+### Rolling Objects
+
+When ` + "`rolling.enabled`" + ` is true messages are instead appended to an
+in-progress object using a multipart upload, with each message part followed by
+a line break. The object path is resolved from the first message written to
+it. An object is completed once it contains ` + "`rolling.max_bytes`" + `
+bytes (before compression) or ` + "`rolling.max_messages`" + ` messages, or
+once it has been open for ` + "`rolling.period_ms`" + ` milliseconds, and a
+limit of zero disables it. Any in-progress object is also completed when
+Benthos shuts down.
+
+Data is uploaded in parts of ` + "`rolling.part_size`" + ` bytes, which must
+be at least 5MiB. Objects can be gzip compressed by setting
+` + "`rolling.compression`" + ` to ` + "`gzip`" + `.
+
+Messages are only acknowledged once the object containing them is completed. If
+a part fails to upload, or the object fails to complete, then the upload is
+aborted and every message of the object is sent again, and therefore
+` + "`retry.max_retries`" + ` and ` + "`retry.on_failure: drop`" + ` cannot be used with rolling
+objects. When an object is completed after ` + "`rolling.period_ms`" + ` without a new
+message arriving the acknowledgement of its messages is delivered with the next
+message written, and the object containing that message is therefore completed
+straight away.
+
+The fields ` + "`endpoint`" + ` and ` + "`force_path_style`" + ` can be used in
+order to connect to S3 compatible services.`,
 	}
 }
 
@@ -44,8 +76,13 @@ for each object you should use function interpolations described
 
 // NewAmazonS3 creates a new AmazonS3 output type.
 func NewAmazonS3(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	w, err := writer.NewAmazonS3(conf.S3, log, stats)
+	if err != nil {
+		return nil, err
+	}
 	return NewWriter(
-		"s3", writer.NewAmazonS3(conf.S3, log, stats), log, stats,
+		"s3", w, log, stats,
+		OptWriterSetRetries(conf.S3.Retry),
 	)
 }
 
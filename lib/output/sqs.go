@@ -43,6 +43,7 @@ Sends messages to an SQS queue.`,
 func NewAmazonSQS(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
 	return NewWriter(
 		"sqs", writer.NewAmazonSQS(conf.SQS, log, stats), log, stats,
+		OptWriterSetRetries(conf.SQS.Retry),
 	)
 }
 
@@ -29,6 +29,7 @@ import (
 	"github.com/Jeffail/benthos/lib/output/writer"
 	"github.com/Jeffail/benthos/lib/response"
 	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/retries"
 )
 
 //------------------------------------------------------------------------------
@@ -37,8 +38,9 @@ import (
 type Writer struct {
 	running int32
 
-	typeStr string
-	writer  writer.Type
+	typeStr   string
+	writer    writer.Type
+	retryConf retries.Config
 
 	log   log.Modular
 	stats metrics.Type
@@ -55,17 +57,36 @@ func NewWriter(
 	w writer.Type,
 	log log.Modular,
 	stats metrics.Type,
+	opts ...func(*Writer),
 ) (Type, error) {
-	return &Writer{
+	wr := &Writer{
 		running:      1,
 		typeStr:      typeStr,
 		writer:       w,
+		retryConf:    retries.NewConfig(),
 		log:          log.NewModule(".output." + typeStr),
 		stats:        stats,
 		transactions: nil,
 		closeChan:    make(chan struct{}),
 		closedChan:   make(chan struct{}),
-	}, nil
+	}
+	for _, opt := range opts {
+		opt(wr)
+	}
+	if err := wr.retryConf.Validate(); err != nil {
+		return nil, err
+	}
+	return wr, nil
+}
+
+//------------------------------------------------------------------------------
+
+// OptWriterSetRetries sets the retry policy applied to failed connection
+// attempts and writes.
+func OptWriterSetRetries(conf retries.Config) func(*Writer) {
+	return func(w *Writer) {
+		w.retryConf = conf
+	}
 }
 
 //------------------------------------------------------------------------------
@@ -88,6 +109,10 @@ func (w *Writer) loop() {
 		mFailedConnF = w.stats.GetCounter("output." + w.typeStr + ".connection.failed")
 		mLostConn    = w.stats.GetCounter("output.connection.lost")
 		mLostConnF   = w.stats.GetCounter("output." + w.typeStr + ".connection.lost")
+		mRetry       = w.stats.GetCounter("output.send.retry")
+		mRetryF      = w.stats.GetCounter("output." + w.typeStr + ".send.retry")
+		mDropped     = w.stats.GetCounter("output.send.dropped")
+		mDroppedF    = w.stats.GetCounter("output." + w.typeStr + ".send.dropped")
 	)
 
 	defer func() {
@@ -101,7 +126,14 @@ func (w *Writer) loop() {
 	mRunning.Incr(1)
 	mRunningF.Incr(1)
 
+	connThrot := w.retryConf.Throttle(w.closeChan)
+	writeThrot := w.retryConf.Throttle(w.closeChan)
+
 	for {
+		// Avoid connecting at all if we were closed before starting.
+		if atomic.LoadInt32(&w.running) == 0 {
+			return
+		}
 		if err := w.writer.Connect(); err != nil {
 			// Close immediately if our writer is closed.
 			if err == types.ErrTypeClosed {
@@ -111,18 +143,42 @@ func (w *Writer) loop() {
 			w.log.Errorf("Failed to connect to %v: %v\n", w.typeStr, err)
 			mFailedConn.Incr(1)
 			mFailedConnF.Incr(1)
-			select {
-			case <-time.After(time.Second):
-			case <-w.closeChan:
+			if !connThrot.ExponentialRetry() {
 				return
 			}
 		} else {
 			break
 		}
 	}
+	connThrot.Reset()
 	mConn.Incr(1)
 	mConnF.Incr(1)
 
+	// write attempts to write a message, and if our writer says it is not
+	// connected makes a single attempt to reconnect and write it again. Any
+	// further attempts are left to the retry policy.
+	write := func(msg types.Message) error {
+		err := w.writer.Write(msg)
+		if err != types.ErrNotConnected {
+			return err
+		}
+
+		mLostConn.Incr(1)
+		mLostConnF.Incr(1)
+
+		if err = w.writer.Connect(); err != nil {
+			if err != types.ErrTypeClosed {
+				w.log.Errorf("Failed to reconnect to %v: %v\n", w.typeStr, err)
+				mFailedConn.Incr(1)
+				mFailedConnF.Incr(1)
+			}
+			return err
+		}
+		mConn.Incr(1)
+		mConnF.Incr(1)
+		return w.writer.Write(msg)
+	}
+
 	for atomic.LoadInt32(&w.running) == 1 {
 		var ts types.Transaction
 		var open bool
@@ -137,52 +193,45 @@ func (w *Writer) loop() {
 			return
 		}
 
-		err := w.writer.Write(ts.Payload)
-
-		// If our writer says it is not connected.
-		if err == types.ErrNotConnected {
-			mLostConn.Incr(1)
-			mLostConnF.Incr(1)
-
-			// Continue to try to reconnect while still active.
-			for atomic.LoadInt32(&w.running) == 1 {
-				if err = w.writer.Connect(); err != nil {
-					// Close immediately if our writer is closed.
-					if err == types.ErrTypeClosed {
-						return
-					}
-
-					w.log.Errorf("Failed to reconnect to %v: %v\n", w.typeStr, err)
-					mFailedConn.Incr(1)
-					mFailedConnF.Incr(1)
-					select {
-					case <-time.After(time.Second):
-					case <-w.closeChan:
-						return
-					}
-				} else if err = w.writer.Write(ts.Payload); err != types.ErrNotConnected {
-					mConn.Incr(1)
-					mConnF.Incr(1)
-					break
-				}
+		err := write(ts.Payload)
+		for i := 0; err != nil && err != types.ErrTypeClosed && err != writer.ErrMessagePending && i < w.retryConf.MaxRetries; i++ {
+			w.log.Warnf("Failed to send message to %v, retrying: %v\n", w.typeStr, err)
+			mRetry.Incr(1)
+			mRetryF.Incr(1)
+			if !writeThrot.ExponentialRetry() {
+				return
 			}
+			err = write(ts.Payload)
 		}
+		writeThrot.Reset()
 
 		// Close immediately if our writer is closed.
 		if err == types.ErrTypeClosed {
 			return
 		}
 
-		if err != nil {
+		// A pending message is neither acknowledged nor failed until a later
+		// write concludes it.
+		var res types.Response = response.NewError(err)
+		if err == writer.ErrMessagePending {
+			mSuccess.Incr(1)
+			mSuccessF.Incr(1)
+			res = response.NewUnack()
+		} else if err != nil {
 			w.log.Errorf("Failed to send message to %v: %v\n", w.typeStr, err)
 			mError.Incr(1)
 			mErrorF.Incr(1)
+			if w.retryConf.OnFailure == retries.OnFailureDrop {
+				mDropped.Incr(1)
+				mDroppedF.Incr(1)
+				res = response.NewAck()
+			}
 		} else {
 			mSuccess.Incr(1)
 			mSuccessF.Incr(1)
 		}
 		select {
-		case ts.ResponseChan <- response.NewError(err):
+		case ts.ResponseChan <- res:
 		case <-w.closeChan:
 			return
 		}
@@ -22,16 +22,21 @@ package writer
 
 import (
 	"bytes"
+	"fmt"
+	"sync"
 	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/parquet"
+	"github.com/Jeffail/benthos/lib/util/retries"
 	"github.com/Jeffail/benthos/lib/util/text"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
 	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 )
 
@@ -47,25 +52,37 @@ type AmazonAWSCredentialsConfig struct {
 
 // AmazonS3Config contains configuration fields for the AmazonS3 output type.
 type AmazonS3Config struct {
-	Region      string                     `json:"region" yaml:"region"`
-	Bucket      string                     `json:"bucket" yaml:"bucket"`
-	Path        string                     `json:"path" yaml:"path"`
-	Credentials AmazonAWSCredentialsConfig `json:"credentials" yaml:"credentials"`
-	TimeoutS    int64                      `json:"timeout_s" yaml:"timeout_s"`
+	Region         string                     `json:"region" yaml:"region"`
+	Endpoint       string                     `json:"endpoint" yaml:"endpoint"`
+	ForcePathStyle bool                       `json:"force_path_style" yaml:"force_path_style"`
+	Bucket         string                     `json:"bucket" yaml:"bucket"`
+	Path           string                     `json:"path" yaml:"path"`
+	Encoding       string                     `json:"encoding" yaml:"encoding"`
+	Parquet        parquet.Config             `json:"parquet" yaml:"parquet"`
+	Rolling        AmazonS3RollingConfig      `json:"rolling" yaml:"rolling"`
+	Credentials    AmazonAWSCredentialsConfig `json:"credentials" yaml:"credentials"`
+	TimeoutS       int64                      `json:"timeout_s" yaml:"timeout_s"`
+	Retry          retries.Config             `json:"retry" yaml:"retry"`
 }
 
 // NewAmazonS3Config creates a new Config with default values.
 func NewAmazonS3Config() AmazonS3Config {
 	return AmazonS3Config{
-		Region: "eu-west-1",
-		Bucket: "",
-		Path:   "${!count:files}-${!timestamp_unix_nano}.txt",
+		Region:         "eu-west-1",
+		Endpoint:       "",
+		ForcePathStyle: false,
+		Bucket:         "",
+		Path:           "${!count:files}-${!timestamp_unix_nano}.txt",
+		Encoding:       "raw",
+		Parquet:        parquet.NewConfig(),
+		Rolling:        NewAmazonS3RollingConfig(),
 		Credentials: AmazonAWSCredentialsConfig{
 			ID:     "",
 			Secret: "",
 			Token:  "",
 		},
 		TimeoutS: 5,
+		Retry:    retries.NewConfig(),
 	}
 }
 
@@ -79,8 +96,19 @@ type AmazonS3 struct {
 	pathBytes       []byte
 	interpolatePath bool
 
+	parquet *parquet.Encoder
+
 	session  *session.Session
 	uploader *s3manager.Uploader
+	client   s3MultipartClient
+
+	rollMut    sync.Mutex
+	rolling    *s3RollingObject
+	rollAckDue bool
+	rollErr    error
+	closeOnce  sync.Once
+	closeChan  chan struct{}
+	closedChan chan struct{}
 
 	log   log.Modular
 	stats metrics.Type
@@ -91,16 +119,35 @@ func NewAmazonS3(
 	conf AmazonS3Config,
 	log log.Modular,
 	stats metrics.Type,
-) *AmazonS3 {
+) (*AmazonS3, error) {
 	pathBytes := []byte(conf.Path)
 	interpolatePath := text.ContainsFunctionVariables(pathBytes)
-	return &AmazonS3{
+	a := &AmazonS3{
 		conf:            conf,
 		pathBytes:       pathBytes,
 		interpolatePath: interpolatePath,
 		log:             log.NewModule(".output.amazon_s3"),
 		stats:           stats,
 	}
+	switch conf.Encoding {
+	case "raw":
+	case "parquet":
+		var err error
+		if a.parquet, err = parquet.NewEncoder(conf.Parquet); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("encoding not recognised: %v", conf.Encoding)
+	}
+	if conf.Rolling.Enabled {
+		if err := a.validateRolling(); err != nil {
+			return nil, err
+		}
+		a.closeChan = make(chan struct{})
+		a.closedChan = make(chan struct{})
+		go a.rollLoop()
+	}
+	return a, nil
 }
 
 // Connect attempts to establish a connection to the target S3 bucket.
@@ -113,6 +160,12 @@ func (a *AmazonS3) Connect() error {
 	if len(a.conf.Region) > 0 {
 		awsConf = awsConf.WithRegion(a.conf.Region)
 	}
+	if len(a.conf.Endpoint) > 0 {
+		awsConf = awsConf.WithEndpoint(a.conf.Endpoint)
+	}
+	if a.conf.ForcePathStyle {
+		awsConf = awsConf.WithS3ForcePathStyle(true)
+	}
 	if len(a.conf.Credentials.ID) > 0 {
 		awsConf = awsConf.WithCredentials(credentials.NewStaticCredentials(
 			a.conf.Credentials.ID,
@@ -132,8 +185,11 @@ func (a *AmazonS3) Connect() error {
 		)
 	}
 
+	a.rollMut.Lock()
 	a.session = sess
 	a.uploader = s3manager.NewUploader(sess)
+	a.client = s3.New(sess)
+	a.rollMut.Unlock()
 
 	a.log.Infof("Uploading message parts as objects to Amazon S3 bucket: %v\n", a.conf.Bucket)
 	return nil
@@ -145,31 +201,58 @@ func (a *AmazonS3) Write(msg types.Message) error {
 		return types.ErrNotConnected
 	}
 
-	for _, part := range msg.GetAll() {
-		path := a.conf.Path
-		if a.interpolatePath {
-			path = string(text.ReplaceFunctionVariables(msg, a.pathBytes))
+	if a.conf.Rolling.Enabled {
+		return a.writeRolling(msg)
+	}
+	if a.parquet != nil {
+		object, err := a.parquet.Encode(msg.GetAll())
+		if err != nil {
+			return err
 		}
-
-		if _, err := a.uploader.Upload(&s3manager.UploadInput{
-			Body:   bytes.NewReader(part),
-			Bucket: aws.String(a.conf.Bucket),
-			Key:    aws.String(path),
-		}); err != nil {
+		return a.upload(msg, object)
+	}
+	for _, part := range msg.GetAll() {
+		if err := a.upload(msg, part); err != nil {
 			return err
 		}
 	}
-
 	return nil
 }
 
+func (a *AmazonS3) upload(msg types.Message, contents []byte) error {
+	path := a.conf.Path
+	if a.interpolatePath {
+		path = string(text.ReplaceFunctionVariables(msg, a.pathBytes))
+	}
+
+	_, err := a.uploader.Upload(&s3manager.UploadInput{
+		Body:   bytes.NewReader(contents),
+		Bucket: aws.String(a.conf.Bucket),
+		Key:    aws.String(path),
+	})
+	return err
+}
+
 // CloseAsync begins cleaning up resources used by this reader asynchronously.
 func (a *AmazonS3) CloseAsync() {
+	if a.closeChan != nil {
+		a.closeOnce.Do(func() {
+			close(a.closeChan)
+		})
+	}
 }
 
 // WaitForClose will block until either the reader is closed or a specified
 // timeout occurs.
-func (a *AmazonS3) WaitForClose(time.Duration) error {
+func (a *AmazonS3) WaitForClose(timeout time.Duration) error {
+	if a.closedChan == nil {
+		return nil
+	}
+	select {
+	case <-a.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
 	return nil
 }
 
@@ -0,0 +1,325 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/retries"
+	"github.com/Jeffail/benthos/lib/util/text"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+//------------------------------------------------------------------------------
+
+// minS3PartSize is the smallest size permitted by S3 for all but the last part
+// of a multipart upload.
+const minS3PartSize = 5 * 1024 * 1024
+
+// AmazonS3RollingConfig contains configuration fields for writing messages to
+// rolling objects with multipart uploads.
+type AmazonS3RollingConfig struct {
+	Enabled     bool   `json:"enabled" yaml:"enabled"`
+	MaxBytes    int64  `json:"max_bytes" yaml:"max_bytes"`
+	MaxMessages int    `json:"max_messages" yaml:"max_messages"`
+	PeriodMS    int    `json:"period_ms" yaml:"period_ms"`
+	PartSize    int    `json:"part_size" yaml:"part_size"`
+	Compression string `json:"compression" yaml:"compression"`
+}
+
+// NewAmazonS3RollingConfig creates a new AmazonS3RollingConfig with default
+// values.
+func NewAmazonS3RollingConfig() AmazonS3RollingConfig {
+	return AmazonS3RollingConfig{
+		Enabled:     false,
+		MaxBytes:    100 * 1024 * 1024,
+		MaxMessages: 0,
+		PeriodMS:    60000,
+		PartSize:    minS3PartSize,
+		Compression: "none",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// s3MultipartClient is the subset of the S3 API used for rolling uploads.
+type s3MultipartClient interface {
+	CreateMultipartUpload(*s3.CreateMultipartUploadInput) (*s3.CreateMultipartUploadOutput, error)
+	UploadPart(*s3.UploadPartInput) (*s3.UploadPartOutput, error)
+	CompleteMultipartUpload(*s3.CompleteMultipartUploadInput) (*s3.CompleteMultipartUploadOutput, error)
+	AbortMultipartUpload(*s3.AbortMultipartUploadInput) (*s3.AbortMultipartUploadOutput, error)
+}
+
+// s3RollingObject is an object that is in the process of being written with a
+// multipart upload.
+type s3RollingObject struct {
+	key      string
+	uploadID *string
+	started  time.Time
+
+	buf   *bytes.Buffer
+	gzipW *gzip.Writer
+	parts []*s3.CompletedPart
+
+	messages int
+	bytes    int64
+}
+
+//------------------------------------------------------------------------------
+
+func (a *AmazonS3) validateRolling() error {
+	conf := a.conf.Rolling
+	if a.parquet != nil {
+		return errors.New("rolling objects cannot be used with the parquet encoding")
+	}
+	switch conf.Compression {
+	case "none", "gzip":
+	default:
+		return fmt.Errorf("compression type not recognised: %v", conf.Compression)
+	}
+	if conf.PartSize < minS3PartSize {
+		return fmt.Errorf("part_size must be at least %v bytes", minS3PartSize)
+	}
+	if conf.MaxBytes <= 0 && conf.MaxMessages <= 0 && conf.PeriodMS <= 0 {
+		return errors.New("at least one of max_bytes, max_messages or period_ms must be set")
+	}
+	// Retrying or dropping a single message would acknowledge the pending
+	// messages of an object that was given up along with it.
+	if a.conf.Retry.MaxRetries > 0 || a.conf.Retry.OnFailure == retries.OnFailureDrop {
+		return errors.New("rolling objects cannot be used with retry.max_retries or retry.on_failure set to drop")
+	}
+	return nil
+}
+
+// writeRolling appends the parts of a message to the current object, starting
+// a new object if required.
+//
+// A message is only acknowledged once the object containing it is completed,
+// until then ErrMessagePending is returned and the acknowledgement is carried
+// by the write that completes the object. If a part of an object fails to
+// upload, or the object fails to complete, then the upload is aborted and an
+// error is returned, which fails the message along with all pending messages
+// so that they are sent again.
+func (a *AmazonS3) writeRolling(msg types.Message) error {
+	a.rollMut.Lock()
+	defer a.rollMut.Unlock()
+
+	// An object given up by the roll loop fails its pending messages with the
+	// next write.
+	if err := a.rollErr; err != nil {
+		a.rollErr = nil
+		return err
+	}
+
+	if a.rolling == nil {
+		path := a.conf.Path
+		if a.interpolatePath {
+			path = string(text.ReplaceFunctionVariables(msg, a.pathBytes))
+		}
+		input := &s3.CreateMultipartUploadInput{
+			Bucket: aws.String(a.conf.Bucket),
+			Key:    aws.String(path),
+		}
+		if a.conf.Rolling.Compression == "gzip" {
+			input.ContentEncoding = aws.String("gzip")
+		}
+		out, err := a.client.CreateMultipartUpload(input)
+		if err != nil {
+			return err
+		}
+		a.rolling = &s3RollingObject{
+			key:      path,
+			uploadID: out.UploadId,
+			started:  time.Now(),
+			buf:      &bytes.Buffer{},
+		}
+		if a.conf.Rolling.Compression == "gzip" {
+			a.rolling.gzipW = gzip.NewWriter(a.rolling.buf)
+		}
+	}
+
+	obj := a.rolling
+	for _, part := range msg.GetAll() {
+		if obj.gzipW != nil {
+			obj.gzipW.Write(part)
+			obj.gzipW.Write([]byte("\n"))
+		} else {
+			obj.buf.Write(part)
+			obj.buf.WriteByte('\n')
+		}
+		obj.bytes += int64(len(part) + 1)
+	}
+	obj.messages++
+
+	period := time.Duration(a.conf.Rolling.PeriodMS) * time.Millisecond
+
+	// An object completed by the roll loop still has pending messages awaiting
+	// an acknowledgement, and therefore the object containing this message is
+	// completed straight away in order to deliver it.
+	if a.rollAckDue ||
+		(a.conf.Rolling.MaxBytes > 0 && obj.bytes >= a.conf.Rolling.MaxBytes) ||
+		(a.conf.Rolling.MaxMessages > 0 && obj.messages >= a.conf.Rolling.MaxMessages) ||
+		(period > 0 && time.Since(obj.started) >= period) {
+		if err := a.completeRolling(); err != nil {
+			a.abortRolling()
+			return fmt.Errorf("failed to complete object '%v': %v", obj.key, err)
+		}
+		return nil
+	}
+
+	if obj.buf.Len() >= a.conf.Rolling.PartSize {
+		if err := a.uploadRollingPart(); err != nil {
+			a.abortRolling()
+			return fmt.Errorf("failed to upload part of object '%v': %v", obj.key, err)
+		}
+	}
+	return ErrMessagePending
+}
+
+// uploadRollingPart uploads the buffered contents of the current object as a
+// new part.
+func (a *AmazonS3) uploadRollingPart() error {
+	obj := a.rolling
+	partNumber := aws.Int64(int64(len(obj.parts) + 1))
+	out, err := a.client.UploadPart(&s3.UploadPartInput{
+		Body:       bytes.NewReader(obj.buf.Bytes()),
+		Bucket:     aws.String(a.conf.Bucket),
+		Key:        aws.String(obj.key),
+		PartNumber: partNumber,
+		UploadId:   obj.uploadID,
+	})
+	if err != nil {
+		return err
+	}
+	obj.parts = append(obj.parts, &s3.CompletedPart{
+		ETag:       out.ETag,
+		PartNumber: partNumber,
+	})
+	obj.buf.Reset()
+	return nil
+}
+
+// completeRolling uploads any remaining contents of the current object and
+// completes it. If an error is returned the object must be aborted.
+func (a *AmazonS3) completeRolling() error {
+	obj := a.rolling
+	if obj.gzipW != nil {
+		if err := obj.gzipW.Close(); err != nil {
+			return err
+		}
+	}
+	if obj.buf.Len() > 0 || len(obj.parts) == 0 {
+		if err := a.uploadRollingPart(); err != nil {
+			return err
+		}
+	}
+	if _, err := a.client.CompleteMultipartUpload(&s3.CompleteMultipartUploadInput{
+		Bucket:   aws.String(a.conf.Bucket),
+		Key:      aws.String(obj.key),
+		UploadId: obj.uploadID,
+		MultipartUpload: &s3.CompletedMultipartUpload{
+			Parts: obj.parts,
+		},
+	}); err != nil {
+		return err
+	}
+	a.rolling = nil
+	a.rollAckDue = false
+	a.log.Debugf("Completed object '%v' with %v messages\n", obj.key, obj.messages)
+	return nil
+}
+
+// abortRolling gives up on the current object, aborting its multipart upload
+// so that any parts already uploaded are discarded.
+func (a *AmazonS3) abortRolling() {
+	obj := a.rolling
+	a.rolling = nil
+	a.rollAckDue = false
+	if _, err := a.client.AbortMultipartUpload(&s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(a.conf.Bucket),
+		Key:      aws.String(obj.key),
+		UploadId: obj.uploadID,
+	}); err != nil {
+		a.log.Errorf("Failed to abort upload of object '%v': %v\n", obj.key, err)
+	}
+}
+
+// rollLoop completes objects once they have been open for longer than the
+// configured period without a write arriving to complete them, and completes
+// any remaining object on shut down. The pending messages of an object
+// completed here are acknowledged by the next write, and those of an object
+// that fails are failed by the next write.
+func (a *AmazonS3) rollLoop() {
+	defer close(a.closedChan)
+
+	period := time.Duration(a.conf.Rolling.PeriodMS) * time.Millisecond
+	for {
+		var timer <-chan time.Time
+		if period > 0 {
+			wait := period
+			a.rollMut.Lock()
+			if a.rolling != nil {
+				wait = period - time.Since(a.rolling.started)
+			}
+			a.rollMut.Unlock()
+			if wait < time.Millisecond*100 {
+				wait = time.Millisecond * 100
+			}
+			timer = time.After(wait)
+		}
+
+		select {
+		case <-timer:
+		case <-a.closeChan:
+			a.rollMut.Lock()
+			if a.rolling != nil && a.client != nil {
+				key := a.rolling.key
+				if err := a.completeRolling(); err != nil {
+					a.log.Errorf("Failed to complete object '%v': %v\n", key, err)
+					a.abortRolling()
+				}
+			}
+			a.rollMut.Unlock()
+			return
+		}
+
+		a.rollMut.Lock()
+		if a.rolling != nil && time.Since(a.rolling.started) >= period {
+			key := a.rolling.key
+			if err := a.completeRolling(); err != nil {
+				a.log.Errorf("Failed to complete object '%v': %v\n", key, err)
+				a.abortRolling()
+				a.rollErr = fmt.Errorf("failed to complete object '%v': %v", key, err)
+			} else {
+				a.rollAckDue = true
+			}
+		}
+		a.rollMut.Unlock()
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,494 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+//------------------------------------------------------------------------------
+
+type mockS3Upload struct {
+	key   string
+	parts map[int64][]byte
+}
+
+type mockS3Multipart struct {
+	sync.Mutex
+
+	created   int
+	uploads   map[string]*mockS3Upload
+	completed map[string][]byte
+	partSizes map[string][]int
+	aborted   []string
+
+	errUpload   error
+	errComplete error
+}
+
+func newMockS3Multipart() *mockS3Multipart {
+	return &mockS3Multipart{
+		uploads:   map[string]*mockS3Upload{},
+		completed: map[string][]byte{},
+		partSizes: map[string][]int{},
+	}
+}
+
+func (m *mockS3Multipart) CreateMultipartUpload(input *s3.CreateMultipartUploadInput) (*s3.CreateMultipartUploadOutput, error) {
+	m.Lock()
+	defer m.Unlock()
+	id := fmt.Sprintf("upload-%v", m.created)
+	m.created++
+	m.uploads[id] = &mockS3Upload{
+		key:   *input.Key,
+		parts: map[int64][]byte{},
+	}
+	return &s3.CreateMultipartUploadOutput{UploadId: aws.String(id)}, nil
+}
+
+func (m *mockS3Multipart) UploadPart(input *s3.UploadPartInput) (*s3.UploadPartOutput, error) {
+	m.Lock()
+	defer m.Unlock()
+	if m.errUpload != nil {
+		return nil, m.errUpload
+	}
+	upload, exists := m.uploads[*input.UploadId]
+	if !exists {
+		return nil, errors.New("upload does not exist")
+	}
+	data, err := ioutil.ReadAll(input.Body)
+	if err != nil {
+		return nil, err
+	}
+	upload.parts[*input.PartNumber] = data
+	return &s3.UploadPartOutput{
+		ETag: aws.String(fmt.Sprintf("etag-%v", *input.PartNumber)),
+	}, nil
+}
+
+func (m *mockS3Multipart) CompleteMultipartUpload(input *s3.CompleteMultipartUploadInput) (*s3.CompleteMultipartUploadOutput, error) {
+	m.Lock()
+	defer m.Unlock()
+	if m.errComplete != nil {
+		return nil, m.errComplete
+	}
+	upload, exists := m.uploads[*input.UploadId]
+	if !exists {
+		return nil, errors.New("upload does not exist")
+	}
+	var buf bytes.Buffer
+	var sizes []int
+	for i, part := range input.MultipartUpload.Parts {
+		if exp, act := int64(i+1), *part.PartNumber; exp != act {
+			return nil, fmt.Errorf("wrong part number: %v != %v", act, exp)
+		}
+		if exp, act := fmt.Sprintf("etag-%v", i+1), *part.ETag; exp != act {
+			return nil, fmt.Errorf("wrong etag: %v != %v", act, exp)
+		}
+		buf.Write(upload.parts[*part.PartNumber])
+		sizes = append(sizes, len(upload.parts[*part.PartNumber]))
+	}
+	m.completed[upload.key] = buf.Bytes()
+	m.partSizes[upload.key] = sizes
+	delete(m.uploads, *input.UploadId)
+	return &s3.CompleteMultipartUploadOutput{}, nil
+}
+
+func (m *mockS3Multipart) AbortMultipartUpload(input *s3.AbortMultipartUploadInput) (*s3.AbortMultipartUploadOutput, error) {
+	m.Lock()
+	defer m.Unlock()
+	upload, exists := m.uploads[*input.UploadId]
+	if !exists {
+		return nil, errors.New("upload does not exist")
+	}
+	m.aborted = append(m.aborted, upload.key)
+	delete(m.uploads, *input.UploadId)
+	return &s3.AbortMultipartUploadOutput{}, nil
+}
+
+func (m *mockS3Multipart) getAborted() []string {
+	m.Lock()
+	defer m.Unlock()
+	return append([]string{}, m.aborted...)
+}
+
+func (m *mockS3Multipart) getCompleted() map[string]string {
+	m.Lock()
+	defer m.Unlock()
+	res := map[string]string{}
+	for k, v := range m.completed {
+		res[k] = string(v)
+	}
+	return res
+}
+
+func newRollingTestS3(t *testing.T, conf AmazonS3Config) (*AmazonS3, *mockS3Multipart) {
+	conf.Rolling.Enabled = true
+	a, err := NewAmazonS3(conf, log.New(os.Stdout, log.Config{LogLevel: "NONE"}), metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock := newMockS3Multipart()
+	a.session = &session.Session{}
+	a.client = mock
+	return a, mock
+}
+
+//------------------------------------------------------------------------------
+
+func TestAmazonS3RollingMaxMessages(t *testing.T) {
+	conf := NewAmazonS3Config()
+	conf.Path = "${!metadata:name}.txt"
+	conf.Rolling.MaxMessages = 2
+	conf.Rolling.PeriodMS = 0
+
+	a, mock := newRollingTestS3(t, conf)
+
+	expErrs := []error{ErrMessagePending, nil, ErrMessagePending}
+	for i, name := range []string{"foo", "bar", "baz"} {
+		msg := message.New([][]byte{
+			[]byte(fmt.Sprintf("hello%v", i)),
+			[]byte(fmt.Sprintf("world%v", i)),
+		})
+		msg.SetMetadata("name", name)
+		if err := a.Write(msg); err != expErrs[i] {
+			t.Fatalf("Wrong result from write %v: %v != %v", i, err, expErrs[i])
+		}
+	}
+
+	exp := map[string]string{
+		"foo.txt": "hello0\nworld0\nhello1\nworld1\n",
+	}
+	if act := mock.getCompleted(); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong completed objects: %v != %v", act, exp)
+	}
+
+	a.CloseAsync()
+	if err := a.WaitForClose(time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	exp["baz.txt"] = "hello2\nworld2\n"
+	if act := mock.getCompleted(); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong completed objects: %v != %v", act, exp)
+	}
+}
+
+func TestAmazonS3RollingMaxBytesGzip(t *testing.T) {
+	conf := NewAmazonS3Config()
+	conf.Path = "${!count:s3_rolling_gzip_test}.gz"
+	conf.Rolling.MaxBytes = 10
+	conf.Rolling.PeriodMS = 0
+	conf.Rolling.Compression = "gzip"
+
+	a, mock := newRollingTestS3(t, conf)
+	defer func() {
+		a.CloseAsync()
+		a.WaitForClose(time.Second)
+	}()
+
+	expErrs := []error{ErrMessagePending, ErrMessagePending, nil, ErrMessagePending}
+	for i, part := range []string{"foo", "bar", "bazbuz", "qux"} {
+		if err := a.Write(message.New([][]byte{[]byte(part)})); err != expErrs[i] {
+			t.Fatalf("Wrong result from write %v: %v != %v", i, err, expErrs[i])
+		}
+	}
+
+	completed := mock.getCompleted()
+	if exp, act := 1, len(completed); exp != act {
+		t.Fatalf("Wrong count of completed objects: %v != %v", act, exp)
+	}
+
+	zr, err := gzip.NewReader(strings.NewReader(completed["1.gz"]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "foo\nbar\nbazbuz\n", string(data); exp != act {
+		t.Errorf("Wrong object contents: %q != %q", act, exp)
+	}
+}
+
+func TestAmazonS3RollingPeriod(t *testing.T) {
+	conf := NewAmazonS3Config()
+	conf.Path = "foo.txt"
+	conf.Rolling.MaxBytes = 0
+	conf.Rolling.PeriodMS = 10
+
+	a, mock := newRollingTestS3(t, conf)
+	defer func() {
+		a.CloseAsync()
+		a.WaitForClose(time.Second)
+	}()
+
+	if err := a.Write(message.New([][]byte{[]byte("foo")})); err != ErrMessagePending {
+		t.Fatalf("Wrong result from write: %v != %v", err, ErrMessagePending)
+	}
+
+	exp := map[string]string{
+		"foo.txt": "foo\n",
+	}
+	for i := 0; i < 100; i++ {
+		if len(mock.getCompleted()) > 0 {
+			break
+		}
+		<-time.After(time.Millisecond * 10)
+	}
+	if act := mock.getCompleted(); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong completed objects: %v != %v", act, exp)
+	}
+
+	// The pending message of the completed object is acknowledged by the next
+	// write, which therefore completes its own object immediately.
+	if err := a.Write(message.New([][]byte{[]byte("bar")})); err != nil {
+		t.Fatal(err)
+	}
+	exp["foo.txt"] = "bar\n"
+	if act := mock.getCompleted(); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong completed objects: %v != %v", act, exp)
+	}
+}
+
+func TestAmazonS3RollingParts(t *testing.T) {
+	conf := NewAmazonS3Config()
+	conf.Path = "foo.txt"
+	conf.Rolling.MaxMessages = 3
+	conf.Rolling.PeriodMS = 0
+
+	a, mock := newRollingTestS3(t, conf)
+	defer func() {
+		a.CloseAsync()
+		a.WaitForClose(time.Second)
+	}()
+
+	bigPart := bytes.Repeat([]byte("a"), minS3PartSize)
+	expErrs := []error{ErrMessagePending, ErrMessagePending, nil}
+	for i := 0; i < 3; i++ {
+		if err := a.Write(message.New([][]byte{bigPart})); err != expErrs[i] {
+			t.Fatalf("Wrong result from write %v: %v != %v", i, err, expErrs[i])
+		}
+	}
+
+	mock.Lock()
+	sizes := mock.partSizes["foo.txt"]
+	mock.Unlock()
+
+	if exp, act := []int{minS3PartSize + 1, minS3PartSize + 1, minS3PartSize + 1}, sizes; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong part sizes: %v != %v", act, exp)
+	}
+}
+
+func TestAmazonS3RollingCompleteFailure(t *testing.T) {
+	conf := NewAmazonS3Config()
+	conf.Path = "${!count:s3_rolling_complete_fail_test}.txt"
+	conf.Rolling.MaxMessages = 2
+	conf.Rolling.PeriodMS = 0
+
+	a, mock := newRollingTestS3(t, conf)
+	defer func() {
+		a.CloseAsync()
+		a.WaitForClose(time.Second)
+	}()
+
+	mock.Lock()
+	mock.errComplete = errors.New("nope")
+	mock.Unlock()
+
+	if err := a.Write(message.New([][]byte{[]byte("foo")})); err != ErrMessagePending {
+		t.Fatalf("Wrong result from write: %v != %v", err, ErrMessagePending)
+	}
+	if err := a.Write(message.New([][]byte{[]byte("bar")})); err == nil || err == ErrMessagePending {
+		t.Fatalf("Expected error whilst object cannot be completed: %v", err)
+	}
+	if exp, act := []string{"1.txt"}, mock.getAborted(); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong aborted objects: %v != %v", act, exp)
+	}
+
+	mock.Lock()
+	mock.errComplete = nil
+	mock.Unlock()
+
+	// Both messages are sent again after the failure.
+	if err := a.Write(message.New([][]byte{[]byte("foo")})); err != ErrMessagePending {
+		t.Fatalf("Wrong result from write: %v != %v", err, ErrMessagePending)
+	}
+	if err := a.Write(message.New([][]byte{[]byte("bar")})); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := map[string]string{
+		"2.txt": "foo\nbar\n",
+	}
+	if act := mock.getCompleted(); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong completed objects: %v != %v", act, exp)
+	}
+}
+
+func TestAmazonS3RollingUploadFailure(t *testing.T) {
+	conf := NewAmazonS3Config()
+	conf.Path = "foo.txt"
+	conf.Rolling.MaxMessages = 3
+	conf.Rolling.PeriodMS = 0
+
+	a, mock := newRollingTestS3(t, conf)
+	defer func() {
+		a.CloseAsync()
+		a.WaitForClose(time.Second)
+	}()
+
+	mock.Lock()
+	mock.errUpload = errors.New("nope")
+	mock.Unlock()
+
+	bigPart := bytes.Repeat([]byte("a"), minS3PartSize)
+	if err := a.Write(message.New([][]byte{bigPart})); err == nil || err == ErrMessagePending {
+		t.Fatalf("Expected error whilst part cannot be uploaded: %v", err)
+	}
+	if exp, act := []string{"foo.txt"}, mock.getAborted(); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong aborted objects: %v != %v", act, exp)
+	}
+
+	mock.Lock()
+	mock.errUpload = nil
+	mock.Unlock()
+
+	if err := a.Write(message.New([][]byte{bigPart})); err != ErrMessagePending {
+		t.Fatalf("Wrong result from write: %v != %v", err, ErrMessagePending)
+	}
+
+	mock.Lock()
+	uploads := len(mock.uploads)
+	mock.Unlock()
+	if exp, act := 1, uploads; exp != act {
+		t.Errorf("Wrong count of open uploads: %v != %v", act, exp)
+	}
+}
+
+func TestAmazonS3RollingPeriodFailure(t *testing.T) {
+	conf := NewAmazonS3Config()
+	conf.Path = "foo.txt"
+	conf.Rolling.MaxBytes = 0
+	conf.Rolling.PeriodMS = 10
+
+	a, mock := newRollingTestS3(t, conf)
+	defer func() {
+		a.CloseAsync()
+		a.WaitForClose(time.Second)
+	}()
+
+	mock.Lock()
+	mock.errComplete = errors.New("nope")
+	mock.Unlock()
+
+	if err := a.Write(message.New([][]byte{[]byte("foo")})); err != ErrMessagePending {
+		t.Fatalf("Wrong result from write: %v != %v", err, ErrMessagePending)
+	}
+	for i := 0; i < 100; i++ {
+		if len(mock.getAborted()) > 0 {
+			break
+		}
+		<-time.After(time.Millisecond * 10)
+	}
+	if exp, act := []string{"foo.txt"}, mock.getAborted(); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong aborted objects: %v != %v", act, exp)
+	}
+
+	mock.Lock()
+	mock.errComplete = nil
+	mock.Unlock()
+
+	// The pending message of the aborted object is failed by the next write.
+	if err := a.Write(message.New([][]byte{[]byte("bar")})); err == nil || err == ErrMessagePending {
+		t.Fatalf("Expected error from aborted object: %v", err)
+	}
+	if exp, act := 0, len(mock.getCompleted()); exp != act {
+		t.Errorf("Wrong count of completed objects: %v != %v", act, exp)
+	}
+}
+
+func TestAmazonS3RollingBadConfig(t *testing.T) {
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	conf := NewAmazonS3Config()
+	conf.Rolling.Enabled = true
+	conf.Rolling.Compression = "nope"
+	if _, err := NewAmazonS3(conf, testLog, metrics.DudType{}); err == nil {
+		t.Error("Expected error from bad compression")
+	}
+
+	conf = NewAmazonS3Config()
+	conf.Rolling.Enabled = true
+	conf.Rolling.PartSize = 10
+	if _, err := NewAmazonS3(conf, testLog, metrics.DudType{}); err == nil {
+		t.Error("Expected error from small part size")
+	}
+
+	conf = NewAmazonS3Config()
+	conf.Rolling.Enabled = true
+	conf.Encoding = "parquet"
+	if _, err := NewAmazonS3(conf, testLog, metrics.DudType{}); err == nil {
+		t.Error("Expected error from parquet encoding")
+	}
+
+	conf = NewAmazonS3Config()
+	conf.Rolling.Enabled = true
+	conf.Rolling.MaxBytes = 0
+	conf.Rolling.PeriodMS = 0
+	if _, err := NewAmazonS3(conf, testLog, metrics.DudType{}); err == nil {
+		t.Error("Expected error from no rolling limits")
+	}
+
+	conf = NewAmazonS3Config()
+	conf.Rolling.Enabled = true
+	conf.Retry.MaxRetries = 1
+	if _, err := NewAmazonS3(conf, testLog, metrics.DudType{}); err == nil {
+		t.Error("Expected error from retries")
+	}
+
+	conf = NewAmazonS3Config()
+	conf.Rolling.Enabled = true
+	conf.Retry.OnFailure = "drop"
+	if _, err := NewAmazonS3(conf, testLog, metrics.DudType{}); err == nil {
+		t.Error("Expected error from dropped failures")
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -26,6 +26,7 @@ import (
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/retries"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
@@ -40,6 +41,7 @@ type AmazonSQSConfig struct {
 	Region      string                     `json:"region" yaml:"region"`
 	URL         string                     `json:"url" yaml:"url"`
 	Credentials AmazonAWSCredentialsConfig `json:"credentials" yaml:"credentials"`
+	Retry       retries.Config             `json:"retry" yaml:"retry"`
 }
 
 // NewAmazonSQSConfig creates a new Config with default values.
@@ -52,6 +54,7 @@ func NewAmazonSQSConfig() AmazonSQSConfig {
 			Secret: "",
 			Token:  "",
 		},
+		Retry: retries.NewConfig(),
 	}
 }
 
@@ -29,6 +29,7 @@ import (
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/retries"
 	"github.com/Jeffail/benthos/lib/util/text"
 	"github.com/streadway/amqp"
 )
@@ -37,13 +38,14 @@ import (
 
 // AMQPConfig contains configuration fields for the AMQP output type.
 type AMQPConfig struct {
-	URL          string `json:"url" yaml:"url"`
-	Exchange     string `json:"exchange" yaml:"exchange"`
-	ExchangeType string `json:"exchange_type" yaml:"exchange_type"`
-	BindingKey   string `json:"key" yaml:"key"`
-	Persistent   bool   `json:"persistent" yaml:"persistent"`
-	Mandatory    bool   `json:"mandatory" yaml:"mandatory"`
-	Immediate    bool   `json:"immediate" yaml:"immediate"`
+	URL          string         `json:"url" yaml:"url"`
+	Exchange     string         `json:"exchange" yaml:"exchange"`
+	ExchangeType string         `json:"exchange_type" yaml:"exchange_type"`
+	BindingKey   string         `json:"key" yaml:"key"`
+	Persistent   bool           `json:"persistent" yaml:"persistent"`
+	Mandatory    bool           `json:"mandatory" yaml:"mandatory"`
+	Immediate    bool           `json:"immediate" yaml:"immediate"`
+	Retry        retries.Config `json:"retry" yaml:"retry"`
 }
 
 // NewAMQPConfig creates a new AMQPConfig with default values.
@@ -56,6 +58,7 @@ func NewAMQPConfig() AMQPConfig {
 		Persistent:   false,
 		Mandatory:    false,
 		Immediate:    false,
+		Retry:        retries.NewConfig(),
 	}
 }
 
@@ -31,6 +31,7 @@ import (
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
 	"github.com/Jeffail/benthos/lib/util/http/auth"
+	"github.com/Jeffail/benthos/lib/util/retries"
 	"github.com/Jeffail/benthos/lib/util/text"
 	"github.com/olivere/elastic"
 )
@@ -45,6 +46,7 @@ type ElasticsearchConfig struct {
 	Index     string               `json:"index" yaml:"index"`
 	TimeoutMS int                  `json:"timeout_ms" yaml:"timeout_ms"`
 	Auth      auth.BasicAuthConfig `json:"basic_auth" yaml:"basic_auth"`
+	Retry     retries.Config       `json:"retry" yaml:"retry"`
 }
 
 // NewElasticsearchConfig creates a new ElasticsearchConfig with default values.
@@ -55,6 +57,7 @@ func NewElasticsearchConfig() ElasticsearchConfig {
 		Index:     "benthos_index",
 		TimeoutMS: 5000,
 		Auth:      auth.NewBasicAuthConfig(),
+		Retry:     retries.NewConfig(),
 	}
 }
 
@@ -21,6 +21,7 @@
 package writer
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -29,6 +30,8 @@ import (
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/parquet"
+	"github.com/Jeffail/benthos/lib/util/retries"
 	"github.com/Jeffail/benthos/lib/util/text"
 )
 
@@ -36,26 +39,35 @@ import (
 
 // FilesConfig contains configuration fields for the files output type.
 type FilesConfig struct {
-	Path string `json:"path" yaml:"path"`
+	Path     string         `json:"path" yaml:"path"`
+	Encoding string         `json:"encoding" yaml:"encoding"`
+	Parquet  parquet.Config `json:"parquet" yaml:"parquet"`
+	Retry    retries.Config `json:"retry" yaml:"retry"`
 }
 
 // NewFilesConfig creates a new Config with default values.
 func NewFilesConfig() FilesConfig {
 	return FilesConfig{
-		Path: "${!count:files}-${!timestamp_unix_nano}.txt",
+		Path:     "${!count:files}-${!timestamp_unix_nano}.txt",
+		Encoding: "raw",
+		Parquet:  parquet.NewConfig(),
+		Retry:    retries.NewConfig(),
 	}
 }
 
 //------------------------------------------------------------------------------
 
 // Files is a benthos writer.Type implementation that writes message parts each
-// to their own file.
+// to their own file, or entire messages to a single file when an encoding such
+// as parquet is used.
 type Files struct {
 	conf FilesConfig
 
 	pathBytes       []byte
 	interpolatePath bool
 
+	parquet *parquet.Encoder
+
 	log   log.Modular
 	stats metrics.Type
 }
@@ -65,16 +77,27 @@ func NewFiles(
 	conf FilesConfig,
 	log log.Modular,
 	stats metrics.Type,
-) *Files {
+) (*Files, error) {
 	pathBytes := []byte(conf.Path)
 	interpolatePath := text.ContainsFunctionVariables(pathBytes)
-	return &Files{
+	f := &Files{
 		conf:            conf,
 		pathBytes:       pathBytes,
 		interpolatePath: interpolatePath,
 		log:             log.NewModule(".output.files"),
 		stats:           stats,
 	}
+	switch conf.Encoding {
+	case "raw":
+	case "parquet":
+		var err error
+		if f.parquet, err = parquet.NewEncoder(conf.Parquet); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("encoding not recognised: %v", conf.Encoding)
+	}
+	return f, nil
 }
 
 // Connect is a noop.
@@ -85,23 +108,34 @@ func (f *Files) Connect() error {
 
 // Write attempts to write message contents to a directory as files.
 func (f *Files) Write(msg types.Message) error {
-	for _, part := range msg.GetAll() {
-		path := f.conf.Path
-		if f.interpolatePath {
-			path = string(text.ReplaceFunctionVariables(msg, f.pathBytes))
-		}
-
-		err := os.MkdirAll(filepath.Dir(path), os.FileMode(0777))
+	if f.parquet != nil {
+		file, err := f.parquet.Encode(msg.GetAll())
 		if err != nil {
 			return err
 		}
-		if err = ioutil.WriteFile(path, part, os.FileMode(0666)); err != nil {
+		return f.writeFile(msg, file)
+	}
+	for _, part := range msg.GetAll() {
+		if err := f.writeFile(msg, part); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+func (f *Files) writeFile(msg types.Message, contents []byte) error {
+	path := f.conf.Path
+	if f.interpolatePath {
+		path = string(text.ReplaceFunctionVariables(msg, f.pathBytes))
+	}
+
+	err := os.MkdirAll(filepath.Dir(path), os.FileMode(0777))
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, contents, os.FileMode(0666))
+}
+
 // CloseAsync begins cleaning up resources used by this reader asynchronously.
 func (f *Files) CloseAsync() {
 }
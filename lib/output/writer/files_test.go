@@ -0,0 +1,120 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+func TestFilesRaw(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "benthos_files_output_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	conf := NewFilesConfig()
+	conf.Path = filepath.Join(tmpDir, "${!count:files_raw_test}.txt")
+
+	f, err := NewFiles(conf, log.New(os.Stdout, log.Config{LogLevel: "NONE"}), metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = f.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	if err = f.Write(message.New([][]byte{[]byte("foo"), []byte("bar")})); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, exp := range []string{"foo", "bar"} {
+		act, err := ioutil.ReadFile(filepath.Join(tmpDir, []string{"1.txt", "2.txt"}[i]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exp != string(act) {
+			t.Errorf("Wrong file contents: %s != %v", act, exp)
+		}
+	}
+}
+
+func TestFilesParquet(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "benthos_files_output_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	conf := NewFilesConfig()
+	conf.Path = filepath.Join(tmpDir, "${!count:files_parquet_test}.parquet")
+	conf.Encoding = "parquet"
+
+	f, err := NewFiles(conf, log.New(os.Stdout, log.Config{LogLevel: "NONE"}), metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = f.Write(message.New([][]byte{
+		[]byte(`{"id":1}`),
+		[]byte(`{"id":2}`),
+	})); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := ioutil.ReadDir(tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := 1, len(files); exp != act {
+		t.Fatalf("Wrong count of files: %v != %v", act, exp)
+	}
+
+	contents, err := ioutil.ReadFile(filepath.Join(tmpDir, "1.parquet"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(contents, []byte("PAR1")) || !bytes.HasSuffix(contents, []byte("PAR1")) {
+		t.Errorf("Expected parquet file: %q", contents)
+	}
+
+	if err = f.Write(message.New([][]byte{[]byte(`not json`)})); err == nil {
+		t.Error("Expected error from bad row")
+	}
+}
+
+func TestFilesBadEncoding(t *testing.T) {
+	conf := NewFilesConfig()
+	conf.Encoding = "nope"
+	if _, err := NewFiles(conf, log.New(os.Stdout, log.Config{LogLevel: "NONE"}), metrics.DudType{}); err == nil {
+		t.Error("Expected error from bad encoding")
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -27,6 +27,7 @@ import (
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
 	"github.com/Jeffail/benthos/lib/util/http/client"
+	"github.com/Jeffail/benthos/lib/util/retries"
 )
 
 //------------------------------------------------------------------------------
@@ -35,12 +36,14 @@ import (
 // type.
 type HTTPClientConfig struct {
 	client.Config `json:",inline" yaml:",inline"`
+	Retry         retries.Config `json:"retry" yaml:"retry"`
 }
 
 // NewHTTPClientConfig creates a new HTTPClientConfig with default values.
 func NewHTTPClientConfig() HTTPClientConfig {
 	return HTTPClientConfig{
 		Config: client.NewConfig(),
+		Retry:  retries.NewConfig(),
 	}
 }
 
@@ -21,9 +21,17 @@
 package writer
 
 import (
+	"errors"
+
 	"github.com/Jeffail/benthos/lib/types"
 )
 
+// ErrMessagePending is returned by Write when a message has been accepted by a
+// writer but is not yet written to the sink. The message is acknowledged along
+// with all prior pending messages once a later call to Write succeeds, and is
+// failed along with them if a later call to Write fails.
+var ErrMessagePending = errors.New("message is pending a later write")
+
 // Type is a type that writes Benthos messages to a third party sink. If the
 // protocol supports a form of acknowledgement then it will be returned by the
 // call to Write.
@@ -22,7 +22,9 @@ package writer
 
 import (
 	"crypto/tls"
+	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -30,6 +32,7 @@ import (
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/retries"
 	"github.com/Jeffail/benthos/lib/util/text"
 	btls "github.com/Jeffail/benthos/lib/util/tls"
 	"github.com/Shopify/sarama"
@@ -37,19 +40,41 @@ import (
 
 //------------------------------------------------------------------------------
 
+// KafkaMetadataHeadersConfig contains configuration fields for writing message
+// metadata as Kafka record headers.
+type KafkaMetadataHeadersConfig struct {
+	Enabled  bool     `json:"enabled" yaml:"enabled"`
+	Keys     []string `json:"keys" yaml:"keys"`
+	Prefixes []string `json:"prefixes" yaml:"prefixes"`
+}
+
+// NewKafkaMetadataHeadersConfig creates a new KafkaMetadataHeadersConfig with
+// default values.
+func NewKafkaMetadataHeadersConfig() KafkaMetadataHeadersConfig {
+	return KafkaMetadataHeadersConfig{
+		Enabled:  false,
+		Keys:     []string{},
+		Prefixes: []string{},
+	}
+}
+
 // KafkaConfig contains configuration fields for the Kafka output type.
 type KafkaConfig struct {
-	Addresses            []string    `json:"addresses" yaml:"addresses"`
-	ClientID             string      `json:"client_id" yaml:"client_id"`
-	Key                  string      `json:"key" yaml:"key"`
-	RoundRobinPartitions bool        `json:"round_robin_partitions" yaml:"round_robin_partitions"`
-	Topic                string      `json:"topic" yaml:"topic"`
-	Compression          string      `json:"compression" yaml:"compression"`
-	MaxMsgBytes          int         `json:"max_msg_bytes" yaml:"max_msg_bytes"`
-	TimeoutMS            int         `json:"timeout_ms" yaml:"timeout_ms"`
-	AckReplicas          bool        `json:"ack_replicas" yaml:"ack_replicas"`
-	TargetVersion        string      `json:"target_version" yaml:"target_version"`
-	TLS                  btls.Config `json:"tls" yaml:"tls"`
+	Addresses            []string                   `json:"addresses" yaml:"addresses"`
+	ClientID             string                     `json:"client_id" yaml:"client_id"`
+	Key                  string                     `json:"key" yaml:"key"`
+	Partitioner          string                     `json:"partitioner" yaml:"partitioner"`
+	Partition            string                     `json:"partition" yaml:"partition"`
+	RoundRobinPartitions bool                       `json:"round_robin_partitions" yaml:"round_robin_partitions"`
+	Topic                string                     `json:"topic" yaml:"topic"`
+	MetadataHeaders      KafkaMetadataHeadersConfig `json:"metadata_headers" yaml:"metadata_headers"`
+	Compression          string                     `json:"compression" yaml:"compression"`
+	MaxMsgBytes          int                        `json:"max_msg_bytes" yaml:"max_msg_bytes"`
+	TimeoutMS            int                        `json:"timeout_ms" yaml:"timeout_ms"`
+	AckReplicas          bool                       `json:"ack_replicas" yaml:"ack_replicas"`
+	TargetVersion        string                     `json:"target_version" yaml:"target_version"`
+	TLS                  btls.Config                `json:"tls" yaml:"tls"`
+	Retry                retries.Config             `json:"retry" yaml:"retry"`
 }
 
 // NewKafkaConfig creates a new KafkaConfig with default values.
@@ -58,14 +83,18 @@ func NewKafkaConfig() KafkaConfig {
 		Addresses:            []string{"localhost:9092"},
 		ClientID:             "benthos_kafka_output",
 		Key:                  "",
+		Partitioner:          "hash",
+		Partition:            "",
 		RoundRobinPartitions: false,
 		Topic:                "benthos_stream",
+		MetadataHeaders:      NewKafkaMetadataHeadersConfig(),
 		Compression:          "none",
 		MaxMsgBytes:          1000000,
 		TimeoutMS:            5000,
 		AckReplicas:          false,
 		TargetVersion:        sarama.V1_0_0_0.String(),
 		TLS:                  btls.NewConfig(),
+		Retry:                retries.NewConfig(),
 	}
 }
 
@@ -82,10 +111,12 @@ type Kafka struct {
 	version   sarama.KafkaVersion
 	conf      KafkaConfig
 
-	key *text.InterpolatedBytes
+	key       *text.InterpolatedBytes
+	partition *text.InterpolatedString
 
 	producer    sarama.SyncProducer
 	compression sarama.CompressionCodec
+	partitioner sarama.PartitionerConstructor
 
 	connMut sync.RWMutex
 }
@@ -97,12 +128,26 @@ func NewKafka(conf KafkaConfig, log log.Modular, stats metrics.Type) (*Kafka, er
 		return nil, err
 	}
 
+	partitioner, err := strToPartitioner(conf.Partitioner)
+	if err != nil {
+		return nil, err
+	}
+	if conf.RoundRobinPartitions {
+		partitioner = sarama.NewRoundRobinPartitioner
+	}
+
+	if conf.Partitioner == "manual" && len(conf.Partition) == 0 {
+		return nil, errors.New("a partition must be specified when using the manual partitioner")
+	}
+
 	k := Kafka{
 		log:         log.NewModule(".output.kafka"),
 		stats:       stats,
 		conf:        conf,
 		key:         text.NewInterpolatedBytes([]byte(conf.Key)),
+		partition:   text.NewInterpolatedString(conf.Partition),
 		compression: compression,
+		partitioner: partitioner,
 	}
 
 	if conf.TLS.Enabled {
@@ -143,6 +188,59 @@ func strToCompressionCodec(str string) (sarama.CompressionCodec, error) {
 	return sarama.CompressionNone, fmt.Errorf("compression codec not recognised: %v", str)
 }
 
+func strToPartitioner(str string) (sarama.PartitionerConstructor, error) {
+	switch str {
+	case "hash":
+		return sarama.NewHashPartitioner, nil
+	case "random":
+		return sarama.NewRandomPartitioner, nil
+	case "round_robin":
+		return sarama.NewRoundRobinPartitioner, nil
+	case "manual":
+		return sarama.NewManualPartitioner, nil
+	}
+	return nil, fmt.Errorf("partitioner not recognised: %v", str)
+}
+
+// metadataHeaders returns the metadata of a message that should be written as
+// Kafka record headers.
+func (k *Kafka) metadataHeaders(msg types.Message) []sarama.RecordHeader {
+	if !k.conf.MetadataHeaders.Enabled {
+		return nil
+	}
+	var headers []sarama.RecordHeader
+	msg.IterMetadata(func(key, value string) error {
+		if k.headerAllowed(key) {
+			headers = append(headers, sarama.RecordHeader{
+				Key:   []byte(key),
+				Value: []byte(value),
+			})
+		}
+		return nil
+	})
+	return headers
+}
+
+// headerAllowed returns whether a metadata key passes the configured allowlist
+// and prefix filters. When neither are set all keys are allowed.
+func (k *Kafka) headerAllowed(key string) bool {
+	keys, prefixes := k.conf.MetadataHeaders.Keys, k.conf.MetadataHeaders.Prefixes
+	if len(keys) == 0 && len(prefixes) == 0 {
+		return true
+	}
+	for _, allowed := range keys {
+		if key == allowed {
+			return true
+		}
+	}
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
 //------------------------------------------------------------------------------
 
 // Connect attempts to establish a connection to a Kafka broker.
@@ -169,9 +267,7 @@ func (k *Kafka) Connect() error {
 		config.Net.TLS.Config = k.tlsConf
 	}
 
-	if k.conf.RoundRobinPartitions {
-		config.Producer.Partitioner = sarama.NewRoundRobinPartitioner
-	}
+	config.Producer.Partitioner = k.partitioner
 
 	if k.conf.AckReplicas {
 		config.Producer.RequiredAcks = sarama.WaitForAll
@@ -199,6 +295,18 @@ func (k *Kafka) Write(msg types.Message) error {
 		return types.ErrNotConnected
 	}
 
+	var partition int32
+	if k.conf.Partitioner == "manual" && !k.conf.RoundRobinPartitions {
+		partitionStr := k.partition.Get(msg)
+		p, err := strconv.ParseInt(partitionStr, 10, 32)
+		if err != nil {
+			return fmt.Errorf("failed to parse partition '%v': %v", partitionStr, err)
+		}
+		partition = int32(p)
+	}
+
+	headers := k.metadataHeaders(msg)
+
 	msgs := []*sarama.ProducerMessage{}
 	for _, part := range msg.GetAll() {
 		if len(part) > k.conf.MaxMsgBytes {
@@ -208,8 +316,10 @@ func (k *Kafka) Write(msg types.Message) error {
 
 		key := k.key.Get(msg)
 		nextMsg := &sarama.ProducerMessage{
-			Topic: k.conf.Topic,
-			Value: sarama.ByteEncoder(part),
+			Topic:     k.conf.Topic,
+			Value:     sarama.ByteEncoder(part),
+			Partition: partition,
+			Headers:   headers,
 		}
 		if len(key) > 0 {
 			nextMsg.Key = sarama.ByteEncoder(key)
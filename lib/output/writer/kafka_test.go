@@ -0,0 +1,122 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+func TestKafkaBadPartitioner(t *testing.T) {
+	conf := NewKafkaConfig()
+	conf.Partitioner = "nope"
+	if _, err := NewKafka(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad partitioner")
+	}
+
+	conf.Partitioner = "manual"
+	if _, err := NewKafka(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from manual partitioner without partition")
+	}
+
+	conf.Partition = "${!metadata:partition}"
+	if _, err := NewKafka(conf, log.Noop(), metrics.Noop()); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestKafkaMetadataHeaders(t *testing.T) {
+	msg := message.New([][]byte{[]byte("hello world")})
+	msg.SetMetadata("kafka_key", "foo")
+	msg.SetMetadata("trace_id", "bar")
+	msg.SetMetadata("trace_span", "baz")
+	msg.SetMetadata("user", "qux")
+
+	tests := map[string]struct {
+		enabled  bool
+		keys     []string
+		prefixes []string
+		exp      map[string]string
+	}{
+		"disabled": {
+			enabled: false,
+			exp:     map[string]string{},
+		},
+		"all metadata": {
+			enabled: true,
+			exp: map[string]string{
+				"kafka_key":  "foo",
+				"trace_id":   "bar",
+				"trace_span": "baz",
+				"user":       "qux",
+			},
+		},
+		"allowlist": {
+			enabled: true,
+			keys:    []string{"user", "nope"},
+			exp: map[string]string{
+				"user": "qux",
+			},
+		},
+		"prefixes": {
+			enabled:  true,
+			prefixes: []string{"trace_"},
+			exp: map[string]string{
+				"trace_id":   "bar",
+				"trace_span": "baz",
+			},
+		},
+		"allowlist and prefixes": {
+			enabled:  true,
+			keys:     []string{"user"},
+			prefixes: []string{"trace_"},
+			exp: map[string]string{
+				"trace_id":   "bar",
+				"trace_span": "baz",
+				"user":       "qux",
+			},
+		},
+	}
+
+	for name, test := range tests {
+		conf := NewKafkaConfig()
+		conf.MetadataHeaders.Enabled = test.enabled
+		conf.MetadataHeaders.Keys = test.keys
+		conf.MetadataHeaders.Prefixes = test.prefixes
+
+		k, err := NewKafka(conf, log.Noop(), metrics.Noop())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		act := map[string]string{}
+		for _, h := range k.metadataHeaders(msg) {
+			act[string(h.Key)] = string(h.Value)
+		}
+		if !reflect.DeepEqual(test.exp, act) {
+			t.Errorf("Wrong headers for %v: %v != %v", name, act, test.exp)
+		}
+	}
+}
@@ -28,6 +28,7 @@ import (
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/retries"
 	mqtt "github.com/eclipse/paho.mqtt.golang"
 )
 
@@ -35,10 +36,11 @@ import (
 
 // MQTTConfig contains configuration fields for the MQTT output type.
 type MQTTConfig struct {
-	URLs     []string `json:"urls" yaml:"urls"`
-	QoS      uint8    `json:"qos" yaml:"qos"`
-	Topic    string   `json:"topic" yaml:"topic"`
-	ClientID string   `json:"client_id" yaml:"client_id"`
+	URLs     []string       `json:"urls" yaml:"urls"`
+	QoS      uint8          `json:"qos" yaml:"qos"`
+	Topic    string         `json:"topic" yaml:"topic"`
+	ClientID string         `json:"client_id" yaml:"client_id"`
+	Retry    retries.Config `json:"retry" yaml:"retry"`
 }
 
 // NewMQTTConfig creates a new MQTTConfig with default values.
@@ -48,6 +50,7 @@ func NewMQTTConfig() MQTTConfig {
 		QoS:      1,
 		Topic:    "benthos_topic",
 		ClientID: "benthos_output",
+		Retry:    retries.NewConfig(),
 	}
 }
 
@@ -28,6 +28,7 @@ import (
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/retries"
 	"github.com/go-redis/redis"
 )
 
@@ -35,15 +36,17 @@ import (
 
 // RedisListConfig contains configuration fields for the RedisList output type.
 type RedisListConfig struct {
-	URL string `json:"url" yaml:"url"`
-	Key string `json:"key" yaml:"key"`
+	URL   string         `json:"url" yaml:"url"`
+	Key   string         `json:"key" yaml:"key"`
+	Retry retries.Config `json:"retry" yaml:"retry"`
 }
 
 // NewRedisListConfig creates a new RedisListConfig with default values.
 func NewRedisListConfig() RedisListConfig {
 	return RedisListConfig{
-		URL: "tcp://localhost:6379",
-		Key: "benthos_list",
+		URL:   "tcp://localhost:6379",
+		Key:   "benthos_list",
+		Retry: retries.NewConfig(),
 	}
 }
 
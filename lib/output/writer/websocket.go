@@ -30,6 +30,7 @@ import (
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
 	"github.com/Jeffail/benthos/lib/util/http/auth"
+	"github.com/Jeffail/benthos/lib/util/retries"
 	"github.com/gorilla/websocket"
 )
 
@@ -39,6 +40,7 @@ import (
 type WebsocketConfig struct {
 	URL         string `json:"url" yaml:"url"`
 	auth.Config `json:",inline" yaml:",inline"`
+	Retry       retries.Config `json:"retry" yaml:"retry"`
 }
 
 // NewWebsocketConfig creates a new WebsocketConfig with default values.
@@ -46,6 +48,7 @@ func NewWebsocketConfig() WebsocketConfig {
 	return WebsocketConfig{
 		URL:    "ws://localhost:4195/post/ws",
 		Config: auth.NewConfig(),
+		Retry:  retries.NewConfig(),
 	}
 }
 
@@ -29,6 +29,7 @@ import (
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/retries"
 	"github.com/pebbe/zmq4"
 )
 
@@ -36,11 +37,12 @@ import (
 
 // ZMQ4Config contains configuration fields for the ZMQ4 output type.
 type ZMQ4Config struct {
-	URLs          []string `json:"urls" yaml:"urls"`
-	Bind          bool     `json:"bind" yaml:"bind"`
-	SocketType    string   `json:"socket_type" yaml:"socket_type"`
-	HighWaterMark int      `json:"high_water_mark" yaml:"high_water_mark"`
-	PollTimeoutMS int      `json:"poll_timeout_ms" yaml:"poll_timeout_ms"`
+	URLs          []string       `json:"urls" yaml:"urls"`
+	Bind          bool           `json:"bind" yaml:"bind"`
+	SocketType    string         `json:"socket_type" yaml:"socket_type"`
+	HighWaterMark int            `json:"high_water_mark" yaml:"high_water_mark"`
+	PollTimeoutMS int            `json:"poll_timeout_ms" yaml:"poll_timeout_ms"`
+	Retry         retries.Config `json:"retry" yaml:"retry"`
 }
 
 // NewZMQ4Config creates a new ZMQ4Config with default values.
@@ -51,6 +53,7 @@ func NewZMQ4Config() *ZMQ4Config {
 		SocketType:    "PUSH",
 		HighWaterMark: 0,
 		PollTimeoutMS: 5000,
+		Retry:         retries.NewConfig(),
 	}
 }
 
@@ -30,7 +30,9 @@ import (
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/message"
 	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/output/writer"
 	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/retries"
 )
 
 //------------------------------------------------------------------------------
@@ -570,4 +572,297 @@ func TestWriterSadPath(t *testing.T) {
 	}
 }
 
+func TestWriterBadRetryConfig(t *testing.T) {
+	t.Parallel()
+
+	retryConf := retries.NewConfig()
+	retryConf.OnFailure = "nope"
+
+	if _, err := NewWriter(
+		"foo", newMockWriter(),
+		log.New(os.Stdout, logConfig), metrics.DudType{},
+		OptWriterSetRetries(retryConf),
+	); err == nil {
+		t.Error("Expected error from bad on_failure")
+	}
+}
+
+func TestWriterRetries(t *testing.T) {
+	t.Parallel()
+
+	writerImpl := newMockWriter()
+
+	exp := [][]byte{[]byte("foo"), []byte("bar")}
+	errTest := errors.New("message got lost or something")
+
+	retryConf := retries.NewConfig()
+	retryConf.MaxRetries = 2
+	retryConf.InitialPeriodMS = 1
+
+	w, err := NewWriter(
+		"foo", writerImpl,
+		log.New(os.Stdout, logConfig), metrics.DudType{},
+		OptWriterSetRetries(retryConf),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+
+	if err = w.Consume(msgChan); err != nil {
+		t.Error(err)
+	}
+
+	go func() {
+		select {
+		case msgChan <- types.NewTransaction(message.New(exp), resChan):
+		case <-time.After(time.Second):
+			t.Error("Timed out")
+		}
+	}()
+
+	select {
+	case writerImpl.connChan <- nil:
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+	for _, wErr := range []error{errTest, errTest, nil} {
+		select {
+		case writerImpl.writeChan <- wErr:
+		case <-resChan:
+			t.Fatal("Premature response")
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+	}
+
+	select {
+	case res := <-resChan:
+		if actErr := res.Error(); actErr != nil {
+			t.Errorf("Unexpected error: %v", actErr)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	w.CloseAsync()
+	if err = w.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+
+	if act := writerImpl.msgRcvd.GetAll(); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong message sent: %v != %v", act, exp)
+	}
+}
+
+func TestWriterRetriesExhausted(t *testing.T) {
+	t.Parallel()
+
+	errTest := errors.New("message got lost or something")
+
+	tests := map[string]error{
+		retries.OnFailureNack: errTest,
+		retries.OnFailureDrop: nil,
+	}
+
+	for onFailure, expErr := range tests {
+		writerImpl := newMockWriter()
+
+		retryConf := retries.NewConfig()
+		retryConf.MaxRetries = 1
+		retryConf.InitialPeriodMS = 1
+		retryConf.OnFailure = onFailure
+
+		w, err := NewWriter(
+			"foo", writerImpl,
+			log.New(os.Stdout, logConfig), metrics.DudType{},
+			OptWriterSetRetries(retryConf),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		msgChan := make(chan types.Transaction)
+		resChan := make(chan types.Response)
+
+		if err = w.Consume(msgChan); err != nil {
+			t.Error(err)
+		}
+
+		go func() {
+			select {
+			case msgChan <- types.NewTransaction(message.New(nil), resChan):
+			case <-time.After(time.Second):
+				t.Error("Timed out")
+			}
+		}()
+
+		select {
+		case writerImpl.connChan <- nil:
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+		for i := 0; i < 2; i++ {
+			select {
+			case writerImpl.writeChan <- errTest:
+			case <-resChan:
+				t.Fatal("Premature response")
+			case <-time.After(time.Second):
+				t.Fatal("Timed out")
+			}
+		}
+
+		select {
+		case res := <-resChan:
+			if actErr := res.Error(); expErr != actErr {
+				t.Errorf("Wrong response for %v: %v != %v", onFailure, actErr, expErr)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+
+		w.CloseAsync()
+		if err = w.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestWriterReconnectRetriesExhausted(t *testing.T) {
+	t.Parallel()
+
+	writerImpl := newMockWriter()
+
+	errTest := errors.New("connection refused or something")
+
+	retryConf := retries.NewConfig()
+	retryConf.MaxRetries = 1
+	retryConf.InitialPeriodMS = 1
+
+	w, err := NewWriter(
+		"foo", writerImpl,
+		log.New(os.Stdout, logConfig), metrics.DudType{},
+		OptWriterSetRetries(retryConf),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+
+	if err = w.Consume(msgChan); err != nil {
+		t.Error(err)
+	}
+
+	go func() {
+		select {
+		case msgChan <- types.NewTransaction(message.New(nil), resChan):
+		case <-time.After(time.Second):
+			t.Error("Timed out")
+		}
+	}()
+
+	select {
+	case writerImpl.connChan <- nil:
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	// Each attempt to reconnect and resend counts towards the retries.
+	for i := 0; i < 2; i++ {
+		select {
+		case writerImpl.writeChan <- types.ErrNotConnected:
+		case <-resChan:
+			t.Fatal("Premature response")
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+		select {
+		case writerImpl.connChan <- errTest:
+		case <-resChan:
+			t.Fatal("Premature response")
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+	}
+
+	select {
+	case res := <-resChan:
+		if actErr := res.Error(); actErr != errTest {
+			t.Errorf("Wrong response: %v != %v", actErr, errTest)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	w.CloseAsync()
+	if err = w.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWriterPending(t *testing.T) {
+	t.Parallel()
+
+	writerImpl := newMockWriter()
+
+	retryConf := retries.NewConfig()
+	retryConf.MaxRetries = 2
+	retryConf.InitialPeriodMS = 1
+
+	w, err := NewWriter(
+		"foo", writerImpl,
+		log.New(os.Stdout, logConfig), metrics.DudType{},
+		OptWriterSetRetries(retryConf),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+
+	if err = w.Consume(msgChan); err != nil {
+		t.Error(err)
+	}
+
+	select {
+	case writerImpl.connChan <- nil:
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	for _, wErr := range []error{writer.ErrMessagePending, nil} {
+		select {
+		case msgChan <- types.NewTransaction(message.New(nil), resChan):
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+		select {
+		case writerImpl.writeChan <- wErr:
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+		select {
+		case res := <-resChan:
+			if actErr := res.Error(); actErr != nil {
+				t.Errorf("Unexpected error: %v", actErr)
+			}
+			if exp, act := wErr != nil, res.SkipAck(); exp != act {
+				t.Errorf("Wrong skip ack: %v != %v", act, exp)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+	}
+
+	w.CloseAsync()
+	if err = w.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
 //------------------------------------------------------------------------------
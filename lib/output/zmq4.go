@@ -50,6 +50,7 @@ func NewZMQ4(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type
 	}
 	return NewWriter(
 		"zmq4", z, log, stats,
+		OptWriterSetRetries(conf.ZMQ4.Retry),
 	)
 }
 
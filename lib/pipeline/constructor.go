@@ -22,6 +22,7 @@ package pipeline
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/Jeffail/benthos/lib/log"
@@ -75,6 +76,12 @@ func SanitiseConfig(conf Config) (interface{}, error) {
 
 //------------------------------------------------------------------------------
 
+// ErrWindowCacheThreads is returned when creating a pipeline with more than one
+// thread that contains a window processor with a cache.
+var ErrWindowCacheThreads = errors.New("window processors cannot use a cache within a pipeline of more than one thread")
+
+//------------------------------------------------------------------------------
+
 // New creates an input type based on an input configuration.
 func New(
 	conf Config,
@@ -83,6 +90,14 @@ func New(
 	stats metrics.Type,
 	processorCtors ...types.ProcessorConstructorFunc,
 ) (Type, error) {
+	if conf.Threads > 1 {
+		for _, procConf := range conf.Processors {
+			// Each thread would checkpoint its windows to the same cache key.
+			if procConf.Type == processor.TypeWindow && len(procConf.Window.Cache) > 0 {
+				return nil, ErrWindowCacheThreads
+			}
+		}
+	}
 	procCtor := func() (types.Pipeline, error) {
 		processors := make([]types.Processor, len(conf.Processors)+len(processorCtors))
 		for i, procConf := range conf.Processors {
@@ -177,3 +177,18 @@ func TestProcCtor(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestWindowCacheThreads(t *testing.T) {
+	proc := processor.NewConfig()
+	proc.Type = "window"
+	proc.Window.Cache = "foo"
+
+	conf := NewConfig()
+	conf.Threads = 2
+	conf.Processors = append(conf.Processors, proc)
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+	if _, err := New(conf, nil, testLog, metrics.DudType{}); err != ErrWindowCacheThreads {
+		t.Errorf("Wrong error returned: %v != %v", err, ErrWindowCacheThreads)
+	}
+}
@@ -0,0 +1,275 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package pipeline
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/throttle"
+)
+
+//------------------------------------------------------------------------------
+
+// Metadata keys added to messages that are routed to a dead letter output.
+const (
+	DeadLetterComponentKey = "dead_letter_component"
+	DeadLetterErrorKey     = "dead_letter_error"
+	DeadLetterAttemptsKey  = "dead_letter_attempts"
+)
+
+//------------------------------------------------------------------------------
+
+// DeadLetter is a pipeline that forwards transactions downstream and, when a
+// transaction has failed more times than a retry budget allows, routes the
+// original message to a dead letter channel instead of returning the error
+// upstream. Messages sent to the dead letter channel are annotated with
+// metadata describing the failing component, the last error and the number of
+// attempts made.
+//
+// Transactions are handled by a number of parallel threads so that the
+// pipeline does not serialise the layers behind it.
+type DeadLetter struct {
+	running int32
+
+	component  string
+	maxRetries int
+	period     time.Duration
+	threads    int
+
+	log   log.Modular
+	stats metrics.Type
+
+	messagesIn  <-chan types.Transaction
+	messagesOut chan types.Transaction
+	deadLetters chan<- types.Transaction
+
+	closeChan chan struct{}
+	closed    chan struct{}
+}
+
+// NewDeadLetter returns a new dead letter pipeline, where component is a label
+// for the stage of the stream being protected and deadLetters is a channel
+// that failed messages are sent through once the retry budget is exhausted.
+// The number of threads should match the number of parallel consumers of the
+// pipeline.
+func NewDeadLetter(
+	component string,
+	maxRetries int,
+	retryPeriod time.Duration,
+	threads int,
+	deadLetters chan<- types.Transaction,
+	log log.Modular,
+	stats metrics.Type,
+) *DeadLetter {
+	return &DeadLetter{
+		running:     1,
+		component:   component,
+		maxRetries:  maxRetries,
+		period:      retryPeriod,
+		threads:     threads,
+		log:         log.NewModule(".pipeline.dead_letter." + component),
+		stats:       stats,
+		messagesOut: make(chan types.Transaction),
+		deadLetters: deadLetters,
+		closeChan:   make(chan struct{}),
+		closed:      make(chan struct{}),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// loop is the processing loop of this pipeline, which runs a worker for each
+// thread and closes the pipeline once they have all finished.
+func (d *DeadLetter) loop() {
+	defer func() {
+		atomic.StoreInt32(&d.running, 0)
+
+		close(d.messagesOut)
+		close(d.closed)
+	}()
+
+	threads := d.threads
+	if threads < 1 {
+		threads = 1
+	}
+
+	wg := sync.WaitGroup{}
+	wg.Add(threads)
+	for i := 0; i < threads; i++ {
+		go func() {
+			defer wg.Done()
+			d.worker()
+		}()
+	}
+	wg.Wait()
+}
+
+// worker reads transactions and forwards them downstream until the input
+// closes or the pipeline is shut down.
+func (d *DeadLetter) worker() {
+	var (
+		mCount    = d.stats.GetCounter("pipeline.dead_letter." + d.component + ".count")
+		mRetry    = d.stats.GetCounter("pipeline.dead_letter." + d.component + ".retry")
+		mSent     = d.stats.GetCounter("pipeline.dead_letter." + d.component + ".send.success")
+		mSentErr  = d.stats.GetCounter("pipeline.dead_letter." + d.component + ".send.error")
+		resChan   = make(chan types.Response)
+		dlResChan = make(chan types.Response)
+	)
+
+	throt := throttle.New(
+		throttle.OptMaxUnthrottledRetries(0),
+		throttle.OptThrottlePeriod(d.period),
+		throttle.OptCloseChan(d.closeChan),
+	)
+
+	for atomic.LoadInt32(&d.running) == 1 {
+		var tran types.Transaction
+		var open bool
+		select {
+		case tran, open = <-d.messagesIn:
+			if !open {
+				return
+			}
+		case <-d.closeChan:
+			return
+		}
+		mCount.Incr(1)
+
+		var res types.Response
+		attempts := 0
+	retryLoop:
+		for {
+			select {
+			case d.messagesOut <- types.NewTransaction(tran.Payload, resChan):
+			case <-d.closeChan:
+				return
+			}
+			select {
+			case res, open = <-resChan:
+				if !open {
+					return
+				}
+			case <-d.closeChan:
+				return
+			}
+			attempts++
+			if res.Error() == nil || attempts > d.maxRetries {
+				break retryLoop
+			}
+			mRetry.Incr(1)
+			if !throt.Retry() {
+				return
+			}
+		}
+		throt.Reset()
+
+		if err := res.Error(); err != nil {
+			d.log.Warnf(
+				"Routing message to dead letter output after %v failed attempts: %v\n",
+				attempts, err,
+			)
+
+			msg := tran.Payload.ShallowCopy()
+			msg.SetMetadata(DeadLetterComponentKey, d.component)
+			msg.SetMetadata(DeadLetterErrorKey, err.Error())
+			msg.SetMetadata(DeadLetterAttemptsKey, strconv.Itoa(attempts))
+
+			for {
+				select {
+				case d.deadLetters <- types.NewTransaction(msg, dlResChan):
+				case <-d.closeChan:
+					return
+				}
+				var dlRes types.Response
+				select {
+				case dlRes, open = <-dlResChan:
+					if !open {
+						return
+					}
+				case <-d.closeChan:
+					return
+				}
+				if dlErr := dlRes.Error(); dlErr != nil {
+					mSentErr.Incr(1)
+					d.log.Errorf("Failed to send message to dead letter output: %v\n", dlErr)
+					if !throt.Retry() {
+						return
+					}
+					continue
+				}
+				mSent.Incr(1)
+				break
+			}
+			throt.Reset()
+			res = response.NewAck()
+		}
+
+		select {
+		case tran.ResponseChan <- res:
+		case <-d.closeChan:
+			return
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Consume assigns a messages channel for the pipeline to read.
+func (d *DeadLetter) Consume(msgs <-chan types.Transaction) error {
+	if d.messagesIn != nil {
+		return types.ErrAlreadyStarted
+	}
+	d.messagesIn = msgs
+	go d.loop()
+	return nil
+}
+
+// TransactionChan returns the channel used for consuming messages from this
+// pipeline.
+func (d *DeadLetter) TransactionChan() <-chan types.Transaction {
+	return d.messagesOut
+}
+
+// CloseAsync shuts down the pipeline and stops processing messages.
+func (d *DeadLetter) CloseAsync() {
+	if atomic.CompareAndSwapInt32(&d.running, 1, 0) {
+		close(d.closeChan)
+	}
+}
+
+// WaitForClose blocks until the DeadLetter pipeline has closed down.
+func (d *DeadLetter) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-d.closed:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,265 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package pipeline
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+func TestDeadLetterSuccess(t *testing.T) {
+	dlChan := make(chan types.Transaction)
+	dl := NewDeadLetter(
+		"output", 2, time.Millisecond, 1, dlChan,
+		log.New(os.Stdout, log.Config{LogLevel: "NONE"}),
+		metrics.DudType{},
+	)
+
+	tChan, resChan := make(chan types.Transaction), make(chan types.Response)
+	if err := dl.Consume(tChan); err != nil {
+		t.Fatal(err)
+	}
+	if err := dl.Consume(tChan); err == nil {
+		t.Error("Expected error from dupe listening")
+	}
+
+	msg := message.New([][]byte{[]byte("foo")})
+
+	select {
+	case tChan <- types.NewTransaction(msg, resChan):
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	var tran types.Transaction
+	select {
+	case tran = <-dl.TransactionChan():
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+	if exp, act := "foo", string(tran.Payload.Get(0)); exp != act {
+		t.Errorf("Wrong message: %v != %v", act, exp)
+	}
+
+	select {
+	case tran.ResponseChan <- response.NewUnack():
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	select {
+	case res := <-resChan:
+		if res.Error() != nil {
+			t.Error(res.Error())
+		}
+		if !res.SkipAck() {
+			t.Error("Expected skip ack to be propagated")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	dl.CloseAsync()
+	if err := dl.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDeadLetterRouting(t *testing.T) {
+	dlChan := make(chan types.Transaction)
+	dl := NewDeadLetter(
+		"output", 2, time.Millisecond, 1, dlChan,
+		log.New(os.Stdout, log.Config{LogLevel: "NONE"}),
+		metrics.DudType{},
+	)
+
+	tChan, resChan := make(chan types.Transaction), make(chan types.Response)
+	if err := dl.Consume(tChan); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := message.New([][]byte{[]byte("foo")})
+	msg.SetMetadata("baz", "qux")
+
+	select {
+	case tChan <- types.NewTransaction(msg, resChan):
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	errTest := errors.New("this is a test")
+
+	// Original attempt plus two retries.
+	for i := 0; i < 3; i++ {
+		var tran types.Transaction
+		select {
+		case tran = <-dl.TransactionChan():
+		case <-dlChan:
+			t.Fatalf("Message dead lettered after %v attempts", i)
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+		if exp, act := "foo", string(tran.Payload.Get(0)); exp != act {
+			t.Errorf("Wrong message: %v != %v", act, exp)
+		}
+		select {
+		case tran.ResponseChan <- response.NewError(errTest):
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+	}
+
+	var tran types.Transaction
+	select {
+	case tran = <-dlChan:
+	case <-dl.TransactionChan():
+		t.Fatal("Message was retried beyond budget")
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	if exp, act := "foo", string(tran.Payload.Get(0)); exp != act {
+		t.Errorf("Wrong message: %v != %v", act, exp)
+	}
+	for k, exp := range map[string]string{
+		"baz":                  "qux",
+		DeadLetterComponentKey: "output",
+		DeadLetterErrorKey:     "this is a test",
+		DeadLetterAttemptsKey:  "3",
+	} {
+		if act := tran.Payload.GetMetadata(k); exp != act {
+			t.Errorf("Wrong metadata value for '%v': %v != %v", k, act, exp)
+		}
+	}
+	if act := msg.GetMetadata(DeadLetterErrorKey); len(act) > 0 {
+		t.Errorf("Original message was modified: %v", act)
+	}
+
+	select {
+	case tran.ResponseChan <- response.NewAck():
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	select {
+	case res := <-resChan:
+		if res.Error() != nil {
+			t.Error(res.Error())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	dl.CloseAsync()
+	if err := dl.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDeadLetterParallel(t *testing.T) {
+	dl := NewDeadLetter(
+		"pipeline", 0, time.Millisecond, 2, make(chan types.Transaction),
+		log.New(os.Stdout, log.Config{LogLevel: "NONE"}),
+		metrics.DudType{},
+	)
+
+	tChan := make(chan types.Transaction)
+	if err := dl.Consume(tChan); err != nil {
+		t.Fatal(err)
+	}
+
+	resChans := []chan types.Response{
+		make(chan types.Response),
+		make(chan types.Response),
+	}
+	for i, resChan := range resChans {
+		msg := message.New([][]byte{[]byte(strconv.Itoa(i))})
+		select {
+		case tChan <- types.NewTransaction(msg, resChan):
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+	}
+
+	// Both transactions must be in flight at the same time.
+	trans := []types.Transaction{}
+	for i := 0; i < 2; i++ {
+		select {
+		case tran := <-dl.TransactionChan():
+			trans = append(trans, tran)
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+	}
+
+	for _, tran := range trans {
+		select {
+		case tran.ResponseChan <- response.NewAck():
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+	}
+	for _, resChan := range resChans {
+		select {
+		case res := <-resChan:
+			if res.Error() != nil {
+				t.Error(res.Error())
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+	}
+
+	dl.CloseAsync()
+	if err := dl.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDeadLetterClosesWithInput(t *testing.T) {
+	dl := NewDeadLetter(
+		"pipeline", 0, time.Millisecond, 1, make(chan types.Transaction),
+		log.New(os.Stdout, log.Config{LogLevel: "NONE"}),
+		metrics.DudType{},
+	)
+
+	tChan := make(chan types.Transaction)
+	if err := dl.Consume(tChan); err != nil {
+		t.Fatal(err)
+	}
+	close(tChan)
+
+	if err := dl.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+	if _, open := <-dl.TransactionChan(); open {
+		t.Error("Transaction chan still open")
+	}
+}
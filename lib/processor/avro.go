@@ -0,0 +1,334 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sync"
+
+	"github.com/linkedin/goavro"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/schemaregistry"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeAvro] = TypeSpec{
+		constructor: NewAvro,
+		description: `
+Converts message parts between Avro binary and JSON. The operator
+` + "`to_json`" + ` decodes Avro binary into JSON and the operator
+` + "`from_json`" + ` encodes JSON into Avro binary. JSON documents follow the
+[Avro JSON encoding](https://avro.apache.org/docs/1.8.2/spec.html#json_encoding),
+where union values are wrapped in an object keyed by their type.
+
+The schema can either be read from a local file with ` + "`schema_path`" + `,
+in which case parts are plain Avro binary, or be obtained from a
+[Confluent schema registry](https://docs.confluent.io/current/schema-registry/docs/index.html)
+by setting ` + "`schema_registry.url`" + `, in which case parts are framed in
+the Confluent wire format. When decoding with a registry the schema ID is read
+from the header of each part, and when encoding the schema of
+` + "`schema_id`" + ` is used and its ID is written to the header.
+
+Schemas obtained from a registry are kept in memory once parsed, and can also be
+stored in a cache resource by setting the ` + "`cache`" + ` field, which allows
+them to be shared between processors and reduces the number of requests made to
+the registry. Caches should be configured as a resource, for more information
+check out the [documentation here](../caches).
+
+Parts that fail to be converted are left unchanged and flagged as having failed,
+which can be handled with the ` + "[`catch`](#catch)" + ` processor.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// AvroConfig contains configuration fields for the Avro processor.
+type AvroConfig struct {
+	Parts          []int                 `json:"parts" yaml:"parts"`
+	Operator       string                `json:"operator" yaml:"operator"`
+	SchemaPath     string                `json:"schema_path" yaml:"schema_path"`
+	SchemaRegistry schemaregistry.Config `json:"schema_registry" yaml:"schema_registry"`
+	SchemaID       int                   `json:"schema_id" yaml:"schema_id"`
+	Cache          string                `json:"cache" yaml:"cache"`
+}
+
+// NewAvroConfig returns an AvroConfig with default values.
+func NewAvroConfig() AvroConfig {
+	return AvroConfig{
+		Parts:          []int{},
+		Operator:       "to_json",
+		SchemaPath:     "",
+		SchemaRegistry: schemaregistry.NewConfig(),
+		SchemaID:       0,
+		Cache:          "",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type avroOperator func(part []byte) ([]byte, error)
+
+// Avro is a processor that converts message parts between Avro binary and JSON.
+type Avro struct {
+	parts    []int
+	operator avroOperator
+
+	registry  *schemaregistry.Client
+	cache     types.Cache
+	codecs    map[int]*goavro.Codec
+	codecsMut sync.RWMutex
+
+	conf  Config
+	log   log.Modular
+	stats metrics.Type
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mErrSchema metrics.StatCounter
+	mErrCache  metrics.StatCounter
+	mSucc      metrics.StatCounter
+	mSent      metrics.StatCounter
+	mSentParts metrics.StatCounter
+}
+
+// NewAvro returns an Avro processor.
+func NewAvro(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	a := &Avro{
+		parts:  conf.Avro.Parts,
+		codecs: map[int]*goavro.Codec{},
+		conf:   conf,
+		log:    log.NewModule(".processor.avro"),
+		stats:  stats,
+
+		mCount:     stats.GetCounter("processor.avro.count"),
+		mErr:       stats.GetCounter("processor.avro.error"),
+		mErrSchema: stats.GetCounter("processor.avro.error.schema"),
+		mErrCache:  stats.GetCounter("processor.avro.error.cache"),
+		mSucc:      stats.GetCounter("processor.avro.success"),
+		mSent:      stats.GetCounter("processor.avro.sent"),
+		mSentParts: stats.GetCounter("processor.avro.parts.sent"),
+	}
+
+	var err error
+	if len(conf.Avro.Cache) > 0 {
+		if a.cache, err = mgr.GetCache(conf.Avro.Cache); err != nil {
+			return nil, fmt.Errorf("failed to obtain cache '%v': %v", conf.Avro.Cache, err)
+		}
+	}
+
+	var codec *goavro.Codec
+	if len(conf.Avro.SchemaPath) > 0 {
+		var schema []byte
+		if schema, err = ioutil.ReadFile(conf.Avro.SchemaPath); err != nil {
+			return nil, fmt.Errorf("failed to read schema file: %v", err)
+		}
+		if codec, err = goavro.NewCodec(string(schema)); err != nil {
+			return nil, fmt.Errorf("failed to parse schema: %v", err)
+		}
+	} else if len(conf.Avro.SchemaRegistry.URL) > 0 {
+		if a.registry, err = schemaregistry.New(conf.Avro.SchemaRegistry); err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, errors.New("either a schema_path or a schema_registry url must be specified")
+	}
+
+	switch conf.Avro.Operator {
+	case "to_json":
+		if codec != nil {
+			a.operator = avroToJSON(func(part []byte) (*goavro.Codec, []byte, error) {
+				return codec, part, nil
+			})
+		} else {
+			a.operator = avroToJSON(a.registryCodecFromHeader)
+		}
+	case "from_json":
+		if codec != nil {
+			a.operator = avroFromJSON(func() (*goavro.Codec, error) {
+				return codec, nil
+			}, nil)
+		} else {
+			if conf.Avro.SchemaID <= 0 {
+				return nil, errors.New("a schema_id must be specified in order to encode with a schema registry")
+			}
+			schemaID := conf.Avro.SchemaID
+			a.operator = avroFromJSON(func() (*goavro.Codec, error) {
+				return a.getRegistryCodec(schemaID)
+			}, func(b []byte) []byte {
+				return schemaregistry.EncodeHeader(schemaID, b)
+			})
+		}
+	default:
+		return nil, fmt.Errorf("operator not recognised: %v", conf.Avro.Operator)
+	}
+	return a, nil
+}
+
+//------------------------------------------------------------------------------
+
+func avroToJSON(getCodec func(part []byte) (*goavro.Codec, []byte, error)) avroOperator {
+	return func(part []byte) ([]byte, error) {
+		codec, payload, err := getCodec(part)
+		if err != nil {
+			return nil, err
+		}
+		native, _, err := codec.NativeFromBinary(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode avro: %v", err)
+		}
+		return codec.TextualFromNative(nil, native)
+	}
+}
+
+func avroFromJSON(getCodec func() (*goavro.Codec, error), frame func([]byte) []byte) avroOperator {
+	return func(part []byte) ([]byte, error) {
+		codec, err := getCodec()
+		if err != nil {
+			return nil, err
+		}
+		native, _, err := codec.NativeFromTextual(part)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse json as avro: %v", err)
+		}
+		b, err := codec.BinaryFromNative(nil, native)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode avro: %v", err)
+		}
+		if frame != nil {
+			b = frame(b)
+		}
+		return b, nil
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// registryCodecFromHeader reads the schema ID from the wire header of a part
+// and returns the codec of that schema along with the remaining payload.
+func (a *Avro) registryCodecFromHeader(part []byte) (*goavro.Codec, []byte, error) {
+	id, payload, err := schemaregistry.DecodeHeader(part)
+	if err != nil {
+		return nil, nil, err
+	}
+	codec, err := a.getRegistryCodec(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return codec, payload, nil
+}
+
+// getRegistryCodec returns the codec of a schema ID, attempting to obtain the
+// schema from memory, then the cache resource, and then the schema registry.
+func (a *Avro) getRegistryCodec(id int) (*goavro.Codec, error) {
+	a.codecsMut.RLock()
+	codec, exists := a.codecs[id]
+	a.codecsMut.RUnlock()
+	if exists {
+		return codec, nil
+	}
+
+	cacheKey := a.registry.SchemaURL(id)
+
+	var schema []byte
+	if a.cache != nil {
+		var err error
+		if schema, err = a.cache.Get(cacheKey); err != nil {
+			if err != types.ErrKeyNotFound {
+				a.mErrCache.Incr(1)
+				a.log.Debugf("Failed to read schema %v from cache: %v\n", id, err)
+			}
+			schema = nil
+		}
+	}
+
+	if schema == nil {
+		schemaStr, err := a.registry.GetSchemaByID(id)
+		if err != nil {
+			a.mErrSchema.Incr(1)
+			return nil, fmt.Errorf("failed to obtain schema %v from registry: %v", id, err)
+		}
+		schema = []byte(schemaStr)
+		if a.cache != nil {
+			if err = a.cache.Set(cacheKey, schema); err != nil {
+				a.mErrCache.Incr(1)
+				a.log.Debugf("Failed to write schema %v to cache: %v\n", id, err)
+			}
+		}
+	}
+
+	codec, err := goavro.NewCodec(string(schema))
+	if err != nil {
+		a.mErrSchema.Incr(1)
+		return nil, fmt.Errorf("failed to parse schema %v: %v", id, err)
+	}
+
+	a.codecsMut.Lock()
+	a.codecs[id] = codec
+	a.codecsMut.Unlock()
+	return codec, nil
+}
+
+//------------------------------------------------------------------------------
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (a *Avro) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	a.mCount.Incr(1)
+
+	newMsg := msg.ShallowCopy()
+
+	targetParts := a.parts
+	if len(targetParts) == 0 {
+		targetParts = make([]int, newMsg.Len())
+		for i := range targetParts {
+			targetParts[i] = i
+		}
+	}
+
+	for _, index := range targetParts {
+		newPart, err := a.operator(newMsg.Get(index))
+		if err != nil {
+			a.mErr.Incr(1)
+			a.log.Debugf("Failed to convert part: %v\n", err)
+			newMsg.SetError(index, err)
+			continue
+		}
+		a.mSucc.Incr(1)
+		newMsg.Set(index, newPart)
+	}
+
+	msgs := [1]types.Message{newMsg}
+
+	a.mSent.Incr(1)
+	a.mSentParts.Incr(int64(newMsg.Len()))
+	return msgs[:], nil
+}
+
+//------------------------------------------------------------------------------
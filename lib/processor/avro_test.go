@@ -0,0 +1,247 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"testing"
+
+	"github.com/linkedin/goavro"
+
+	"github.com/Jeffail/benthos/lib/cache"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/schemaregistry"
+)
+
+const testAvroSchema = `{
+  "type": "record",
+  "name": "user",
+  "fields": [
+    {"name": "name", "type": "string"},
+    {"name": "age", "type": "int"},
+    {"name": "email", "type": ["null", "string"], "default": null}
+  ]
+}`
+
+func testAvroBinary(t *testing.T, textual string) []byte {
+	t.Helper()
+	codec, err := goavro.NewCodec(testAvroSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	native, _, err := codec.NativeFromTextual([]byte(textual))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := codec.BinaryFromNative(nil, native)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func testAvroJSONEqual(t *testing.T, exp, act string) {
+	t.Helper()
+	var expV, actV interface{}
+	if err := json.Unmarshal([]byte(exp), &expV); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(act), &actV); err != nil {
+		t.Fatalf("Failed to parse result %v: %v", act, err)
+	}
+	if !reflect.DeepEqual(expV, actV) {
+		t.Errorf("Wrong decoded result: %v != %v", act, exp)
+	}
+}
+
+func TestAvroSchemaFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_avro_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	schemaPath := filepath.Join(dir, "schema.avsc")
+	if err = ioutil.WriteFile(schemaPath, []byte(testAvroSchema), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	conf := NewConfig()
+	conf.Avro.Operator = "from_json"
+	conf.Avro.SchemaPath = schemaPath
+
+	encoder, err := NewAvro(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf.Avro.Operator = "to_json"
+	decoder, err := NewAvro(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := `{"name":"foo","age":21,"email":{"string":"foo@example.com"}}`
+
+	msgs, res := encoder.ProcessMessage(message.New([][]byte{
+		[]byte(input),
+		[]byte(`{"name":"bar"}`),
+	}))
+	if len(msgs) != 1 {
+		t.Fatal("Wrong count of messages")
+	}
+	if res != nil {
+		t.Fatal("Non-nil result")
+	}
+	if exp, act := string(testAvroBinary(t, input)), string(msgs[0].Get(0)); exp != act {
+		t.Errorf("Wrong encoded result: %v != %v", act, exp)
+	}
+	if msgs[0].GetError(0) != nil {
+		t.Errorf("Unexpected error flag: %v", msgs[0].GetError(0))
+	}
+	if msgs[0].GetError(1) == nil {
+		t.Error("Expected error flag on part missing fields")
+	}
+
+	if msgs, _ = decoder.ProcessMessage(msgs[0]); len(msgs) != 1 {
+		t.Fatal("Wrong count of messages")
+	}
+	testAvroJSONEqual(t, input, string(msgs[0].Get(0)))
+}
+
+func TestAvroSchemaRegistry(t *testing.T) {
+	var reqCount uint32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddUint32(&reqCount, 1)
+		if r.URL.Path != "/schemas/ids/3" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"schema":"{\"type\":\"record\",\"name\":\"user\",\"fields\":[{\"name\":\"name\",\"type\":\"string\"},{\"name\":\"age\",\"type\":\"int\"},{\"name\":\"email\",\"type\":[\"null\",\"string\"],\"default\":null}]}"}`))
+	}))
+	defer ts.Close()
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr := &fakeMgr{
+		caches: map[string]types.Cache{
+			"foocache": memCache,
+		},
+	}
+
+	conf := NewConfig()
+	conf.Avro.Operator = "to_json"
+	conf.Avro.SchemaRegistry.URL = ts.URL
+	conf.Avro.Cache = "foocache"
+
+	decoder, err := NewAvro(conf, mgr, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := `{"name":"foo","age":21,"email":null}`
+	avroBytes := testAvroBinary(t, input)
+
+	msgs, res := decoder.ProcessMessage(message.New([][]byte{
+		schemaregistry.EncodeHeader(3, avroBytes),
+		schemaregistry.EncodeHeader(3, avroBytes),
+		schemaregistry.EncodeHeader(4, avroBytes),
+		avroBytes,
+	}))
+	if len(msgs) != 1 {
+		t.Fatal("Wrong count of messages")
+	}
+	if res != nil {
+		t.Fatal("Non-nil result")
+	}
+	for i := 0; i < 2; i++ {
+		testAvroJSONEqual(t, input, string(msgs[0].Get(i)))
+		if err = msgs[0].GetError(i); err != nil {
+			t.Errorf("Unexpected error flag: %v", err)
+		}
+	}
+	for i := 2; i < 4; i++ {
+		if msgs[0].GetError(i) == nil {
+			t.Errorf("Expected error flag on part %v", i)
+		}
+	}
+	if exp, act := uint32(2), atomic.LoadUint32(&reqCount); exp != act {
+		t.Errorf("Wrong count of registry requests: %v != %v", act, exp)
+	}
+
+	// A new processor sharing the cache should not need to hit the registry.
+	conf.Avro.Operator = "from_json"
+	conf.Avro.SchemaID = 3
+	encoder, err := NewAvro(conf, mgr, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msgs, _ = encoder.ProcessMessage(message.New([][]byte{[]byte(input)})); len(msgs) != 1 {
+		t.Fatal("Wrong count of messages")
+	}
+	if exp, act := string(schemaregistry.EncodeHeader(3, avroBytes)), string(msgs[0].Get(0)); exp != act {
+		t.Errorf("Wrong encoded result: %v != %v", act, exp)
+	}
+	if exp, act := uint32(2), atomic.LoadUint32(&reqCount); exp != act {
+		t.Errorf("Wrong count of registry requests: %v != %v", act, exp)
+	}
+}
+
+func TestAvroBadConfig(t *testing.T) {
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	conf := NewConfig()
+	if _, err := NewAvro(conf, nil, testLog, metrics.DudType{}); err == nil {
+		t.Error("Expected error from missing schema")
+	}
+
+	conf.Avro.SchemaRegistry.URL = "http://localhost:8081"
+	conf.Avro.Operator = "nope"
+	if _, err := NewAvro(conf, nil, testLog, metrics.DudType{}); err == nil {
+		t.Error("Expected error from bad operator")
+	}
+
+	conf.Avro.Operator = "from_json"
+	if _, err := NewAvro(conf, nil, testLog, metrics.DudType{}); err == nil {
+		t.Error("Expected error from missing schema_id")
+	}
+
+	conf.Avro.SchemaPath = "/does/not/exist.avsc"
+	if _, err := NewAvro(conf, nil, testLog, metrics.DudType{}); err == nil {
+		t.Error("Expected error from missing schema file")
+	}
+}
@@ -124,6 +124,7 @@ type Batch struct {
 	cond      condition.Type
 	sizeTally int
 	parts     [][]byte
+	partErrs  []error
 
 	lastBatch time.Time
 
@@ -172,9 +173,10 @@ func (c *Batch) ProcessMessage(msg types.Message) ([]types.Message, types.Respon
 	c.mCount.Incr(1)
 
 	// Add new parts to the buffer.
-	for _, part := range msg.GetAll() {
+	for i, part := range msg.GetAll() {
 		c.sizeTally += len(part)
 		c.parts = append(c.parts, part)
+		c.partErrs = append(c.partErrs, msg.GetError(i))
 	}
 
 	batch := false
@@ -197,12 +199,16 @@ func (c *Batch) ProcessMessage(msg types.Message) ([]types.Message, types.Respon
 	// If we have reached our target count of parts in the buffer.
 	if batch {
 		newMsg := message.New(c.parts)
+		for i, err := range c.partErrs {
+			newMsg.SetError(i, err)
+		}
 		msg.IterMetadata(func(k, v string) error {
 			newMsg.SetMetadata(k, v)
 			return nil
 		})
 
 		c.parts = nil
+		c.partErrs = nil
 		c.sizeTally = 0
 		c.lastBatch = time.Now()
 
@@ -0,0 +1,421 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/text"
+	"github.com/Jeffail/gabs"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeCache] = TypeSpec{
+		constructor: NewCache,
+		description: `
+Performs operations against a [cache resource](../caches) for each message part,
+allowing you to enrich messages with reference data or to populate a cache
+table for other streams to read from.
+
+The ` + "`key`" + ` field is required and supports
+[interpolation functions](../config_interpolation.md#functions), which are
+resolved individually for each message part. This allows you to derive the key
+from the contents or metadata of each part, e.g. ` + "`${!json_field:user.id}`" + `.
+
+### Operators
+
+#### ` + "`get`" + `
+
+Looks up the key within the cache and places the result at the dot path
+specified by ` + "`path`" + `. If the cached value is valid JSON it is set as a
+structured value, otherwise it is set as a string. When both the cached value
+and the existing value at the path are JSON objects they are merged, with the
+cached fields taking precedence. If ` + "`path`" + ` is empty the whole part is
+replaced with the cached value.
+
+When a key is not found the ` + "`on_miss`" + ` field determines what happens
+to the part:
+
+- ` + "`skip`" + `: the part is left unchanged.
+- ` + "`drop`" + `: the part is removed from the message. If no parts remain the
+  message is dropped entirely.
+- ` + "`error`" + `: the part is flagged as having failed processing, which can
+  be handled with processors such as ` + "[`catch`](#catch)" + `.
+- ` + "`default`" + `: the contents of the ` + "`default`" + ` field are used in
+  place of the missing value.
+
+#### ` + "`set`" + `
+
+Sets the key within the cache to the value of ` + "`value`" + `, which also
+supports interpolation functions. If ` + "`value`" + ` is empty the full
+contents of the part are stored instead. The message itself is not modified.
+
+#### ` + "`add`" + `
+
+Identical to ` + "`set`" + ` except that keys that already exist within the
+cache are not overwritten.
+
+### Example
+
+The following pair of streams populates a cache with user records from one
+source and then enriches events from another:
+
+` + "``` yaml" + `
+# Stream one, populating the table:
+cache:
+  cache: users
+  operator: set
+  key: ${!json_field:id}
+
+# Stream two, enriching events:
+cache:
+  cache: users
+  operator: get
+  key: ${!json_field:user_id}
+  path: user
+  on_miss: default
+  default: '{"name":"unknown"}'
+` + "```" + `
+
+Any errors returned by the cache other than a missing key result in the part
+being flagged as having failed processing.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// CacheConfig contains configuration fields for the Cache processor.
+type CacheConfig struct {
+	Cache    string `json:"cache" yaml:"cache"`
+	Operator string `json:"operator" yaml:"operator"`
+	Key      string `json:"key" yaml:"key"`
+	Value    string `json:"value" yaml:"value"`
+	Path     string `json:"path" yaml:"path"`
+	OnMiss   string `json:"on_miss" yaml:"on_miss"`
+	Default  string `json:"default" yaml:"default"`
+	Parts    []int  `json:"parts" yaml:"parts"`
+}
+
+// NewCacheConfig returns a CacheConfig with default values.
+func NewCacheConfig() CacheConfig {
+	return CacheConfig{
+		Cache:    "",
+		Operator: "get",
+		Key:      "",
+		Value:    "",
+		Path:     "",
+		OnMiss:   "skip",
+		Default:  "",
+		Parts:    []int{},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Cache is a processor that performs get, set or add operations against a cache
+// resource for each message part.
+type Cache struct {
+	conf  Config
+	log   log.Modular
+	stats metrics.Type
+
+	cache types.Cache
+	parts []int
+	path  []string
+
+	keyBytes         []byte
+	interpolateKey   bool
+	valueBytes       []byte
+	interpolateValue bool
+	defaultValue     interface{}
+
+	mCount       metrics.StatCounter
+	mHit         metrics.StatCounter
+	mMiss        metrics.StatCounter
+	mErrJSON     metrics.StatCounter
+	mErrCache    metrics.StatCounter
+	mErrMiss     metrics.StatCounter
+	mPartDropped metrics.StatCounter
+	mDropped     metrics.StatCounter
+	mSucc        metrics.StatCounter
+	mSent        metrics.StatCounter
+	mSentParts   metrics.StatCounter
+}
+
+// NewCache returns a Cache processor.
+func NewCache(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	switch conf.Cache.Operator {
+	case "get", "set", "add":
+	default:
+		return nil, fmt.Errorf("operator not recognised: %v", conf.Cache.Operator)
+	}
+	switch conf.Cache.OnMiss {
+	case "skip", "drop", "error", "default":
+	default:
+		return nil, fmt.Errorf("on_miss behaviour not recognised: %v", conf.Cache.OnMiss)
+	}
+	if len(conf.Cache.Key) == 0 {
+		return nil, errors.New("a key must be specified")
+	}
+
+	c, err := mgr.GetCache(conf.Cache.Cache)
+	if err != nil {
+		return nil, err
+	}
+
+	var path []string
+	if len(conf.Cache.Path) > 0 && conf.Cache.Path != "." {
+		path = strings.Split(conf.Cache.Path, ".")
+	}
+
+	keyBytes := []byte(conf.Cache.Key)
+	valueBytes := []byte(conf.Cache.Value)
+
+	return &Cache{
+		conf:  conf,
+		log:   log.NewModule(".processor.cache"),
+		stats: stats,
+
+		cache: c,
+		parts: conf.Cache.Parts,
+		path:  path,
+
+		keyBytes:         keyBytes,
+		interpolateKey:   text.ContainsFunctionVariables(keyBytes),
+		valueBytes:       valueBytes,
+		interpolateValue: text.ContainsFunctionVariables(valueBytes),
+		defaultValue:     parseCacheValue([]byte(conf.Cache.Default)),
+
+		mCount:       stats.GetCounter("processor.cache.count"),
+		mHit:         stats.GetCounter("processor.cache.hit"),
+		mMiss:        stats.GetCounter("processor.cache.miss"),
+		mErrJSON:     stats.GetCounter("processor.cache.error.json_parse"),
+		mErrCache:    stats.GetCounter("processor.cache.error.cache"),
+		mErrMiss:     stats.GetCounter("processor.cache.error.miss"),
+		mPartDropped: stats.GetCounter("processor.cache.parts.dropped"),
+		mDropped:     stats.GetCounter("processor.cache.dropped"),
+		mSucc:        stats.GetCounter("processor.cache.success"),
+		mSent:        stats.GetCounter("processor.cache.sent"),
+		mSentParts:   stats.GetCounter("processor.cache.parts.sent"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// parseCacheValue returns the structured form of a cached value if it is valid
+// JSON, otherwise it is returned as a string.
+func parseCacheValue(value []byte) interface{} {
+	var jObj interface{}
+	if err := json.Unmarshal(value, &jObj); err == nil {
+		return jObj
+	}
+	return string(value)
+}
+
+// mergeCacheValue places a value at a path within a JSON document. If both the
+// existing value and the new value are objects then their fields are merged.
+func mergeCacheValue(gPart *gabs.Container, path []string, value interface{}) {
+	if vObj, isObj := value.(map[string]interface{}); isObj {
+		if eObj, isObj := gPart.S(path...).Data().(map[string]interface{}); isObj {
+			merged := make(map[string]interface{}, len(eObj)+len(vObj))
+			for k, v := range eObj {
+				merged[k] = v
+			}
+			for k, v := range vObj {
+				merged[k] = v
+			}
+			value = merged
+		}
+	}
+	gPart.Set(value, path...)
+}
+
+//------------------------------------------------------------------------------
+
+// get looks up the value of a key and places it within a message part. Returns
+// false if the part should be dropped.
+func (c *Cache) get(msg types.Message, index int, key string) bool {
+	var value interface{}
+
+	valueBytes, err := c.cache.Get(key)
+	if err == nil {
+		c.mHit.Incr(1)
+		if len(c.path) == 0 {
+			msg.Set(index, valueBytes)
+			c.mSucc.Incr(1)
+			return true
+		}
+		value = parseCacheValue(valueBytes)
+	} else if err == types.ErrKeyNotFound {
+		c.mMiss.Incr(1)
+		switch c.conf.Cache.OnMiss {
+		case "skip":
+			return true
+		case "drop":
+			return false
+		case "error":
+			c.mErrMiss.Incr(1)
+			msg.SetError(index, err)
+			return true
+		}
+		if len(c.path) == 0 {
+			msg.Set(index, []byte(c.conf.Cache.Default))
+			c.mSucc.Incr(1)
+			return true
+		}
+		value = c.defaultValue
+	} else {
+		c.mErrCache.Incr(1)
+		c.log.Errorf("Cache error: %v\n", err)
+		msg.SetError(index, err)
+		return true
+	}
+
+	jObj, err := msg.GetJSON(index)
+	if err != nil {
+		c.mErrJSON.Incr(1)
+		c.log.Debugf("Failed to parse part into json: %v\n", err)
+		msg.SetError(index, err)
+		return true
+	}
+	gPart, err := gabs.Consume(jObj)
+	if err != nil {
+		c.mErrJSON.Incr(1)
+		c.log.Debugf("Failed to parse part into json: %v\n", err)
+		msg.SetError(index, err)
+		return true
+	}
+
+	mergeCacheValue(gPart, c.path, value)
+	if err = msg.SetJSON(index, gPart.Data()); err != nil {
+		c.mErrJSON.Incr(1)
+		c.log.Debugf("Failed to convert json into part: %v\n", err)
+		msg.SetError(index, err)
+		return true
+	}
+	c.mSucc.Incr(1)
+	return true
+}
+
+// set writes a message part, or an interpolated value, into the cache.
+func (c *Cache) set(msg types.Message, index int, key string) {
+	value := c.valueBytes
+	if len(value) == 0 {
+		value = msg.Get(index)
+	} else if c.interpolateValue {
+		value = text.ReplaceFunctionVariables(message.Lock(msg, index), value)
+	}
+
+	var err error
+	if c.conf.Cache.Operator == "add" {
+		if err = c.cache.Add(key, value); err == types.ErrKeyAlreadyExists {
+			err = nil
+		}
+	} else {
+		err = c.cache.Set(key, value)
+	}
+	if err != nil {
+		c.mErrCache.Incr(1)
+		c.log.Errorf("Cache error: %v\n", err)
+		msg.SetError(index, err)
+		return
+	}
+	c.mSucc.Incr(1)
+}
+
+//------------------------------------------------------------------------------
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (c *Cache) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	c.mCount.Incr(1)
+
+	newMsg := msg.ShallowCopy()
+
+	targetParts := c.parts
+	if len(targetParts) == 0 {
+		targetParts = make([]int, newMsg.Len())
+		for i := range targetParts {
+			targetParts[i] = i
+		}
+	}
+
+	dropParts := map[int]struct{}{}
+	for _, index := range targetParts {
+		if index < 0 {
+			index = newMsg.Len() + index
+		}
+		if index < 0 || index >= newMsg.Len() {
+			continue
+		}
+
+		key := c.keyBytes
+		if c.interpolateKey {
+			key = text.ReplaceFunctionVariables(message.Lock(newMsg, index), key)
+		}
+
+		if c.conf.Cache.Operator != "get" {
+			c.set(newMsg, index, string(key))
+		} else if !c.get(newMsg, index, string(key)) {
+			dropParts[index] = struct{}{}
+		}
+	}
+
+	if len(dropParts) > 0 {
+		filteredMsg := message.New(nil)
+		newMsg.IterMetadata(func(k, v string) error {
+			filteredMsg.SetMetadata(k, v)
+			return nil
+		})
+		for i := 0; i < newMsg.Len(); i++ {
+			if _, drop := dropParts[i]; drop {
+				c.mPartDropped.Incr(1)
+				continue
+			}
+			index := filteredMsg.Append(newMsg.Get(i))
+			filteredMsg.SetError(index, newMsg.GetError(i))
+		}
+		if filteredMsg.Len() == 0 {
+			c.mDropped.Incr(1)
+			return nil, response.NewAck()
+		}
+		newMsg = filteredMsg
+	}
+
+	c.mSent.Incr(1)
+	c.mSentParts.Incr(int64(newMsg.Len()))
+	msgs := [1]types.Message{newMsg}
+	return msgs[:], nil
+}
+
+//------------------------------------------------------------------------------
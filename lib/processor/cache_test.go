@@ -0,0 +1,332 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/cache"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+func TestCacheSetGet(t *testing.T) {
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr := &fakeMgr{
+		caches: map[string]types.Cache{
+			"users": memCache,
+		},
+	}
+
+	setConf := NewConfig()
+	setConf.Type = "cache"
+	setConf.Cache.Cache = "users"
+	setConf.Cache.Operator = "set"
+	setConf.Cache.Key = "${!json_field:id}"
+
+	setProc, err := New(setConf, mgr, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := [][]byte{
+		[]byte(`{"id":"1","name":"foo"}`),
+		[]byte(`{"id":"2","name":"bar"}`),
+	}
+	msgs, res := setProc.ProcessMessage(message.New(input))
+	if res != nil {
+		t.Fatalf("Unexpected response: %v", res)
+	}
+	if len(msgs) != 1 {
+		t.Fatalf("Wrong count of messages: %v", len(msgs))
+	}
+	if exp, act := input, msgs[0].GetAll(); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+
+	getConf := NewConfig()
+	getConf.Type = "cache"
+	getConf.Cache.Cache = "users"
+	getConf.Cache.Key = "${!json_field:user_id}"
+	getConf.Cache.Path = "user"
+
+	getProc, err := New(getConf, mgr, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, res = getProc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"user_id":"2","user":{"age":10}}`),
+		[]byte(`{"user_id":"3"}`),
+		[]byte(`{"user_id":"1"}`),
+	}))
+	if res != nil {
+		t.Fatalf("Unexpected response: %v", res)
+	}
+	if len(msgs) != 1 {
+		t.Fatalf("Wrong count of messages: %v", len(msgs))
+	}
+	exp := [][]byte{
+		[]byte(`{"user":{"age":10,"id":"2","name":"bar"},"user_id":"2"}`),
+		[]byte(`{"user_id":"3"}`),
+		[]byte(`{"user":{"id":"1","name":"foo"},"user_id":"1"}`),
+	}
+	if act := msgs[0].GetAll(); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+}
+
+func TestCacheAdd(t *testing.T) {
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr := &fakeMgr{
+		caches: map[string]types.Cache{
+			"foocache": memCache,
+		},
+	}
+
+	conf := NewConfig()
+	conf.Type = "cache"
+	conf.Cache.Cache = "foocache"
+	conf.Cache.Operator = "add"
+	conf.Cache.Key = "${!metadata:key}"
+	conf.Cache.Value = "${!json_field:value}"
+
+	proc, err := New(conf, mgr, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, v := range []string{"first", "second"} {
+		msg := message.New([][]byte{[]byte(`{"value":"` + v + `"}`)})
+		msg.SetMetadata("key", "foo")
+		msgs, res := proc.ProcessMessage(msg)
+		if res != nil {
+			t.Fatalf("Unexpected response: %v", res)
+		}
+		if err = msgs[0].GetError(0); err != nil {
+			t.Errorf("Unexpected part error: %v", err)
+		}
+	}
+
+	value, err := memCache.Get("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "first", string(value); exp != act {
+		t.Errorf("Wrong cached value: %v != %v", act, exp)
+	}
+}
+
+func TestCacheGetMiss(t *testing.T) {
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = memCache.Set("hit", []byte("raw value")); err != nil {
+		t.Fatal(err)
+	}
+	mgr := &fakeMgr{
+		caches: map[string]types.Cache{
+			"foocache": memCache,
+		},
+	}
+
+	input := [][]byte{
+		[]byte(`{"key":"miss"}`),
+		[]byte(`{"key":"hit"}`),
+	}
+
+	type testCase struct {
+		onMiss   string
+		path     string
+		exp      [][]byte
+		expFails []bool
+	}
+
+	tests := []testCase{
+		{
+			onMiss: "skip",
+			path:   "result",
+			exp: [][]byte{
+				[]byte(`{"key":"miss"}`),
+				[]byte(`{"key":"hit","result":"raw value"}`),
+			},
+			expFails: []bool{false, false},
+		},
+		{
+			onMiss: "drop",
+			path:   "result",
+			exp: [][]byte{
+				[]byte(`{"key":"hit","result":"raw value"}`),
+			},
+			expFails: []bool{false},
+		},
+		{
+			onMiss: "error",
+			path:   "result",
+			exp: [][]byte{
+				[]byte(`{"key":"miss"}`),
+				[]byte(`{"key":"hit","result":"raw value"}`),
+			},
+			expFails: []bool{true, false},
+		},
+		{
+			onMiss: "default",
+			path:   "result",
+			exp: [][]byte{
+				[]byte(`{"key":"miss","result":{"default":true}}`),
+				[]byte(`{"key":"hit","result":"raw value"}`),
+			},
+			expFails: []bool{false, false},
+		},
+		{
+			onMiss: "default",
+			path:   "",
+			exp: [][]byte{
+				[]byte(`{"default":true}`),
+				[]byte(`raw value`),
+			},
+			expFails: []bool{false, false},
+		},
+	}
+
+	for _, test := range tests {
+		conf := NewConfig()
+		conf.Type = "cache"
+		conf.Cache.Cache = "foocache"
+		conf.Cache.Key = "${!json_field:key}"
+		conf.Cache.Path = test.path
+		conf.Cache.OnMiss = test.onMiss
+		conf.Cache.Default = `{"default":true}`
+
+		proc, err := New(conf, mgr, testLog, metrics.DudType{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		msgs, res := proc.ProcessMessage(message.New(input))
+		if res != nil {
+			t.Fatalf("Unexpected response for '%v': %v", test.onMiss, res)
+		}
+		if len(msgs) != 1 {
+			t.Fatalf("Wrong count of messages for '%v': %v", test.onMiss, len(msgs))
+		}
+		if act := msgs[0].GetAll(); !reflect.DeepEqual(test.exp, act) {
+			t.Errorf("Wrong result for '%v': %s != %s", test.onMiss, act, test.exp)
+		}
+		for i, expFail := range test.expFails {
+			if act := msgs[0].GetError(i) != nil; act != expFail {
+				t.Errorf("Wrong fail flag for '%v' part %v: %v != %v", test.onMiss, i, act, expFail)
+			}
+		}
+	}
+}
+
+func TestCacheDropAll(t *testing.T) {
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr := &fakeMgr{
+		caches: map[string]types.Cache{
+			"foocache": memCache,
+		},
+	}
+
+	conf := NewConfig()
+	conf.Type = "cache"
+	conf.Cache.Cache = "foocache"
+	conf.Cache.Key = "${!json_field:key}"
+	conf.Cache.OnMiss = "drop"
+
+	proc, err := New(conf, mgr, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{[]byte(`{"key":"nope"}`)}))
+	if len(msgs) != 0 {
+		t.Errorf("Expected message to be dropped: %s", msgs[0].GetAll())
+	}
+	if res == nil || res.Error() != nil {
+		t.Errorf("Expected ack response: %v", res)
+	}
+}
+
+func TestCacheBadConfig(t *testing.T) {
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr := &fakeMgr{
+		caches: map[string]types.Cache{
+			"foocache": memCache,
+		},
+	}
+
+	conf := NewConfig()
+	conf.Type = "cache"
+	conf.Cache.Cache = "foocache"
+	conf.Cache.Key = "foo"
+
+	conf.Cache.Operator = "nope"
+	if _, err = New(conf, mgr, testLog, metrics.DudType{}); err == nil {
+		t.Error("Expected error from bad operator")
+	}
+	conf.Cache.Operator = "get"
+
+	conf.Cache.OnMiss = "nope"
+	if _, err = New(conf, mgr, testLog, metrics.DudType{}); err == nil {
+		t.Error("Expected error from bad on_miss")
+	}
+	conf.Cache.OnMiss = "skip"
+
+	conf.Cache.Key = ""
+	if _, err = New(conf, mgr, testLog, metrics.DudType{}); err == nil {
+		t.Error("Expected error from empty key")
+	}
+	conf.Cache.Key = "foo"
+
+	conf.Cache.Cache = "nope"
+	if _, err = New(conf, mgr, testLog, metrics.DudType{}); err == nil {
+		t.Error("Expected error from missing cache")
+	}
+}
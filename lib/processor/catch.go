@@ -0,0 +1,212 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeCatch] = TypeSpec{
+		constructor: NewCatch,
+		description: `
+Applies a list of child processors only to message parts that have been flagged
+as having failed a previous processing step. Parts that have not failed are left
+unchanged and are not seen by the child processors.
+
+Processors such as ` + "`json`" + ` and ` + "`grok`" + ` flag individual parts
+of a batch when they fail rather than dropping or failing the entire batch.
+Placing a catch processor after them allows you to handle those parts, for
+example by marking their contents:
+
+` + "``` yaml" + `
+- type: grok
+  grok:
+    patterns:
+    - "%{WORD:first},%{INT:second:int}"
+- type: catch
+  catch:
+    processors:
+    - type: text
+      text:
+        operator: prepend
+        value: "failed to parse: "
+` + "```" + `
+
+The failed parts are extracted into a new message, with their failure flags
+cleared, and sent through the child processors. The results are then mapped
+back into the original batch along with their flags, so that parts which fail
+again within the child processors remain flagged. If the number of parts resulting from the child
+processors does not match the number of failed parts then the results are
+discarded and the parts continue unchanged, still flagged. Therefore, you should
+avoid using batch and filter type processors in this list.`,
+		sanitiseConfigFunc: func(conf Config) (interface{}, error) {
+			var err error
+			procConfs := make([]interface{}, len(conf.Catch.Processors))
+			for i, pConf := range conf.Catch.Processors {
+				if procConfs[i], err = SanitiseConfig(pConf); err != nil {
+					return nil, err
+				}
+			}
+			return map[string]interface{}{
+				"processors": procConfs,
+			}, nil
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// CatchConfig is a config struct containing fields for the Catch processor.
+type CatchConfig struct {
+	Processors []Config `json:"processors" yaml:"processors"`
+}
+
+// NewCatchConfig returns a default CatchConfig.
+func NewCatchConfig() CatchConfig {
+	return CatchConfig{
+		Processors: []Config{},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Catch is a processor that applies a list of child processors to message
+// parts that have been flagged as failed.
+type Catch struct {
+	children []Type
+
+	log log.Modular
+
+	mCount         metrics.StatCounter
+	mSkipped       metrics.StatCounter
+	mCaughtParts   metrics.StatCounter
+	mErr           metrics.StatCounter
+	mErrMisaligned metrics.StatCounter
+	mSent          metrics.StatCounter
+	mSentParts     metrics.StatCounter
+}
+
+// NewCatch returns a Catch processor.
+func NewCatch(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	nsStats := metrics.Namespaced(stats, "processor.catch")
+	nsLog := log.NewModule(".processor.catch")
+
+	var children []Type
+	for _, pconf := range conf.Catch.Processors {
+		proc, err := New(pconf, mgr, nsLog, nsStats)
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, proc)
+	}
+	return &Catch{
+		children: children,
+
+		log: nsLog,
+
+		mCount:         stats.GetCounter("processor.catch.count"),
+		mSkipped:       stats.GetCounter("processor.catch.skipped"),
+		mCaughtParts:   stats.GetCounter("processor.catch.parts.caught"),
+		mErr:           stats.GetCounter("processor.catch.error"),
+		mErrMisaligned: stats.GetCounter("processor.catch.error.misaligned"),
+		mSent:          stats.GetCounter("processor.catch.sent"),
+		mSentParts:     stats.GetCounter("processor.catch.parts.sent"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (c *Catch) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	c.mCount.Incr(1)
+
+	payload := msg.ShallowCopy()
+	resMsgs := [1]types.Message{payload}
+
+	var failedParts []int
+	for i := 0; i < payload.Len(); i++ {
+		if payload.GetError(i) != nil {
+			failedParts = append(failedParts, i)
+		}
+	}
+
+	c.mSent.Incr(1)
+	c.mSentParts.Incr(int64(payload.Len()))
+
+	if len(failedParts) == 0 {
+		c.mSkipped.Incr(1)
+		return resMsgs[:], nil
+	}
+	c.mCaughtParts.Incr(int64(len(failedParts)))
+
+	reqMsg := message.New(make([][]byte, len(failedParts)))
+	payload.IterMetadata(func(k, v string) error {
+		reqMsg.SetMetadata(k, v)
+		return nil
+	})
+	for i, index := range failedParts {
+		reqMsg.Set(i, payload.Get(index))
+	}
+
+	resultMsgs := []types.Message{reqMsg}
+	for i := 0; len(resultMsgs) > 0 && i < len(c.children); i++ {
+		var nextResultMsgs []types.Message
+		for _, m := range resultMsgs {
+			var rMsgs []types.Message
+			rMsgs, _ = c.children[i].ProcessMessage(m)
+			nextResultMsgs = append(nextResultMsgs, rMsgs...)
+		}
+		resultMsgs = nextResultMsgs
+	}
+
+	var resParts [][]byte
+	var resErrs []error
+	for _, rMsg := range resultMsgs {
+		resParts = append(resParts, rMsg.GetAll()...)
+		for i := 0; i < rMsg.Len(); i++ {
+			resErrs = append(resErrs, rMsg.GetError(i))
+		}
+	}
+
+	if exp, act := len(failedParts), len(resParts); exp != act {
+		c.mErr.Incr(1)
+		c.mErrMisaligned.Incr(1)
+		c.log.Errorf("Misaligned processor result batch. Expected %v messages, received %v\n", exp, act)
+		return resMsgs[:], nil
+	}
+
+	for i, index := range failedParts {
+		payload.Set(index, resParts[i])
+		payload.SetError(index, resErrs[i])
+	}
+	return resMsgs[:], nil
+}
+
+//------------------------------------------------------------------------------
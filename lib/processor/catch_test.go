@@ -0,0 +1,254 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+func TestCatchNoFailures(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = "catch"
+
+	procConf := NewConfig()
+	procConf.Type = "text"
+	procConf.Text.Operator = "prepend"
+	procConf.Text.Value = "failed: "
+
+	conf.Catch.Processors = append(conf.Catch.Processors, procConf)
+
+	c, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := [][]byte{
+		[]byte(`foo`),
+		[]byte(`bar`),
+	}
+
+	msg, res := c.ProcessMessage(message.New([][]byte{
+		[]byte(`foo`),
+		[]byte(`bar`),
+	}))
+	if res != nil {
+		t.Error(res.Error())
+	}
+	if act := msg[0].GetAll(); !reflect.DeepEqual(act, exp) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+}
+
+func TestCatchFailedParts(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = "catch"
+
+	procConf := NewConfig()
+	procConf.Type = "text"
+	procConf.Text.Operator = "prepend"
+	procConf.Text.Value = "failed: "
+
+	conf.Catch.Processors = append(conf.Catch.Processors, procConf)
+
+	c, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := [][]byte{
+		[]byte(`foo`),
+		[]byte(`failed: bar`),
+		[]byte(`baz`),
+		[]byte(`failed: qux`),
+	}
+
+	input := message.New([][]byte{
+		[]byte(`foo`),
+		[]byte(`bar`),
+		[]byte(`baz`),
+		[]byte(`qux`),
+	})
+	input.SetMetadata("foo", "bar")
+	input.SetError(1, errors.New("test err"))
+	input.SetError(3, errors.New("test err"))
+
+	msg, res := c.ProcessMessage(input)
+	if res != nil {
+		t.Error(res.Error())
+	}
+	if act := msg[0].GetAll(); !reflect.DeepEqual(act, exp) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+	for i := 0; i < msg[0].Len(); i++ {
+		if err := msg[0].GetError(i); err != nil {
+			t.Errorf("Part %v still flagged: %v", i, err)
+		}
+	}
+	if exp, act := "bar", msg[0].GetMetadata("foo"); exp != act {
+		t.Errorf("Wrong metadata: %v != %v", act, exp)
+	}
+	if input.GetError(1) == nil {
+		t.Error("Original message was modified")
+	}
+}
+
+func TestCatchMisaligned(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = "catch"
+
+	procConf := NewConfig()
+	procConf.Type = "select_parts"
+	procConf.SelectParts.Parts = []int{0}
+
+	conf.Catch.Processors = append(conf.Catch.Processors, procConf)
+
+	c, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := [][]byte{
+		[]byte(`foo`),
+		[]byte(`bar`),
+		[]byte(`baz`),
+	}
+
+	input := message.New([][]byte{
+		[]byte(`foo`),
+		[]byte(`bar`),
+		[]byte(`baz`),
+	})
+	input.SetError(1, errors.New("test err"))
+	input.SetError(2, errors.New("test err"))
+
+	msg, res := c.ProcessMessage(input)
+	if res != nil {
+		t.Error(res.Error())
+	}
+	if act := msg[0].GetAll(); !reflect.DeepEqual(act, exp) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+	if msg[0].GetError(1) == nil || msg[0].GetError(2) == nil {
+		t.Error("Expected parts to remain flagged")
+	}
+}
+
+func TestCatchAfterJSON(t *testing.T) {
+	jConf := NewConfig()
+	jConf.Type = "json"
+	jConf.JSON.Operator = "select"
+	jConf.JSON.Path = "foo"
+
+	jProc, err := New(jConf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf := NewConfig()
+	conf.Type = "catch"
+
+	procConf := NewConfig()
+	procConf.Type = "text"
+	procConf.Text.Operator = "prepend"
+	procConf.Text.Value = "failed: "
+
+	conf.Catch.Processors = append(conf.Catch.Processors, procConf)
+
+	c, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, res := jProc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"foo":"bar"}`),
+		[]byte(`not json`),
+	}))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if msgs[0].GetError(0) != nil {
+		t.Errorf("Unexpected error flag: %v", msgs[0].GetError(0))
+	}
+	if msgs[0].GetError(1) == nil {
+		t.Error("Expected error flag on invalid JSON part")
+	}
+
+	if msgs, res = c.ProcessMessage(msgs[0]); res != nil {
+		t.Fatal(res.Error())
+	}
+
+	exp := [][]byte{
+		[]byte(`bar`),
+		[]byte(`failed: not json`),
+	}
+	if act := msgs[0].GetAll(); !reflect.DeepEqual(act, exp) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+}
+
+func TestCatchFailsAgain(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = "catch"
+
+	procConf := NewConfig()
+	procConf.Type = "json"
+	procConf.JSON.Operator = "select"
+	procConf.JSON.Path = "foo"
+
+	conf.Catch.Processors = append(conf.Catch.Processors, procConf)
+
+	c, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := message.New([][]byte{
+		[]byte(`{"foo":"bar"}`),
+		[]byte(`not json`),
+	})
+	input.SetError(0, errors.New("test err"))
+	input.SetError(1, errors.New("test err"))
+
+	msgs, res := c.ProcessMessage(input)
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+
+	exp := [][]byte{
+		[]byte(`bar`),
+		[]byte(`not json`),
+	}
+	if act := msgs[0].GetAll(); !reflect.DeepEqual(act, exp) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+	if err := msgs[0].GetError(0); err != nil {
+		t.Errorf("Unexpected error flag: %v", err)
+	}
+	if msgs[0].GetError(1) == nil {
+		t.Error("Expected part that failed within catch to remain flagged")
+	}
+}
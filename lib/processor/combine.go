@@ -85,10 +85,11 @@ func NewCombineConfig() CombineConfig {
 // the pipeline as a single message and an acknowledgement for that message
 // determines whether the whole batch of messages are acknowledged.
 type Combine struct {
-	log   log.Modular
-	stats metrics.Type
-	n     int
-	parts [][]byte
+	log      log.Modular
+	stats    metrics.Type
+	n        int
+	parts    [][]byte
+	partErrs []error
 
 	mCount     metrics.StatCounter
 	mWarnParts metrics.StatCounter
@@ -130,19 +131,24 @@ func (c *Combine) ProcessMessage(msg types.Message) ([]types.Message, types.Resp
 	}
 
 	// Add new parts to the buffer.
-	for _, part := range msg.GetAll() {
+	for i, part := range msg.GetAll() {
 		c.parts = append(c.parts, part)
+		c.partErrs = append(c.partErrs, msg.GetError(i))
 	}
 
 	// If we have reached our target count of parts in the buffer.
 	if len(c.parts) >= c.n {
 		newMsg := message.New(c.parts)
+		for i, err := range c.partErrs {
+			newMsg.SetError(i, err)
+		}
 		msg.IterMetadata(func(k, v string) error {
 			newMsg.SetMetadata(k, v)
 			return nil
 		})
 
 		c.parts = nil
+		c.partErrs = nil
 
 		c.mSent.Incr(1)
 		c.mSentParts.Incr(int64(newMsg.Len()))
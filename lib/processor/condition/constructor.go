@@ -48,6 +48,11 @@ type TypeSpec struct {
 	sanitiseConfigFunc func(conf Config) (interface{}, error)
 }
 
+// Description returns the documentation of the condition type.
+func (t TypeSpec) Description() string {
+	return t.description
+}
+
 // Constructors is a map of all condition types with their specs.
 var Constructors = map[string]TypeSpec{}
 
@@ -55,46 +60,49 @@ var Constructors = map[string]TypeSpec{}
 
 // String constants representing each condition type.
 var (
-	TypeAnd      = "and"
-	TypeCount    = "count"
-	TypeJMESPath = "jmespath"
-	TypeNot      = "not"
-	TypeOr       = "or"
-	TypeResource = "resource"
-	TypeStatic   = "static"
-	TypeText     = "text"
-	TypeXor      = "xor"
+	TypeAnd             = "and"
+	TypeCount           = "count"
+	TypeJMESPath        = "jmespath"
+	TypeNot             = "not"
+	TypeOr              = "or"
+	TypeProcessorFailed = "processor_failed"
+	TypeResource        = "resource"
+	TypeStatic          = "static"
+	TypeText            = "text"
+	TypeXor             = "xor"
 )
 
 //------------------------------------------------------------------------------
 
 // Config is the all encompassing configuration struct for all condition types.
 type Config struct {
-	Type     string         `json:"type" yaml:"type"`
-	And      AndConfig      `json:"and" yaml:"and"`
-	Count    CountConfig    `json:"count" yaml:"count"`
-	JMESPath JMESPathConfig `json:"jmespath" yaml:"jmespath"`
-	Not      NotConfig      `json:"not" yaml:"not"`
-	Or       OrConfig       `json:"or" yaml:"or"`
-	Resource string         `json:"resource" yaml:"resource"`
-	Static   bool           `json:"static" yaml:"static"`
-	Text     TextConfig     `json:"text" yaml:"text"`
-	Xor      XorConfig      `json:"xor" yaml:"xor"`
+	Type            string                `json:"type" yaml:"type"`
+	And             AndConfig             `json:"and" yaml:"and"`
+	Count           CountConfig           `json:"count" yaml:"count"`
+	JMESPath        JMESPathConfig        `json:"jmespath" yaml:"jmespath"`
+	Not             NotConfig             `json:"not" yaml:"not"`
+	Or              OrConfig              `json:"or" yaml:"or"`
+	ProcessorFailed ProcessorFailedConfig `json:"processor_failed" yaml:"processor_failed"`
+	Resource        string                `json:"resource" yaml:"resource"`
+	Static          bool                  `json:"static" yaml:"static"`
+	Text            TextConfig            `json:"text" yaml:"text"`
+	Xor             XorConfig             `json:"xor" yaml:"xor"`
 }
 
 // NewConfig returns a configuration struct fully populated with default values.
 func NewConfig() Config {
 	return Config{
-		Type:     "text",
-		And:      NewAndConfig(),
-		Count:    NewCountConfig(),
-		JMESPath: NewJMESPathConfig(),
-		Not:      NewNotConfig(),
-		Or:       NewOrConfig(),
-		Resource: "",
-		Static:   true,
-		Text:     NewTextConfig(),
-		Xor:      NewXorConfig(),
+		Type:            "text",
+		And:             NewAndConfig(),
+		Count:           NewCountConfig(),
+		JMESPath:        NewJMESPathConfig(),
+		Not:             NewNotConfig(),
+		Or:              NewOrConfig(),
+		ProcessorFailed: NewProcessorFailedConfig(),
+		Resource:        "",
+		Static:          true,
+		Text:            NewTextConfig(),
+		Xor:             NewXorConfig(),
 	}
 }
 
@@ -0,0 +1,121 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package condition
+
+import (
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeProcessorFailed] = TypeSpec{
+		constructor: NewProcessorFailed,
+		description: `
+Returns true if a message part has been flagged as having failed a processing
+step. Processors such as ` + "`json`" + ` and ` + "`grok`" + ` flag individual
+parts of a batch when they fail rather than dropping or failing the whole batch,
+and this condition can be used to route or filter those parts.
+
+A negative part index counts backwards from the last part of a message, where
+-1 is the last part. If the part does not exist the condition returns false.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// ProcessorFailedConfig is a configuration struct containing fields for the
+// processor_failed condition.
+type ProcessorFailedConfig struct {
+	Part int `json:"part" yaml:"part"`
+}
+
+// NewProcessorFailedConfig returns a ProcessorFailedConfig with default values.
+func NewProcessorFailedConfig() ProcessorFailedConfig {
+	return ProcessorFailedConfig{
+		Part: 0,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// ProcessorFailed is a condition that checks whether a message part has been
+// flagged with a processing error.
+type ProcessorFailed struct {
+	part int
+
+	mSkippedEmpty metrics.StatCounter
+	mSkipped      metrics.StatCounter
+	mSkippedOOB   metrics.StatCounter
+	mApplied      metrics.StatCounter
+	mTrue         metrics.StatCounter
+	mFalse        metrics.StatCounter
+}
+
+// NewProcessorFailed returns a ProcessorFailed condition.
+func NewProcessorFailed(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	return &ProcessorFailed{
+		part: conf.ProcessorFailed.Part,
+
+		mSkippedEmpty: stats.GetCounter("condition.processor_failed.skipped.empty_message"),
+		mSkipped:      stats.GetCounter("condition.processor_failed.skipped"),
+		mSkippedOOB:   stats.GetCounter("condition.processor_failed.skipped.out_of_bounds"),
+		mApplied:      stats.GetCounter("condition.processor_failed.applied"),
+		mTrue:         stats.GetCounter("condition.processor_failed.true"),
+		mFalse:        stats.GetCounter("condition.processor_failed.false"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Check attempts to check a message part against a configured condition.
+func (c *ProcessorFailed) Check(msg types.Message) bool {
+	lParts := msg.Len()
+	if lParts == 0 {
+		c.mSkippedEmpty.Incr(1)
+		c.mSkipped.Incr(1)
+		return false
+	}
+
+	index := c.part
+	if index < 0 {
+		index = lParts + index
+	}
+	if index < 0 || index >= lParts {
+		c.mSkippedOOB.Incr(1)
+		c.mSkipped.Incr(1)
+		return false
+	}
+
+	c.mApplied.Incr(1)
+	if msg.GetError(index) != nil {
+		c.mTrue.Incr(1)
+		return true
+	}
+	c.mFalse.Incr(1)
+	return false
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,113 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package condition
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+func TestProcessorFailedCheck(t *testing.T) {
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+	testMet := metrics.DudType{}
+
+	tests := []struct {
+		name   string
+		part   int
+		failed []int
+		want   bool
+	}{
+		{
+			name:   "no failures",
+			part:   0,
+			failed: nil,
+			want:   false,
+		},
+		{
+			name:   "first part failed",
+			part:   0,
+			failed: []int{0},
+			want:   true,
+		},
+		{
+			name:   "other part failed",
+			part:   0,
+			failed: []int{1},
+			want:   false,
+		},
+		{
+			name:   "last part failed",
+			part:   -1,
+			failed: []int{2},
+			want:   true,
+		},
+		{
+			name:   "out of bounds",
+			part:   5,
+			failed: []int{0, 1, 2},
+			want:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		conf := NewConfig()
+		conf.Type = "processor_failed"
+		conf.ProcessorFailed.Part = tt.part
+
+		c, err := New(conf, nil, testLog, testMet)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		msg := message.New([][]byte{
+			[]byte("foo"),
+			[]byte("bar"),
+			[]byte("baz"),
+		})
+		for _, i := range tt.failed {
+			msg.SetError(i, errors.New("test err"))
+		}
+		if got := c.Check(msg); got != tt.want {
+			t.Errorf("ProcessorFailed.Check() test '%v' = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestProcessorFailedEmpty(t *testing.T) {
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+	testMet := metrics.DudType{}
+
+	conf := NewConfig()
+	conf.Type = "processor_failed"
+
+	c, err := New(conf, nil, testLog, testMet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Check(message.New(nil)) {
+		t.Error("Expected false result on empty message")
+	}
+}
@@ -48,6 +48,11 @@ type TypeSpec struct {
 	sanitiseConfigFunc func(conf Config) (interface{}, error)
 }
 
+// Description returns the documentation of the processor type.
+func (t TypeSpec) Description() string {
+	return t.description
+}
+
 // Constructors is a map of all processor types with their specs.
 var Constructors = map[string]TypeSpec{}
 
@@ -56,11 +61,15 @@ var Constructors = map[string]TypeSpec{}
 // String constants representing each processor type.
 const (
 	TypeArchive      = "archive"
+	TypeAvro         = "avro"
 	TypeBatch        = "batch"
 	TypeBoundsCheck  = "bounds_check"
+	TypeCache        = "cache"
+	TypeCatch        = "catch"
 	TypeCombine      = "combine"
 	TypeCompress     = "compress"
 	TypeConditional  = "conditional"
+	TypeCSV          = "csv"
 	TypeDecode       = "decode"
 	TypeDecompress   = "decompress"
 	TypeDedupe       = "dedupe"
@@ -73,16 +82,19 @@ const (
 	TypeInsertPart   = "insert_part"
 	TypeJMESPath     = "jmespath"
 	TypeJSON         = "json"
+	TypeMapping      = "mapping"
 	TypeMergeJSON    = "merge_json"
 	TypeMetadata     = "metadata"
 	TypeNoop         = "noop"
 	TypeProcessField = "process_field"
 	TypeProcessMap   = "process_map"
+	TypeProtobuf     = "protobuf"
 	TypeSample       = "sample"
 	TypeSelectParts  = "select_parts"
 	TypeSplit        = "split"
 	TypeText         = "text"
 	TypeUnarchive    = "unarchive"
+	TypeWindow       = "window"
 )
 
 //------------------------------------------------------------------------------
@@ -91,11 +103,15 @@ const (
 type Config struct {
 	Type         string             `json:"type" yaml:"type"`
 	Archive      ArchiveConfig      `json:"archive" yaml:"archive"`
+	Avro         AvroConfig         `json:"avro" yaml:"avro"`
 	Batch        BatchConfig        `json:"batch" yaml:"batch"`
 	BoundsCheck  BoundsCheckConfig  `json:"bounds_check" yaml:"bounds_check"`
+	Cache        CacheConfig        `json:"cache" yaml:"cache"`
+	Catch        CatchConfig        `json:"catch" yaml:"catch"`
 	Combine      CombineConfig      `json:"combine" yaml:"combine"`
 	Compress     CompressConfig     `json:"compress" yaml:"compress"`
 	Conditional  ConditionalConfig  `json:"conditional" yaml:"conditional"`
+	CSV          CSVConfig          `json:"csv" yaml:"csv"`
 	Decode       DecodeConfig       `json:"decode" yaml:"decode"`
 	Decompress   DecompressConfig   `json:"decompress" yaml:"decompress"`
 	Dedupe       DedupeConfig       `json:"dedupe" yaml:"dedupe"`
@@ -108,15 +124,18 @@ type Config struct {
 	InsertPart   InsertPartConfig   `json:"insert_part" yaml:"insert_part"`
 	JMESPath     JMESPathConfig     `json:"jmespath" yaml:"jmespath"`
 	JSON         JSONConfig         `json:"json" yaml:"json"`
+	Mapping      MappingConfig      `json:"mapping" yaml:"mapping"`
 	MergeJSON    MergeJSONConfig    `json:"merge_json" yaml:"merge_json"`
 	Metadata     MetadataConfig     `json:"metadata" yaml:"metadata"`
 	ProcessField ProcessFieldConfig `json:"process_field" yaml:"process_field"`
 	ProcessMap   ProcessMapConfig   `json:"process_map" yaml:"process_map"`
+	Protobuf     ProtobufConfig     `json:"protobuf" yaml:"protobuf"`
 	Sample       SampleConfig       `json:"sample" yaml:"sample"`
 	SelectParts  SelectPartsConfig  `json:"select_parts" yaml:"select_parts"`
 	Split        struct{}           `json:"split" yaml:"split"`
 	Text         TextConfig         `json:"text" yaml:"text"`
 	Unarchive    UnarchiveConfig    `json:"unarchive" yaml:"unarchive"`
+	Window       WindowConfig       `json:"window" yaml:"window"`
 }
 
 // NewConfig returns a configuration struct fully populated with default values.
@@ -124,11 +143,15 @@ func NewConfig() Config {
 	return Config{
 		Type:         "bounds_check",
 		Archive:      NewArchiveConfig(),
+		Avro:         NewAvroConfig(),
 		Batch:        NewBatchConfig(),
 		BoundsCheck:  NewBoundsCheckConfig(),
+		Cache:        NewCacheConfig(),
+		Catch:        NewCatchConfig(),
 		Combine:      NewCombineConfig(),
 		Compress:     NewCompressConfig(),
 		Conditional:  NewConditionalConfig(),
+		CSV:          NewCSVConfig(),
 		Decode:       NewDecodeConfig(),
 		Decompress:   NewDecompressConfig(),
 		Dedupe:       NewDedupeConfig(),
@@ -141,15 +164,18 @@ func NewConfig() Config {
 		InsertPart:   NewInsertPartConfig(),
 		JMESPath:     NewJMESPathConfig(),
 		JSON:         NewJSONConfig(),
+		Mapping:      NewMappingConfig(),
 		MergeJSON:    NewMergeJSONConfig(),
 		Metadata:     NewMetadataConfig(),
 		ProcessField: NewProcessFieldConfig(),
 		ProcessMap:   NewProcessMapConfig(),
+		Protobuf:     NewProtobufConfig(),
 		Sample:       NewSampleConfig(),
 		SelectParts:  NewSelectPartsConfig(),
 		Split:        struct{}{},
 		Text:         NewTextConfig(),
 		Unarchive:    NewUnarchiveConfig(),
+		Window:       NewWindowConfig(),
 	}
 }
 
@@ -239,7 +265,17 @@ parts is empty these processors will be applied to all message parts.
 Part indexes can be negative, and if so the part will be selected from the end
 counting backwards starting from -1. E.g. if part = -1 then the selected part
 will be the last part of the message, if part = -2 then the part before the last
-element with be selected, and so on.`
+element with be selected, and so on.
+
+### Error Handling
+
+Some processors, such as [json](#json), [jmespath](#jmespath) and
+[grok](#grok), are able to fail on individual parts of a batch. When this
+happens the part continues through the pipeline unchanged but is flagged as
+having failed. Flagged parts can be detected with the
+[` + "`processor_failed`" + ` condition](../conditions/README.md#processor_failed)
+and handled with the [` + "`catch`" + `](#catch) processor, without losing the
+parts of the batch that succeeded.`
 
 var footer = `
 [0]: ./examples.md`
@@ -0,0 +1,220 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/csv"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeCSV] = TypeSpec{
+		constructor: NewCSV,
+		description: `
+Parses message parts as [RFC 4180](https://tools.ietf.org/html/rfc4180) CSV
+documents, replacing each part with one part per row, where each row is
+converted into a JSON document.
+
+The ` + "`delimiter`" + ` field sets the character separating fields, and
+` + "`lazy_quotes`" + ` allows quotes to appear within unquoted fields and
+non-doubled quotes within quoted fields.
+
+When ` + "`header_row`" + ` is true the first row of each part is used as the
+keys for the rows that follow, and each row is converted into a JSON object.
+Alternatively, the keys can be set explicitly with ` + "`columns`" + `, which
+is useful when parts contain single rows without a header, in which case
+` + "`header_row`" + ` should be set to false. If ` + "`columns`" + ` is set and
+` + "`header_row`" + ` is true then the header row is discarded. Fields beyond
+the known columns are keyed by their column index. When there are no column
+names each row is converted into a JSON array.
+
+Rows that fail to parse are flagged as having failed processing, and can be
+handled with the [` + "`catch`" + `](#catch) processor. Parts that contain no
+rows are removed, and if the message results in zero parts it is skipped
+entirely.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// CSVConfig contains configuration fields for the CSV processor.
+type CSVConfig struct {
+	Parts      []int    `json:"parts" yaml:"parts"`
+	Delim      string   `json:"delimiter" yaml:"delimiter"`
+	LazyQuotes bool     `json:"lazy_quotes" yaml:"lazy_quotes"`
+	HeaderRow  bool     `json:"header_row" yaml:"header_row"`
+	Columns    []string `json:"columns" yaml:"columns"`
+}
+
+// NewCSVConfig returns a CSVConfig with default values.
+func NewCSVConfig() CSVConfig {
+	return CSVConfig{
+		Parts:      []int{},
+		Delim:      ",",
+		LazyQuotes: false,
+		HeaderRow:  true,
+		Columns:    []string{},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// CSV is a processor that parses CSV message parts into JSON rows.
+type CSV struct {
+	conf  Config
+	log   log.Modular
+	stats metrics.Type
+
+	delim rune
+
+	mCount     metrics.StatCounter
+	mRows      metrics.StatCounter
+	mErrParse  metrics.StatCounter
+	mErr       metrics.StatCounter
+	mSucc      metrics.StatCounter
+	mSkipped   metrics.StatCounter
+	mDropped   metrics.StatCounter
+	mSent      metrics.StatCounter
+	mSentParts metrics.StatCounter
+}
+
+// NewCSV returns a CSV processor.
+func NewCSV(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	delim, err := csv.ParseDelimiter(conf.CSV.Delim)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CSV{
+		conf:  conf,
+		log:   log.NewModule(".processor.csv"),
+		stats: stats,
+
+		delim: delim,
+
+		mCount:     stats.GetCounter("processor.csv.count"),
+		mRows:      stats.GetCounter("processor.csv.rows"),
+		mErrParse:  stats.GetCounter("processor.csv.error.parse"),
+		mErr:       stats.GetCounter("processor.csv.error"),
+		mSucc:      stats.GetCounter("processor.csv.success"),
+		mSkipped:   stats.GetCounter("processor.csv.skipped"),
+		mDropped:   stats.GetCounter("processor.csv.dropped"),
+		mSent:      stats.GetCounter("processor.csv.sent"),
+		mSentParts: stats.GetCounter("processor.csv.parts.sent"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (c *CSV) parse(part []byte) ([]csv.Row, error) {
+	r, err := csv.NewReader(
+		bytes.NewReader(part), c.delim,
+		c.conf.CSV.LazyQuotes, c.conf.CSV.HeaderRow, c.conf.CSV.Columns,
+	)
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	rows, err := r.ReadRows(0)
+	if err != io.EOF {
+		return nil, err
+	}
+	return rows, nil
+}
+
+//------------------------------------------------------------------------------
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (c *CSV) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	c.mCount.Incr(1)
+
+	newMsg := message.New(nil)
+	msg.IterMetadata(func(k, v string) error {
+		newMsg.SetMetadata(k, v)
+		return nil
+	})
+	lParts := msg.Len()
+
+	noParts := len(c.conf.CSV.Parts) == 0
+	for i, part := range msg.GetAll() {
+		isTarget := noParts
+		if !isTarget {
+			nI := i - lParts
+			for _, t := range c.conf.CSV.Parts {
+				if t == nI || t == i {
+					isTarget = true
+					break
+				}
+			}
+		}
+		if !isTarget {
+			index := newMsg.Append(part)
+			newMsg.SetError(index, msg.GetError(i))
+			continue
+		}
+
+		rows, err := c.parse(part)
+		if err != nil {
+			c.mErr.Incr(1)
+			c.log.Debugf("Failed to parse part as CSV: %v\n", err)
+			index := newMsg.Append(part)
+			newMsg.SetError(index, err)
+			continue
+		}
+		for _, row := range rows {
+			index := newMsg.Append(row.Doc)
+			if row.Err != nil {
+				c.mErrParse.Incr(1)
+				c.log.Debugf("Failed to parse CSV row: %v\n", row.Err)
+				newMsg.SetError(index, row.Err)
+			}
+		}
+		c.mRows.Incr(int64(len(rows)))
+		c.mSucc.Incr(1)
+	}
+
+	if newMsg.Len() == 0 {
+		c.mSkipped.Incr(1)
+		c.mDropped.Incr(1)
+		return nil, response.NewAck()
+	}
+
+	c.mSent.Incr(1)
+	c.mSentParts.Incr(int64(newMsg.Len()))
+	msgs := [1]types.Message{newMsg}
+	return msgs[:], nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,173 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
+)
+
+func TestCSVHeaderRow(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = "csv"
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+	proc, err := New(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := message.New([][]byte{
+		[]byte("id,name\n1,foo\n2,\"bar, \"\"baz\"\"\"\n"),
+		[]byte("a,b\n3,4,5\n"),
+	})
+	input.SetMetadata("foo", "bar")
+
+	msgs, res := proc.ProcessMessage(input)
+	if res != nil {
+		t.Fatalf("Unexpected response: %v", res)
+	}
+	if len(msgs) != 1 {
+		t.Fatalf("Wrong count of messages: %v", len(msgs))
+	}
+
+	exp := [][]byte{
+		[]byte(`{"id":"1","name":"foo"}`),
+		[]byte(`{"id":"2","name":"bar, \"baz\""}`),
+		[]byte(`{"2":"5","a":"3","b":"4"}`),
+	}
+	if act := msgs[0].GetAll(); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+	if msgs[0].GetError(1) != nil {
+		t.Errorf("Unexpected row error: %v", msgs[0].GetError(1))
+	}
+	if msgs[0].GetError(2) == nil {
+		t.Error("Expected field count error to be flagged on row")
+	}
+	if exp, act := "bar", msgs[0].GetMetadata("foo"); exp != act {
+		t.Errorf("Wrong metadata: %v != %v", act, exp)
+	}
+}
+
+func TestCSVColumns(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = "csv"
+	conf.CSV.Delim = "\t"
+	conf.CSV.HeaderRow = false
+	conf.CSV.Columns = []string{"id", "name"}
+	conf.CSV.Parts = []int{-1}
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+	proc, err := New(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte("not csv"),
+		[]byte("1\tfoo"),
+	}))
+	if res != nil {
+		t.Fatalf("Unexpected response: %v", res)
+	}
+	if len(msgs) != 1 {
+		t.Fatalf("Wrong count of messages: %v", len(msgs))
+	}
+
+	exp := [][]byte{
+		[]byte(`not csv`),
+		[]byte(`{"id":"1","name":"foo"}`),
+	}
+	if act := msgs[0].GetAll(); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+}
+
+func TestCSVNoColumns(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = "csv"
+	conf.CSV.HeaderRow = false
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+	proc, err := New(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte("a,b\nc,\"d\n"),
+	}))
+	if res != nil {
+		t.Fatalf("Unexpected response: %v", res)
+	}
+	if len(msgs) != 1 {
+		t.Fatalf("Wrong count of messages: %v", len(msgs))
+	}
+	if exp, act := 2, msgs[0].Len(); exp != act {
+		t.Fatalf("Wrong count of parts: %v != %v", act, exp)
+	}
+	if exp, act := `["a","b"]`, string(msgs[0].Get(0)); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+	if msgs[0].GetError(1) == nil {
+		t.Error("Expected quote error to be flagged on row")
+	}
+}
+
+func TestCSVEmpty(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = "csv"
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+	proc, err := New(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte("id,name\n"),
+		[]byte(""),
+	}))
+	if len(msgs) != 0 {
+		t.Errorf("Expected message to be skipped: %s", msgs[0].GetAll())
+	}
+	if !reflect.DeepEqual(response.NewAck(), res) {
+		t.Errorf("Expected ack response: %v", res)
+	}
+}
+
+func TestCSVBadDelim(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = "csv"
+	conf.CSV.Delim = ""
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+	if _, err := New(conf, nil, testLog, metrics.DudType{}); err == nil {
+		t.Error("Expected error from bad delimiter")
+	}
+}
@@ -122,7 +122,8 @@ func (c *FilterParts) ProcessMessage(msg types.Message) ([]types.Message, types.
 
 	for i := 0; i < msg.Len(); i++ {
 		if c.condition.Check(message.Lock(msg, i)) {
-			newMsg.Append(msg.Get(i))
+			index := newMsg.Append(msg.Get(i))
+			newMsg.SetError(index, msg.GetError(i))
 		} else {
 			c.mPartDropped.Incr(1)
 		}
@@ -21,6 +21,7 @@
 package processor
 
 import (
+	"errors"
 	"os"
 	"reflect"
 	"testing"
@@ -105,3 +106,36 @@ func TestFilterPartsTextCheck(t *testing.T) {
 		})
 	}
 }
+
+func TestFilterPartsProcessorFailed(t *testing.T) {
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+	testMet := metrics.DudType{}
+
+	conf := NewConfig()
+	conf.Type = "filter_parts"
+	conf.FilterParts.Type = "processor_failed"
+
+	c, err := New(conf, nil, testLog, testMet)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := message.New([][]byte{
+		[]byte("foo"),
+		[]byte("bar"),
+		[]byte("baz"),
+	})
+	errTest := errors.New("test err")
+	input.SetError(1, errTest)
+
+	got, res := c.ProcessMessage(input)
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if exp, act := [][]byte{[]byte("bar")}, got[0].GetAll(); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+	if exp, act := errTest, got[0].GetError(0); exp != act {
+		t.Errorf("Error flag not preserved: %v != %v", act, exp)
+	}
+}
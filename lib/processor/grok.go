@@ -21,6 +21,7 @@
 package processor
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/Jeffail/benthos/lib/log"
@@ -48,6 +49,10 @@ the resulting payload would be ` + "`{\"first\":\"foo\",\"second\":1}`" + `.`,
 
 //------------------------------------------------------------------------------
 
+// ErrNoGrokMatches is flagged on message parts that do not match any of the
+// configured patterns.
+var ErrNoGrokMatches = errors.New("no grok patterns matched")
+
 // GrokConfig contains configuration fields for the Grok processor.
 type GrokConfig struct {
 	Parts       []int    `json:"parts" yaml:"parts"`
@@ -164,12 +169,14 @@ func (g *Grok) ProcessMessage(msg types.Message) ([]types.Message, types.Respons
 		if len(values) == 0 {
 			g.mErrGrok.Incr(1)
 			g.log.Debugf("No matches found for payload: %s\n", body)
+			newMsg.SetError(index, ErrNoGrokMatches)
 			continue
 		}
 
 		if err := newMsg.SetJSON(index, values); err != nil {
 			g.mErrJSONS.Incr(1)
 			g.log.Debugf("Failed to convert grok result into json: %v\n", err)
+			newMsg.SetError(index, err)
 		} else {
 			g.mSucc.Incr(1)
 		}
@@ -68,6 +68,50 @@ func TestGrokAllParts(t *testing.T) {
 	}
 }
 
+func TestGrokFailedParts(t *testing.T) {
+	conf := NewConfig()
+	conf.Grok.Parts = []int{}
+	conf.Grok.Patterns = []string{
+		"%{WORD:first},%{INT:second:int}",
+	}
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	gSet, err := NewGrok(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgIn := message.New([][]byte{
+		[]byte(`foo,0`),
+		[]byte(`nope`),
+		[]byte(`foo,2`),
+	})
+	msgs, res := gSet.ProcessMessage(msgIn)
+	if len(msgs) != 1 {
+		t.Fatal("Wrong count of messages")
+	}
+	if res != nil {
+		t.Fatal("Non-nil result")
+	}
+
+	exp := [][]byte{
+		[]byte(`{"first":"foo","second":0}`),
+		[]byte(`nope`),
+		[]byte(`{"first":"foo","second":2}`),
+	}
+	act := msgs[0].GetAll()
+	if !reflect.DeepEqual(act, exp) {
+		t.Errorf("Wrong output from grok: %s != %s", act, exp)
+	}
+	if msgs[0].GetError(0) != nil || msgs[0].GetError(2) != nil {
+		t.Error("Unexpected error flags on successful parts")
+	}
+	if exp, act := ErrNoGrokMatches, msgs[0].GetError(1); exp != act {
+		t.Errorf("Wrong error flag: %v != %v", act, exp)
+	}
+}
+
 func TestGrok(t *testing.T) {
 	tLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
 	tStats := metrics.DudType{}
@@ -167,6 +167,7 @@ func (p *JMESPath) ProcessMessage(msg types.Message) ([]types.Message, types.Res
 		if err != nil {
 			p.mErrJSONP.Incr(1)
 			p.log.Debugf("Failed to parse part into json: %v\n", err)
+			newMsg.SetError(index, err)
 			continue
 		}
 
@@ -174,12 +175,14 @@ func (p *JMESPath) ProcessMessage(msg types.Message) ([]types.Message, types.Res
 		if result, err = safeSearch(jsonPart, p.query); err != nil {
 			p.mErrJMES.Incr(1)
 			p.log.Debugf("Failed to search json: %v\n", err)
+			newMsg.SetError(index, err)
 			continue
 		}
 
 		if err = newMsg.SetJSON(index, result); err != nil {
 			p.mErrJSONS.Incr(1)
 			p.log.Debugf("Failed to convert jmespath result into part: %v\n", err)
+			newMsg.SetError(index, err)
 		} else {
 			p.mSucc.Incr(1)
 		}
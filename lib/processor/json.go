@@ -532,6 +532,7 @@ func (p *JSON) ProcessMessage(msg types.Message) ([]types.Message, types.Respons
 		if err != nil {
 			p.mErrJSONP.Incr(1)
 			p.log.Debugf("Failed to parse part into json: %v\n", err)
+			newMsg.SetError(index, err)
 			continue
 		}
 
@@ -539,6 +540,7 @@ func (p *JSON) ProcessMessage(msg types.Message) ([]types.Message, types.Respons
 		if data, err = p.operator(jsonPart, valueBytes); err != nil {
 			p.mErr.Incr(1)
 			p.log.Debugf("Failed to apply operator: %v\n", err)
+			newMsg.SetError(index, err)
 			continue
 		}
 
@@ -551,6 +553,7 @@ func (p *JSON) ProcessMessage(msg types.Message) ([]types.Message, types.Respons
 			if err = newMsg.SetJSON(index, data); err != nil {
 				p.mErrJSONS.Incr(1)
 				p.log.Debugf("Failed to convert json into part: %v\n", err)
+				newMsg.SetError(index, err)
 			}
 		}
 
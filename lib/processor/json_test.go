@@ -115,6 +115,9 @@ func TestJSONValidation(t *testing.T) {
 	if exp, act := "this is bad json", string(msgs[0].GetAll()[0]); exp != act {
 		t.Errorf("Wrong output from bad json: %v != %v", act, exp)
 	}
+	if msgs[0].GetError(0) == nil {
+		t.Error("Expected bad json part to be flagged as failed")
+	}
 
 	conf.JSON.Parts = []int{5}
 
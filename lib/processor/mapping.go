@@ -0,0 +1,244 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"fmt"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/mapping"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeMapping] = TypeSpec{
+		constructor: NewMapping,
+		description: `
+Executes a mapping against message parts, where each line of the mapping is an
+assignment that builds a new document from the contents and metadata of the
+part. The mapping is parsed when the processor is created, and therefore any
+syntax errors are reported at startup along with the line they occurred on.
+
+For example, with the following config:
+
+` + "``` yaml" + `
+mapping:
+  mapping: |
+    root.id = this.user.id
+    root.name = this.user.first_name + " " + this.user.last_name
+    root.tier = if this.spend > 1000 { "gold" } else { "standard" }
+    meta topic = meta("kafka_topic").or("none").uppercase()
+` + "```" + `
+
+If the initial contents of a part were:
+
+` + "``` json" + `
+{"user":{"id":"123","first_name":"Ash","last_name":"Jeffs"},"spend":1500}
+` + "```" + `
+
+Then the resulting contents of the part would be:
+
+` + "``` json" + `
+{"id":"123","name":"Ash Jeffs","tier":"gold"}
+` + "```" + `
+
+### Assignments
+
+Assignments to ` + "`root`" + ` set the contents of the resulting document, where
+a dot path such as ` + "`root.foo.bar`" + ` sets a nested field, creating
+objects along the way where required. Assigning to ` + "`root`" + ` directly
+replaces the whole document, and string values are written as raw content
+rather than JSON. If ` + "`root`" + ` is never assigned then the contents of the
+part are left unchanged, which allows mappings that only modify metadata.
+
+Assignments of the form ` + "`meta foo = <expression>`" + ` set a metadata
+value of the message.
+
+Assigning the function ` + "`deleted()`" + ` to a field removes it from the
+document, assigning it to a metadata key removes the key, and assigning it to
+` + "`root`" + ` removes the message part entirely.
+
+### Expressions
+
+Fields of the original document are referenced with ` + "`this`" + `, e.g.
+` + "`this.foo.bar`" + `, where array elements can be accessed with an index
+such as ` + "`this.items.0`" + ` or ` + "`this.items.-1`" + `. Metadata is
+referenced with ` + "`meta(\"key\")`" + `.
+
+Expressions support the arithmetic operators ` + "`+ - * / %`" + ` (where
+` + "`+`" + ` also concatenates strings), comparisons ` + "`== != < <= > >=`" + `,
+boolean operators ` + "`&& || !`" + `, array and object literals, and
+conditional expressions of the form
+` + "`if <cond> { <expr> } else if <cond> { <expr> } else { <expr> }`" + `.
+A conditional without a matching branch leaves its target unchanged.
+
+The following functions are available: ` + "`batch_index()`" + `,
+` + "`batch_size()`" + `, ` + "`content()`" + `, ` + "`deleted()`" + `,
+` + "`meta(key)`" + ` and ` + "`now()`" + `.
+
+The following methods can be called on values: ` + "`contains(v)`" + `,
+` + "`has_prefix(s)`" + `, ` + "`has_suffix(s)`" + `, ` + "`join(s)`" + `,
+` + "`keys()`" + `, ` + "`length()`" + `, ` + "`lowercase()`" + `,
+` + "`number()`" + `, ` + "`or(v)`" + `, ` + "`replace(old, new)`" + `,
+` + "`split(s)`" + `, ` + "`string()`" + `, ` + "`trim()`" + `, ` + "`type()`" + `
+and ` + "`uppercase()`" + `. The method ` + "`or(v)`" + ` returns its argument
+when the target is null or fails to resolve, e.g.
+` + "`this.foo.or(\"default\")`" + `.
+
+If a mapping fails for a part, for example when the part is not valid JSON, the
+part is left unchanged and flagged as having failed, which can be handled with
+the ` + "[`catch`](#catch)" + ` processor.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// MappingConfig contains configuration fields for the Mapping processor.
+type MappingConfig struct {
+	Parts   []int  `json:"parts" yaml:"parts"`
+	Mapping string `json:"mapping" yaml:"mapping"`
+}
+
+// NewMappingConfig returns a MappingConfig with default values.
+func NewMappingConfig() MappingConfig {
+	return MappingConfig{
+		Parts:   []int{},
+		Mapping: "",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Mapping is a processor that executes a mapping against message parts in
+// order to build new documents from their contents and metadata.
+type Mapping struct {
+	parts []int
+	exec  *mapping.Executor
+
+	log   log.Modular
+	stats metrics.Type
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mSucc      metrics.StatCounter
+	mDropped   metrics.StatCounter
+	mSent      metrics.StatCounter
+	mSentParts metrics.StatCounter
+}
+
+// NewMapping returns a Mapping processor.
+func NewMapping(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	exec, err := mapping.Parse(conf.Mapping.Mapping)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse mapping: %v", err)
+	}
+	return &Mapping{
+		parts: conf.Mapping.Parts,
+		exec:  exec,
+		log:   log.NewModule(".processor.mapping"),
+		stats: stats,
+
+		mCount:     stats.GetCounter("processor.mapping.count"),
+		mErr:       stats.GetCounter("processor.mapping.error"),
+		mSucc:      stats.GetCounter("processor.mapping.success"),
+		mDropped:   stats.GetCounter("processor.mapping.dropped"),
+		mSent:      stats.GetCounter("processor.mapping.sent"),
+		mSentParts: stats.GetCounter("processor.mapping.parts.sent"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (p *Mapping) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	p.mCount.Incr(1)
+
+	newMsg := msg.ShallowCopy()
+	lParts := newMsg.Len()
+
+	targetParts := p.parts
+	if len(targetParts) == 0 {
+		targetParts = make([]int, lParts)
+		for i := range targetParts {
+			targetParts[i] = i
+		}
+	}
+
+	var deleted map[int]struct{}
+	for _, index := range targetParts {
+		if index < 0 {
+			index = lParts + index
+		}
+		if index < 0 || index >= lParts {
+			continue
+		}
+		keep, err := p.exec.MapPart(index, msg, newMsg)
+		if err != nil {
+			p.mErr.Incr(1)
+			p.log.Debugf("Failed to execute mapping: %v\n", err)
+			newMsg.SetError(index, err)
+			continue
+		}
+		p.mSucc.Incr(1)
+		if !keep {
+			if deleted == nil {
+				deleted = map[int]struct{}{}
+			}
+			deleted[index] = struct{}{}
+		}
+	}
+
+	if len(deleted) > 0 {
+		p.mDropped.Incr(int64(len(deleted)))
+		if len(deleted) == lParts {
+			return nil, response.NewAck()
+		}
+		filteredMsg := message.New(nil)
+		newMsg.IterMetadata(func(k, v string) error {
+			filteredMsg.SetMetadata(k, v)
+			return nil
+		})
+		for i := 0; i < lParts; i++ {
+			if _, isDeleted := deleted[i]; isDeleted {
+				continue
+			}
+			index := filteredMsg.Append(newMsg.Get(i))
+			filteredMsg.SetError(index, newMsg.GetError(i))
+		}
+		newMsg = filteredMsg
+	}
+
+	msgs := [1]types.Message{newMsg}
+
+	p.mSent.Incr(1)
+	p.mSentParts.Incr(int64(newMsg.Len()))
+	return msgs[:], nil
+}
+
+//------------------------------------------------------------------------------
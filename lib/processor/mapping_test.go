@@ -0,0 +1,205 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+func TestMappingAllParts(t *testing.T) {
+	conf := NewConfig()
+	conf.Mapping.Mapping = `root.id = this.user.id
+root.double = this.value * 2
+meta source = "mapped"`
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	proc, err := NewMapping(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgIn := message.New([][]byte{
+		[]byte(`{"user":{"id":"foo"},"value":1}`),
+		[]byte(`{"user":{"id":"bar"},"value":2}`),
+	})
+	msgs, res := proc.ProcessMessage(msgIn)
+	if len(msgs) != 1 {
+		t.Fatal("Wrong count of messages")
+	}
+	if res != nil {
+		t.Fatal("Non-nil result")
+	}
+
+	exp := [][]byte{
+		[]byte(`{"double":2,"id":"foo"}`),
+		[]byte(`{"double":4,"id":"bar"}`),
+	}
+	if act := msgs[0].GetAll(); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+	if exp, act := "mapped", msgs[0].GetMetadata("source"); exp != act {
+		t.Errorf("Wrong metadata: %v != %v", act, exp)
+	}
+	if exp, act := `{"user":{"id":"foo"},"value":1}`, string(msgIn.Get(0)); exp != act {
+		t.Errorf("Input message was modified: %v != %v", act, exp)
+	}
+}
+
+func TestMappingSomeParts(t *testing.T) {
+	conf := NewConfig()
+	conf.Mapping.Parts = []int{-1}
+	conf.Mapping.Mapping = `root = this.foo.uppercase()`
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	proc, err := NewMapping(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgIn := message.New([][]byte{
+		[]byte(`{"foo":"first"}`),
+		[]byte(`{"foo":"second"}`),
+	})
+	msgs, _ := proc.ProcessMessage(msgIn)
+	if len(msgs) != 1 {
+		t.Fatal("Wrong count of messages")
+	}
+
+	exp := [][]byte{
+		[]byte(`{"foo":"first"}`),
+		[]byte(`SECOND`),
+	}
+	if act := msgs[0].GetAll(); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+}
+
+func TestMappingDeleteParts(t *testing.T) {
+	conf := NewConfig()
+	conf.Mapping.Mapping = `root = if this.drop { deleted() } else { this }`
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	proc, err := NewMapping(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgIn := message.New([][]byte{
+		[]byte(`{"drop":true,"id":0}`),
+		[]byte(`{"drop":false,"id":1}`),
+		[]byte(`not json`),
+	})
+	msgIn.SetMetadata("foo", "bar")
+	msgs, res := proc.ProcessMessage(msgIn)
+	if len(msgs) != 1 {
+		t.Fatal("Wrong count of messages")
+	}
+	if res != nil {
+		t.Fatal("Non-nil result")
+	}
+
+	exp := [][]byte{
+		[]byte(`{"drop":false,"id":1}`),
+		[]byte(`not json`),
+	}
+	if act := msgs[0].GetAll(); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+	if msgs[0].GetError(0) != nil {
+		t.Error("Unexpected error flag on part 0")
+	}
+	if msgs[0].GetError(1) == nil {
+		t.Error("Expected error flag on part 1")
+	}
+	if exp, act := "bar", msgs[0].GetMetadata("foo"); exp != act {
+		t.Errorf("Wrong metadata: %v != %v", act, exp)
+	}
+
+	msgs, res = proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"drop":true}`),
+	}))
+	if len(msgs) != 0 {
+		t.Error("Expected message to be dropped")
+	}
+	if res == nil || res.Error() != nil {
+		t.Errorf("Expected ack response: %v", res)
+	}
+}
+
+func TestMappingFailedParts(t *testing.T) {
+	conf := NewConfig()
+	conf.Mapping.Mapping = `root.foo = this.foo / this.bar`
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	proc, err := NewMapping(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgIn := message.New([][]byte{
+		[]byte(`{"foo":10,"bar":2}`),
+		[]byte(`{"foo":10,"bar":0}`),
+	})
+	msgs, _ := proc.ProcessMessage(msgIn)
+	if len(msgs) != 1 {
+		t.Fatal("Wrong count of messages")
+	}
+
+	exp := [][]byte{
+		[]byte(`{"foo":5}`),
+		[]byte(`{"foo":10,"bar":0}`),
+	}
+	if act := msgs[0].GetAll(); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+	if msgs[0].GetError(0) != nil {
+		t.Error("Unexpected error flag on part 0")
+	}
+	if msgs[0].GetError(1) == nil {
+		t.Error("Expected error flag on part 1")
+	}
+}
+
+func TestMappingParseError(t *testing.T) {
+	conf := NewConfig()
+	conf.Mapping.Mapping = `root.foo = this.foo
+root.bar = nope()`
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	_, err := NewMapping(conf, nil, testLog, metrics.DudType{})
+	if err == nil {
+		t.Fatal("Expected error")
+	}
+	if exp, act := "failed to parse mapping: line 2 char 12: unrecognised function 'nope'", err.Error(); exp != act {
+		t.Errorf("Wrong error: %v != %v", act, exp)
+	}
+}
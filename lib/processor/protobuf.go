@@ -0,0 +1,247 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/jhump/protoreflect/dynamic"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeProtobuf] = TypeSpec{
+		constructor: NewProtobuf,
+		description: `
+Converts message parts between protobuf binary and JSON. The operator
+` + "`to_json`" + ` decodes protobuf binary into JSON and the operator
+` + "`from_json`" + ` encodes JSON into protobuf binary, where JSON documents
+follow the
+[protobuf JSON mapping](https://developers.google.com/protocol-buffers/docs/proto3#json).
+
+The field ` + "`message`" + ` is the fully qualified name of the message type
+to convert, e.g. ` + "`foo.bar.Person`" + `, and ` + "`import_path`" + ` is a
+directory containing ` + "`.proto`" + ` files. All ` + "`.proto`" + ` files
+within the directory are parsed when the processor is created, and imports
+between them are resolved relative to the directory.
+
+Parts that fail to be converted are left unchanged and flagged as having failed,
+which can be handled with the ` + "[`catch`](#catch)" + ` processor.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// ProtobufConfig contains configuration fields for the Protobuf processor.
+type ProtobufConfig struct {
+	Parts      []int  `json:"parts" yaml:"parts"`
+	Operator   string `json:"operator" yaml:"operator"`
+	Message    string `json:"message" yaml:"message"`
+	ImportPath string `json:"import_path" yaml:"import_path"`
+}
+
+// NewProtobufConfig returns a ProtobufConfig with default values.
+func NewProtobufConfig() ProtobufConfig {
+	return ProtobufConfig{
+		Parts:      []int{},
+		Operator:   "to_json",
+		Message:    "",
+		ImportPath: "",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type protobufOperator func(part []byte) ([]byte, error)
+
+func newProtobufToJSONOperator(md *desc.MessageDescriptor) protobufOperator {
+	return func(part []byte) ([]byte, error) {
+		msg := dynamic.NewMessage(md)
+		if err := msg.Unmarshal(part); err != nil {
+			return nil, fmt.Errorf("failed to decode protobuf: %v", err)
+		}
+		return msg.MarshalJSON()
+	}
+}
+
+func newProtobufFromJSONOperator(md *desc.MessageDescriptor) protobufOperator {
+	return func(part []byte) ([]byte, error) {
+		msg := dynamic.NewMessage(md)
+		if err := msg.UnmarshalJSON(part); err != nil {
+			return nil, fmt.Errorf("failed to parse json as protobuf: %v", err)
+		}
+		return msg.Marshal()
+	}
+}
+
+func strToProtobufOperator(str string, md *desc.MessageDescriptor) (protobufOperator, error) {
+	switch str {
+	case "to_json":
+		return newProtobufToJSONOperator(md), nil
+	case "from_json":
+		return newProtobufFromJSONOperator(md), nil
+	}
+	return nil, fmt.Errorf("operator not recognised: %v", str)
+}
+
+// loadProtobufDescriptor parses all .proto files within a directory and
+// returns the descriptor of a message type.
+func loadProtobufDescriptor(importPath, msgName string) (*desc.MessageDescriptor, error) {
+	var files []string
+	if err := filepath.Walk(importPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || filepath.Ext(path) != ".proto" {
+			return nil
+		}
+		rel, err := filepath.Rel(importPath, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to walk import path: %v", err)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no .proto files found in import path: %v", importPath)
+	}
+
+	fds, err := protoparse.Parser{
+		ImportPaths: []string{importPath},
+	}.ParseFiles(files...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse .proto files: %v", err)
+	}
+
+	msgName = strings.TrimPrefix(msgName, ".")
+	for _, fd := range fds {
+		if md := fd.FindMessage(msgName); md != nil {
+			return md, nil
+		}
+	}
+	return nil, fmt.Errorf("message type not found: %v", msgName)
+}
+
+//------------------------------------------------------------------------------
+
+// Protobuf is a processor that converts message parts between protobuf binary
+// and JSON.
+type Protobuf struct {
+	parts    []int
+	operator protobufOperator
+
+	conf  Config
+	log   log.Modular
+	stats metrics.Type
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mSucc      metrics.StatCounter
+	mSent      metrics.StatCounter
+	mSentParts metrics.StatCounter
+}
+
+// NewProtobuf returns a Protobuf processor.
+func NewProtobuf(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	if len(conf.Protobuf.Message) == 0 {
+		return nil, errors.New("a message type must be specified")
+	}
+	if len(conf.Protobuf.ImportPath) == 0 {
+		return nil, errors.New("an import path must be specified")
+	}
+
+	md, err := loadProtobufDescriptor(conf.Protobuf.ImportPath, conf.Protobuf.Message)
+	if err != nil {
+		return nil, err
+	}
+
+	op, err := strToProtobufOperator(conf.Protobuf.Operator, md)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Protobuf{
+		parts:    conf.Protobuf.Parts,
+		operator: op,
+		conf:     conf,
+		log:      log.NewModule(".processor.protobuf"),
+		stats:    stats,
+
+		mCount:     stats.GetCounter("processor.protobuf.count"),
+		mErr:       stats.GetCounter("processor.protobuf.error"),
+		mSucc:      stats.GetCounter("processor.protobuf.success"),
+		mSent:      stats.GetCounter("processor.protobuf.sent"),
+		mSentParts: stats.GetCounter("processor.protobuf.parts.sent"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (p *Protobuf) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	p.mCount.Incr(1)
+
+	newMsg := msg.ShallowCopy()
+
+	targetParts := p.parts
+	if len(targetParts) == 0 {
+		targetParts = make([]int, newMsg.Len())
+		for i := range targetParts {
+			targetParts[i] = i
+		}
+	}
+
+	for _, index := range targetParts {
+		newPart, err := p.operator(newMsg.Get(index))
+		if err != nil {
+			p.mErr.Incr(1)
+			p.log.Debugf("Failed to convert part: %v\n", err)
+			newMsg.SetError(index, err)
+			continue
+		}
+		p.mSucc.Incr(1)
+		newMsg.Set(index, newPart)
+	}
+
+	msgs := [1]types.Message{newMsg}
+
+	p.mSent.Incr(1)
+	p.mSentParts.Incr(int64(newMsg.Len()))
+	return msgs[:], nil
+}
+
+//------------------------------------------------------------------------------
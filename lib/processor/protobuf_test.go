@@ -0,0 +1,173 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+func writeTestProtoFiles(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "benthos_protobuf_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.MkdirAll(filepath.Join(dir, "common"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"common/address.proto": `syntax = "proto3";
+package testing.common;
+
+message Address {
+  string city = 1;
+}
+`,
+		"person.proto": `syntax = "proto3";
+package testing;
+
+import "common/address.proto";
+
+message Person {
+  string name = 1;
+  int32 age = 2;
+  testing.common.Address address = 3;
+}
+`,
+	}
+	for name, content := range files {
+		if err = ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestProtobufRoundTrip(t *testing.T) {
+	dir := writeTestProtoFiles(t)
+	defer os.RemoveAll(dir)
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	conf := NewConfig()
+	conf.Protobuf.Operator = "from_json"
+	conf.Protobuf.Message = "testing.Person"
+	conf.Protobuf.ImportPath = dir
+
+	encoder, err := NewProtobuf(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf.Protobuf.Operator = "to_json"
+	decoder, err := NewProtobuf(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, res := encoder.ProcessMessage(message.New([][]byte{
+		[]byte(`{"name":"foo","age":21}`),
+		[]byte(`{"name":"bar","address":{"city":"london"}}`),
+		[]byte(`{"nope":"baz"}`),
+	}))
+	if len(msgs) != 1 {
+		t.Fatal("Wrong count of messages")
+	}
+	if res != nil {
+		t.Fatal("Non-nil result")
+	}
+
+	if exp, act := []byte{0x0a, 0x03, 'f', 'o', 'o', 0x10, 0x15}, msgs[0].Get(0); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong encoded result: %v != %v", act, exp)
+	}
+	for i := 0; i < 2; i++ {
+		if err = msgs[0].GetError(i); err != nil {
+			t.Errorf("Unexpected error flag on part %v: %v", i, err)
+		}
+	}
+	if msgs[0].GetError(2) == nil {
+		t.Error("Expected error flag on part 2")
+	}
+
+	msgs, _ = decoder.ProcessMessage(message.New(msgs[0].GetAll()[:2]))
+	if len(msgs) != 1 {
+		t.Fatal("Wrong count of messages")
+	}
+	exp := []interface{}{
+		map[string]interface{}{"name": "foo", "age": float64(21)},
+		map[string]interface{}{"name": "bar", "address": map[string]interface{}{"city": "london"}},
+	}
+	for i, e := range exp {
+		act, err := msgs[0].GetJSON(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(e, act) {
+			t.Errorf("Wrong decoded result: %v != %v", act, e)
+		}
+	}
+}
+
+func TestProtobufBadConfig(t *testing.T) {
+	dir := writeTestProtoFiles(t)
+	defer os.RemoveAll(dir)
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	conf := NewConfig()
+	conf.Protobuf.ImportPath = dir
+	if _, err := NewProtobuf(conf, nil, testLog, metrics.DudType{}); err == nil {
+		t.Error("Expected error from missing message")
+	}
+
+	conf.Protobuf.Message = "testing.Nope"
+	if _, err := NewProtobuf(conf, nil, testLog, metrics.DudType{}); err == nil {
+		t.Error("Expected error from unknown message")
+	}
+
+	conf.Protobuf.Message = "testing.Person"
+	conf.Protobuf.Operator = "nope"
+	if _, err := NewProtobuf(conf, nil, testLog, metrics.DudType{}); err == nil {
+		t.Error("Expected error from bad operator")
+	}
+
+	conf.Protobuf.Operator = "to_json"
+	conf.Protobuf.ImportPath = filepath.Join(dir, "does_not_exist")
+	if _, err := NewProtobuf(conf, nil, testLog, metrics.DudType{}); err == nil {
+		t.Error("Expected error from bad import path")
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "bad.proto"), []byte("not a proto file"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	conf.Protobuf.ImportPath = dir
+	if _, err := NewProtobuf(conf, nil, testLog, metrics.DudType{}); err == nil {
+		t.Error("Expected error from bad proto file")
+	}
+}
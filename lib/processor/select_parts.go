@@ -126,7 +126,8 @@ func (m *SelectParts) ProcessMessage(msg types.Message) ([]types.Message, types.
 			m.mSkipped.Incr(1)
 		} else {
 			m.mSelected.Incr(1)
-			newMsg.Append(msg.Get(index))
+			i := newMsg.Append(msg.Get(index))
+			newMsg.SetError(i, msg.GetError(index))
 		}
 	}
 
@@ -96,6 +96,7 @@ func (s *Split) ProcessMessage(msg types.Message) ([]types.Message, types.Respon
 	msgs := make([]types.Message, msg.Len())
 	for i, part := range msg.GetAll() {
 		msgs[i] = message.New([][]byte{part})
+		msgs[i].SetError(0, msg.GetError(i))
 	}
 	msg.IterMetadata(func(k, v string) error {
 		for _, m := range msgs {
@@ -21,6 +21,7 @@
 package processor
 
 import (
+	"errors"
 	"os"
 	"reflect"
 	"testing"
@@ -69,3 +70,46 @@ func TestSplitParts(t *testing.T) {
 		}
 	}
 }
+
+func TestSplitBatchErrorFlags(t *testing.T) {
+	conf := NewConfig()
+	conf.Batch.ByteSize = 6
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+	split, err := NewSplit(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	batch, err := NewBatch(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errTest := errors.New("test error")
+
+	msg := message.New([][]byte{[]byte("foo"), []byte("bar")})
+	msg.SetError(1, errTest)
+
+	msgs, _ := split.ProcessMessage(msg)
+	if exp, act := 2, len(msgs); exp != act {
+		t.Fatalf("Wrong count of messages: %v != %v", act, exp)
+	}
+	for i, exp := range []error{nil, errTest} {
+		if act := msgs[i].GetError(0); act != exp {
+			t.Errorf("Wrong error flag after split for message %v: %v != %v", i, act, exp)
+		}
+	}
+
+	if batched, _ := batch.ProcessMessage(msgs[0]); len(batched) != 0 {
+		t.Fatal("Expected first message to be batched")
+	}
+	batched, _ := batch.ProcessMessage(msgs[1])
+	if exp, act := 1, len(batched); exp != act {
+		t.Fatalf("Wrong count of messages: %v != %v", act, exp)
+	}
+	for i, exp := range []error{nil, errTest} {
+		if act := batched[0].GetError(i); act != exp {
+			t.Errorf("Wrong error flag after batch for part %v: %v != %v", i, act, exp)
+		}
+	}
+}
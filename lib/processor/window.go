@@ -0,0 +1,512 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/text"
+	"github.com/Jeffail/gabs"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeWindow] = TypeSpec{
+		constructor: NewWindow,
+		description: `
+Aggregates message parts into time windows grouped by a key, emitting one
+message per key of a window once the window closes. Each message part is
+treated as an individual event.
+
+The ` + "`key`" + ` field supports
+[interpolation functions](../config_interpolation.md#functions) resolved
+against each part, for example ` + "`${!json_field:user_id}`" + `. When left
+empty all parts are aggregated under the same key.
+
+Windows are ` + "`size_ms`" + ` milliseconds long. When ` + "`slide_ms`" + ` is
+zero the windows are tumbling, meaning each part belongs to exactly one window.
+Otherwise a new window begins every ` + "`slide_ms`" + ` milliseconds, and
+parts belong to every window that overlaps them.
+
+### Time
+
+By default windows are driven by processing time, where each part is placed in
+a window using the time at which it is processed. If ` + "`timestamp_path`" + `
+is set then windows are instead driven by the event time found at that path
+within the JSON contents of each part. Timestamps can either be numbers of
+seconds since the Unix epoch or strings parsed with the layout
+` + "`timestamp_format`" + `, written in the format of Go's
+[time.Parse](https://golang.org/pkg/time/#Parse).
+
+With processing time a window closes once the current time passes its end. With
+event time a window closes once a part has been seen with a timestamp at least
+` + "`allowed_lateness_ms`" + ` past its end. Parts that arrive for a window
+that has already closed are dropped. Windows are only closed when a message
+is processed, meaning a window can remain open beyond its end if no messages
+arrive.
+
+### Output
+
+When a window closes a message is emitted for each key of the window, containing
+a JSON document of the form:
+
+` + "``` json" + `
+{
+  "key": "foo",
+  "window_start": "2018-08-10T10:00:00Z",
+  "window_end": "2018-08-10T10:01:00Z",
+  "count": 3,
+  "sum": 6,
+  "min": 1,
+  "max": 3,
+  "values": [1, 2, 3]
+}
+` + "```" + `
+
+The values of the window are taken from ` + "`value_path`" + ` within the JSON
+contents of each part, or the whole part when left empty, falling back to the
+raw contents as a string if the part is not JSON. The sum, min and max are
+calculated from values that are numbers, and min and max are null when there
+are none.
+
+Messages that are aggregated without closing a window are not acknowledged
+until the messages of a later closed window reach their destination, in the
+same way as the ` + "[`batch`](#batch)" + ` processor.
+
+### Checkpointing
+
+If ` + "`cache`" + ` is set to the name of a cache resource then the state of
+all open windows is written to the cache under the key ` + "`cache_key`" + `
+after each message, and is restored from the cache when the processor is
+created. Caches should be configured as a resource, for more information check
+out the [documentation here](../caches).
+
+Checkpointing is at-least-once. The messages aggregated into open windows are
+still not acknowledged until a later window closes, and if the service stops
+before then those messages are delivered again and counted a second time by the
+restored windows.
+
+Each window processor with a cache must have a ` + "`cache_key`" + ` of its own,
+otherwise processors would overwrite the state of each other. For this reason a
+pipeline with more than one thread cannot contain window processors with a
+cache.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// WindowConfig contains configuration fields for the Window processor.
+type WindowConfig struct {
+	Key               string `json:"key" yaml:"key"`
+	ValuePath         string `json:"value_path" yaml:"value_path"`
+	SizeMS            int    `json:"size_ms" yaml:"size_ms"`
+	SlideMS           int    `json:"slide_ms" yaml:"slide_ms"`
+	TimestampPath     string `json:"timestamp_path" yaml:"timestamp_path"`
+	TimestampFormat   string `json:"timestamp_format" yaml:"timestamp_format"`
+	AllowedLatenessMS int    `json:"allowed_lateness_ms" yaml:"allowed_lateness_ms"`
+	Cache             string `json:"cache" yaml:"cache"`
+	CacheKey          string `json:"cache_key" yaml:"cache_key"`
+}
+
+// NewWindowConfig returns a WindowConfig with default values.
+func NewWindowConfig() WindowConfig {
+	return WindowConfig{
+		Key:               "",
+		ValuePath:         "",
+		SizeMS:            60000,
+		SlideMS:           0,
+		TimestampPath:     "",
+		TimestampFormat:   time.RFC3339,
+		AllowedLatenessMS: 0,
+		Cache:             "",
+		CacheKey:          "benthos_window_state",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// windowAggregate is the aggregated state of a single key within a window.
+type windowAggregate struct {
+	Key    string        `json:"key"`
+	Start  int64         `json:"start"`
+	Count  int64         `json:"count"`
+	Sum    float64       `json:"sum"`
+	Min    *float64      `json:"min"`
+	Max    *float64      `json:"max"`
+	Values []interface{} `json:"values"`
+}
+
+func (a *windowAggregate) add(value interface{}) {
+	a.Count++
+	a.Values = append(a.Values, value)
+
+	var f float64
+	switch t := value.(type) {
+	case float64:
+		f = t
+	case json.Number:
+		var err error
+		if f, err = t.Float64(); err != nil {
+			return
+		}
+	default:
+		return
+	}
+
+	a.Sum += f
+	if a.Min == nil || f < *a.Min {
+		min := f
+		a.Min = &min
+	}
+	if a.Max == nil || f > *a.Max {
+		max := f
+		a.Max = &max
+	}
+}
+
+// windowState is the state of all open windows, which is checkpointed.
+type windowState struct {
+	Watermark  int64              `json:"watermark"`
+	Aggregates []*windowAggregate `json:"aggregates"`
+}
+
+type windowID struct {
+	start int64
+	key   string
+}
+
+//------------------------------------------------------------------------------
+
+// Window is a processor that aggregates message parts into tumbling or sliding
+// time windows grouped by an interpolated key.
+type Window struct {
+	conf  WindowConfig
+	log   log.Modular
+	stats metrics.Type
+
+	keyBytes       []byte
+	interpolateKey bool
+	size           int64
+	slide          int64
+	lateness       int64
+
+	cache types.Cache
+	now   func() time.Time
+
+	watermark  int64
+	aggregates map[windowID]*windowAggregate
+
+	mCount        metrics.StatCounter
+	mErrTimestamp metrics.StatCounter
+	mErrCache     metrics.StatCounter
+	mLate         metrics.StatCounter
+	mDropped      metrics.StatCounter
+	mSent         metrics.StatCounter
+	mSentParts    metrics.StatCounter
+}
+
+// NewWindow returns a Window processor.
+func NewWindow(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	if conf.Window.SizeMS <= 0 {
+		return nil, errors.New("size_ms must be greater than zero")
+	}
+	if conf.Window.SlideMS < 0 || conf.Window.SlideMS > conf.Window.SizeMS {
+		return nil, errors.New("slide_ms must be between zero and size_ms")
+	}
+
+	keyBytes := []byte(conf.Window.Key)
+	w := &Window{
+		conf:  conf.Window,
+		log:   log.NewModule(".processor.window"),
+		stats: stats,
+
+		keyBytes:       keyBytes,
+		interpolateKey: text.ContainsFunctionVariables(keyBytes),
+		size:           int64(conf.Window.SizeMS),
+		slide:          int64(conf.Window.SlideMS),
+		lateness:       int64(conf.Window.AllowedLatenessMS),
+
+		now:        time.Now,
+		aggregates: map[windowID]*windowAggregate{},
+
+		mCount:        stats.GetCounter("processor.window.count"),
+		mErrTimestamp: stats.GetCounter("processor.window.error.timestamp"),
+		mErrCache:     stats.GetCounter("processor.window.error.cache"),
+		mLate:         stats.GetCounter("processor.window.late"),
+		mDropped:      stats.GetCounter("processor.window.dropped"),
+		mSent:         stats.GetCounter("processor.window.sent"),
+		mSentParts:    stats.GetCounter("processor.window.parts.sent"),
+	}
+	if w.slide == 0 {
+		w.slide = w.size
+	}
+
+	if len(conf.Window.Cache) > 0 {
+		var err error
+		if w.cache, err = mgr.GetCache(conf.Window.Cache); err != nil {
+			return nil, err
+		}
+		if err = w.restore(); err != nil {
+			return nil, fmt.Errorf("failed to restore window state: %v", err)
+		}
+	}
+	return w, nil
+}
+
+//------------------------------------------------------------------------------
+
+// restore reads the checkpointed state of open windows from the cache.
+func (w *Window) restore() error {
+	stateBytes, err := w.cache.Get(w.conf.CacheKey)
+	if err == types.ErrKeyNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var state windowState
+	if err = json.Unmarshal(stateBytes, &state); err != nil {
+		return err
+	}
+	w.watermark = state.Watermark
+	for _, agg := range state.Aggregates {
+		w.aggregates[windowID{start: agg.Start, key: agg.Key}] = agg
+	}
+	return nil
+}
+
+// checkpoint writes the state of open windows to the cache.
+func (w *Window) checkpoint() {
+	state := windowState{
+		Watermark:  w.watermark,
+		Aggregates: make([]*windowAggregate, 0, len(w.aggregates)),
+	}
+	for _, agg := range w.aggregates {
+		state.Aggregates = append(state.Aggregates, agg)
+	}
+	stateBytes, err := json.Marshal(state)
+	if err == nil {
+		err = w.cache.Set(w.conf.CacheKey, stateBytes)
+	}
+	if err != nil {
+		w.mErrCache.Incr(1)
+		w.log.Errorf("Failed to checkpoint window state: %v\n", err)
+	}
+}
+
+// timestamp returns the time of a part in milliseconds since the Unix epoch.
+func (w *Window) timestamp(jPart *gabs.Container) (int64, error) {
+	if len(w.conf.TimestampPath) == 0 {
+		return w.now().UnixNano() / int64(time.Millisecond), nil
+	}
+	if jPart == nil {
+		return 0, errors.New("part is not valid JSON")
+	}
+	switch t := jPart.Path(w.conf.TimestampPath).Data().(type) {
+	case float64:
+		return int64(t * 1000), nil
+	case json.Number:
+		f, err := t.Float64()
+		if err != nil {
+			return 0, err
+		}
+		return int64(f * 1000), nil
+	case string:
+		ts, err := time.Parse(w.conf.TimestampFormat, t)
+		if err != nil {
+			return 0, err
+		}
+		return ts.UnixNano() / int64(time.Millisecond), nil
+	case nil:
+		return 0, fmt.Errorf("timestamp not found at path: %v", w.conf.TimestampPath)
+	}
+	return 0, fmt.Errorf("timestamp at path '%v' was not a number or string", w.conf.TimestampPath)
+}
+
+// closeTime returns the time before which all windows are closed.
+func (w *Window) closeTime() int64 {
+	if len(w.conf.TimestampPath) == 0 {
+		return w.watermark
+	}
+	return w.watermark - w.lateness
+}
+
+// addPart adds a message part to each window that it belongs to.
+func (w *Window) addPart(index int, msg types.Message) {
+	part := msg.Get(index)
+	jPart, jErr := gabs.ParseJSON(part)
+	if jErr != nil {
+		jPart = nil
+	}
+
+	ts, err := w.timestamp(jPart)
+	if err != nil {
+		w.mErrTimestamp.Incr(1)
+		w.mDropped.Incr(1)
+		w.log.Debugf("Failed to extract timestamp: %v\n", err)
+		return
+	}
+
+	var value interface{} = string(part)
+	if jPart != nil {
+		if len(w.conf.ValuePath) > 0 {
+			value = jPart.Path(w.conf.ValuePath).Data()
+		} else {
+			value = jPart.Data()
+		}
+	}
+
+	key := w.keyBytes
+	if w.interpolateKey {
+		key = text.ReplaceFunctionVariables(message.Lock(msg, index), key)
+	}
+
+	closed := w.closeTime()
+	added := false
+
+	// The latest window containing ts starts at the last multiple of slide,
+	// with earlier windows containing ts every slide before it.
+	start := ts - ts%w.slide
+	if ts < 0 && ts%w.slide != 0 {
+		start -= w.slide
+	}
+	for ; start > ts-w.size; start -= w.slide {
+		if start+w.size <= closed {
+			continue
+		}
+		id := windowID{start: start, key: string(key)}
+		agg, exists := w.aggregates[id]
+		if !exists {
+			agg = &windowAggregate{Key: id.key, Start: start, Values: []interface{}{}}
+			w.aggregates[id] = agg
+		}
+		agg.add(value)
+		added = true
+	}
+
+	if !added {
+		w.mLate.Incr(1)
+		w.mDropped.Incr(1)
+	}
+	if ts > w.watermark {
+		w.watermark = ts
+	}
+}
+
+// closeWindows removes all windows that have closed and returns a message for
+// each key of them, ordered by window start and then key.
+func (w *Window) closeWindows() []types.Message {
+	closed := w.closeTime()
+
+	var aggs []*windowAggregate
+	for id, agg := range w.aggregates {
+		if id.start+w.size <= closed {
+			aggs = append(aggs, agg)
+			delete(w.aggregates, id)
+		}
+	}
+	sort.Slice(aggs, func(i, j int) bool {
+		if aggs[i].Start == aggs[j].Start {
+			return strings.Compare(aggs[i].Key, aggs[j].Key) < 0
+		}
+		return aggs[i].Start < aggs[j].Start
+	})
+
+	msgs := make([]types.Message, 0, len(aggs))
+	for _, agg := range aggs {
+		start := time.Unix(0, agg.Start*int64(time.Millisecond)).UTC()
+		end := start.Add(time.Duration(w.size) * time.Millisecond)
+		resBytes, err := json.Marshal(struct {
+			Key         string        `json:"key"`
+			WindowStart string        `json:"window_start"`
+			WindowEnd   string        `json:"window_end"`
+			Count       int64         `json:"count"`
+			Sum         float64       `json:"sum"`
+			Min         *float64      `json:"min"`
+			Max         *float64      `json:"max"`
+			Values      []interface{} `json:"values"`
+		}{
+			Key:         agg.Key,
+			WindowStart: start.Format(time.RFC3339Nano),
+			WindowEnd:   end.Format(time.RFC3339Nano),
+			Count:       agg.Count,
+			Sum:         agg.Sum,
+			Min:         agg.Min,
+			Max:         agg.Max,
+			Values:      agg.Values,
+		})
+		if err != nil {
+			w.log.Errorf("Failed to marshal window result: %v\n", err)
+			continue
+		}
+		msgs = append(msgs, message.New([][]byte{resBytes}))
+	}
+	return msgs
+}
+
+//------------------------------------------------------------------------------
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (w *Window) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	w.mCount.Incr(1)
+
+	if len(w.conf.TimestampPath) == 0 {
+		// With processing time the watermark is the current time, which
+		// closes windows before new parts are added.
+		w.watermark = w.now().UnixNano() / int64(time.Millisecond)
+	}
+
+	for i := 0; i < msg.Len(); i++ {
+		w.addPart(i, msg)
+	}
+
+	msgs := w.closeWindows()
+	if w.cache != nil {
+		w.checkpoint()
+	}
+
+	if len(msgs) == 0 {
+		w.log.Traceln("Added message to pending windows")
+		return nil, response.NewUnack()
+	}
+
+	w.mSent.Incr(int64(len(msgs)))
+	for _, m := range msgs {
+		w.mSentParts.Incr(int64(m.Len()))
+	}
+	return msgs, nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,247 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/cache"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+func windowResults(msgs []types.Message) []string {
+	results := []string{}
+	for _, m := range msgs {
+		for _, p := range m.GetAll() {
+			results = append(results, string(p))
+		}
+	}
+	return results
+}
+
+func TestWindowTumblingProcessingTime(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = "window"
+	conf.Window.Key = "${!json_field:user}"
+	conf.Window.ValuePath = "amount"
+	conf.Window.SizeMS = 1000
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+	stats := metrics.NewLocal()
+	proc, err := New(conf, nil, testLog, stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Unix(100, 0)
+	proc.(*Window).now = func() time.Time {
+		return now
+	}
+
+	inputs := [][][]byte{
+		{
+			[]byte(`{"user":"foo","amount":1}`),
+			[]byte(`{"user":"bar","amount":10}`),
+		},
+		{
+			[]byte(`{"user":"foo","amount":3}`),
+			[]byte(`{"user":"foo","amount":"nope"}`),
+		},
+	}
+	for _, parts := range inputs {
+		msgs, res := proc.ProcessMessage(message.New(parts))
+		if len(msgs) != 0 {
+			t.Fatalf("Unexpected messages: %s", windowResults(msgs))
+		}
+		if res == nil || !res.SkipAck() {
+			t.Error("Expected unack response")
+		}
+		now = now.Add(time.Millisecond * 400)
+	}
+
+	now = time.Unix(101, 200000000)
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"user":"foo","amount":5}`),
+	}))
+	if res != nil {
+		t.Errorf("Unexpected response: %v", res)
+	}
+
+	exp := []string{
+		`{"key":"bar","window_start":"1970-01-01T00:01:40Z","window_end":"1970-01-01T00:01:41Z","count":1,"sum":10,"min":10,"max":10,"values":[10]}`,
+		`{"key":"foo","window_start":"1970-01-01T00:01:40Z","window_end":"1970-01-01T00:01:41Z","count":3,"sum":4,"min":1,"max":3,"values":[1,3,"nope"]}`,
+	}
+	if act := windowResults(msgs); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong results: %s != %s", act, exp)
+	}
+	counters := stats.GetCounters()
+	if exp, act := int64(2), counters["processor.window.sent"]; exp != act {
+		t.Errorf("Wrong count of sent messages: %v != %v", act, exp)
+	}
+	if exp, act := int64(2), counters["processor.window.parts.sent"]; exp != act {
+		t.Errorf("Wrong count of sent parts: %v != %v", act, exp)
+	}
+
+	now = time.Unix(102, 0)
+	msgs, _ = proc.ProcessMessage(message.New([][]byte{
+		[]byte(`not json`),
+	}))
+	exp = []string{
+		`{"key":"foo","window_start":"1970-01-01T00:01:41Z","window_end":"1970-01-01T00:01:42Z","count":1,"sum":5,"min":5,"max":5,"values":[5]}`,
+	}
+	if act := windowResults(msgs); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong results: %s != %s", act, exp)
+	}
+}
+
+func TestWindowSlidingEventTime(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = "window"
+	conf.Window.SizeMS = 2000
+	conf.Window.SlideMS = 1000
+	conf.Window.TimestampPath = "ts"
+	conf.Window.ValuePath = "v"
+	conf.Window.AllowedLatenessMS = 500
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+	proc, err := New(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	process := func(parts ...string) []string {
+		msg := message.New(nil)
+		for _, p := range parts {
+			msg.Append([]byte(p))
+		}
+		msgs, _ := proc.ProcessMessage(msg)
+		return windowResults(msgs)
+	}
+
+	if act := process(
+		`{"ts":10.5,"v":1}`,
+		`{"ts":"1970-01-01T00:00:11Z","v":2}`,
+		`{"v":100}`,
+	); len(act) != 0 {
+		t.Errorf("Unexpected results: %s", act)
+	}
+
+	// Moves the watermark to 12.4, which closes the window 9-11 but is within
+	// the allowed lateness of the window 10-12.
+	exp := []string{
+		`{"key":"","window_start":"1970-01-01T00:00:09Z","window_end":"1970-01-01T00:00:11Z","count":1,"sum":1,"min":1,"max":1,"values":[1]}`,
+	}
+	if act := process(`{"ts":12.4,"v":3}`); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong results: %s != %s", act, exp)
+	}
+
+	exp = []string{
+		`{"key":"","window_start":"1970-01-01T00:00:10Z","window_end":"1970-01-01T00:00:12Z","count":3,"sum":7,"min":1,"max":4,"values":[1,2,4]}`,
+	}
+	if act := process(`{"ts":11.9,"v":4}`, `{"ts":12.5,"v":5}`); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong results: %s != %s", act, exp)
+	}
+
+	// The window 10-12 has closed, and therefore this part only belongs to the
+	// window 11-13.
+	if act := process(`{"ts":11.5,"v":6}`); len(act) != 0 {
+		t.Errorf("Unexpected results: %s", act)
+	}
+
+	exp = []string{
+		`{"key":"","window_start":"1970-01-01T00:00:11Z","window_end":"1970-01-01T00:00:13Z","count":5,"sum":20,"min":2,"max":6,"values":[2,3,4,5,6]}`,
+	}
+	if act := process(`{"ts":13.5,"v":7}`); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong results: %s != %s", act, exp)
+	}
+}
+
+func TestWindowCheckpoint(t *testing.T) {
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr := &fakeMgr{
+		caches: map[string]types.Cache{
+			"foocache": memCache,
+		},
+	}
+
+	conf := NewConfig()
+	conf.Type = "window"
+	conf.Window.SizeMS = 1000
+	conf.Window.TimestampPath = "ts"
+	conf.Window.Cache = "foocache"
+
+	proc, err := New(conf, mgr, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msgs, _ := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"ts":1}`),
+		[]byte(`{"ts":1.5}`),
+	})); len(msgs) != 0 {
+		t.Errorf("Unexpected results: %s", windowResults(msgs))
+	}
+
+	// A new processor should continue from the checkpointed state.
+	if proc, err = New(conf, mgr, testLog, metrics.DudType{}); err != nil {
+		t.Fatal(err)
+	}
+	exp := []string{
+		`{"key":"","window_start":"1970-01-01T00:00:01Z","window_end":"1970-01-01T00:00:02Z","count":2,"sum":0,"min":null,"max":null,"values":[{"ts":1},{"ts":1.5}]}`,
+	}
+	msgs, _ := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"ts":2}`),
+	}))
+	if act := windowResults(msgs); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong results: %s != %s", act, exp)
+	}
+
+	if _, err = New(conf, &fakeMgr{}, testLog, metrics.DudType{}); err == nil {
+		t.Error("Expected error from missing cache")
+	}
+}
+
+func TestWindowBadConfig(t *testing.T) {
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	conf := NewConfig()
+	conf.Type = "window"
+	conf.Window.SizeMS = 0
+	if _, err := New(conf, nil, testLog, metrics.DudType{}); err == nil {
+		t.Error("Expected error from zero size")
+	}
+
+	conf.Window.SizeMS = 1000
+	conf.Window.SlideMS = 2000
+	if _, err := New(conf, nil, testLog, metrics.DudType{}); err == nil {
+		t.Error("Expected error from slide larger than size")
+	}
+}
@@ -29,21 +29,60 @@ import (
 
 //------------------------------------------------------------------------------
 
+// DeadLetterConfig contains configuration fields for routing messages that
+// repeatedly fail to be processed or written to a dead letter output.
+type DeadLetterConfig struct {
+	Enabled       bool          `json:"enabled" yaml:"enabled"`
+	MaxRetries    int           `json:"max_retries" yaml:"max_retries"`
+	RetryPeriodMS int           `json:"retry_period_ms" yaml:"retry_period_ms"`
+	Output        output.Config `json:"output" yaml:"output"`
+}
+
+// NewDeadLetterConfig returns a DeadLetterConfig with default values.
+func NewDeadLetterConfig() DeadLetterConfig {
+	return DeadLetterConfig{
+		Enabled:       false,
+		MaxRetries:    3,
+		RetryPeriodMS: 1000,
+		Output:        output.NewConfig(),
+	}
+}
+
+// SanitiseDeadLetterConfig returns a sanitised version of the
+// DeadLetterConfig, meaning sections that aren't relevant to behaviour are
+// removed.
+func SanitiseDeadLetterConfig(conf DeadLetterConfig) (interface{}, error) {
+	outConf, err := output.SanitiseConfig(conf.Output)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"enabled":         conf.Enabled,
+		"max_retries":     conf.MaxRetries,
+		"retry_period_ms": conf.RetryPeriodMS,
+		"output":          outConf,
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
 // Config is a configuration struct for a Benthos stream.
 type Config struct {
-	Input    input.Config    `json:"input" yaml:"input"`
-	Buffer   buffer.Config   `json:"buffer" yaml:"buffer"`
-	Pipeline pipeline.Config `json:"pipeline" yaml:"pipeline"`
-	Output   output.Config   `json:"output" yaml:"output"`
+	Input      input.Config     `json:"input" yaml:"input"`
+	Buffer     buffer.Config    `json:"buffer" yaml:"buffer"`
+	Pipeline   pipeline.Config  `json:"pipeline" yaml:"pipeline"`
+	Output     output.Config    `json:"output" yaml:"output"`
+	DeadLetter DeadLetterConfig `json:"dead_letter" yaml:"dead_letter"`
 }
 
 // NewConfig returns a new configuration with default values.
 func NewConfig() Config {
 	return Config{
-		Input:    input.NewConfig(),
-		Buffer:   buffer.NewConfig(),
-		Pipeline: pipeline.NewConfig(),
-		Output:   output.NewConfig(),
+		Input:      input.NewConfig(),
+		Buffer:     buffer.NewConfig(),
+		Pipeline:   pipeline.NewConfig(),
+		Output:     output.NewConfig(),
+		DeadLetter: NewDeadLetterConfig(),
 	}
 }
 
@@ -74,16 +113,25 @@ func (c Config) Sanitised() (interface{}, error) {
 		return nil, err
 	}
 
+	var dlConf interface{}
+	if c.DeadLetter.Enabled {
+		if dlConf, err = SanitiseDeadLetterConfig(c.DeadLetter); err != nil {
+			return nil, err
+		}
+	}
+
 	return struct {
-		Input    interface{} `json:"input" yaml:"input"`
-		Buffer   interface{} `json:"buffer" yaml:"buffer"`
-		Pipeline interface{} `json:"pipeline" yaml:"pipeline"`
-		Output   interface{} `json:"output" yaml:"output"`
+		Input      interface{} `json:"input" yaml:"input"`
+		Buffer     interface{} `json:"buffer" yaml:"buffer"`
+		Pipeline   interface{} `json:"pipeline" yaml:"pipeline"`
+		Output     interface{} `json:"output" yaml:"output"`
+		DeadLetter interface{} `json:"dead_letter,omitempty" yaml:"dead_letter,omitempty"`
 	}{
-		Input:    inConf,
-		Buffer:   bufConf,
-		Pipeline: pipeConf,
-		Output:   outConf,
+		Input:      inConf,
+		Buffer:     bufConf,
+		Pipeline:   pipeConf,
+		Output:     outConf,
+		DeadLetter: dlConf,
 	}, nil
 }
 
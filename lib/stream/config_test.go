@@ -58,10 +58,10 @@ func TestConfigSanitised(t *testing.T) {
 	c.Output.Type = "kafka"
 
 	exp = `{` +
-		`"input":{"type":"file","file":{"delimiter":"","max_buffer":1000000,"multipart":false,"path":""}},` +
+		`"input":{"type":"file","file":{"delimiter":"","max_buffer":1000000,"multipart":false,"path":"","tail":{"checkpoint_path":"","enabled":false,"poll_period_ms":1000}}},` +
 		`"buffer":{"type":"none","none":{}},` +
 		`"pipeline":{"processors":[],"threads":1},` +
-		`"output":{"type":"kafka","kafka":{"ack_replicas":false,"addresses":["localhost:9092"],"client_id":"benthos_kafka_output","compression":"none","key":"","max_msg_bytes":1000000,"round_robin_partitions":false,"target_version":"1.0.0","timeout_ms":5000,"tls":{"cas_file":"","enabled":false,"skip_cert_verify":false},"topic":"benthos_stream"}}` +
+		`"output":{"type":"kafka","kafka":{"ack_replicas":false,"addresses":["localhost:9092"],"client_id":"benthos_kafka_output","compression":"none","key":"","max_msg_bytes":1000000,"metadata_headers":{"enabled":false,"keys":[],"prefixes":[]},"partition":"","partitioner":"hash","retry":{"initial_period_ms":1000,"jitter":0,"max_period_ms":60000,"max_retries":0,"on_failure":"nack"},"round_robin_partitions":false,"target_version":"1.0.0","timeout_ms":5000,"tls":{"cas_file":"","enabled":false,"skip_cert_verify":false},"topic":"benthos_stream"}}` +
 		`}`
 
 	if dat, err = c.Sanitised(); err != nil {
@@ -74,3 +74,33 @@ func TestConfigSanitised(t *testing.T) {
 		t.Errorf("Wrong sanitised output: %v != %v", act, exp)
 	}
 }
+
+func TestConfigSanitisedDeadLetter(t *testing.T) {
+	c := NewConfig()
+	c.Input.Processors = nil
+	c.Output.Processors = nil
+	c.DeadLetter.Enabled = true
+	c.DeadLetter.Output.Type = "file"
+	c.DeadLetter.Output.File.Path = "/tmp/dead_letters"
+	c.DeadLetter.Output.Processors = nil
+
+	exp := `{` +
+		`"input":{"type":"stdin","stdin":{"delimiter":"","max_buffer":1000000,"multipart":false}},` +
+		`"buffer":{"type":"none","none":{}},` +
+		`"pipeline":{"processors":[],"threads":1},` +
+		`"output":{"type":"stdout","stdout":{"delimiter":""}},` +
+		`"dead_letter":{"enabled":true,"max_retries":3,"output":{"type":"file","file":{"delimiter":"","path":"/tmp/dead_letters"}},"retry_period_ms":1000}` +
+		`}`
+
+	dat, err := c.Sanitised()
+	if err != nil {
+		t.Fatal(err)
+	}
+	actBytes, err := json.Marshal(dat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if act := string(actBytes); exp != act {
+		t.Errorf("Wrong sanitised output: %v != %v", act, exp)
+	}
+}
@@ -31,6 +31,7 @@ import (
 	"time"
 
 	"github.com/Jeffail/benthos/lib/buffer"
+	"github.com/Jeffail/benthos/lib/config"
 	"github.com/Jeffail/benthos/lib/input"
 	"github.com/Jeffail/benthos/lib/output"
 	"github.com/Jeffail/benthos/lib/pipeline"
@@ -54,7 +55,9 @@ func (m *Type) registerEndpoints() {
 		"/streams/{id}",
 		"Perform CRUD operations on streams, supporting POST (Create),"+
 			" GET (Read), PUT (Update), PATCH (Patch update)"+
-			" and DELETE (Delete).",
+			" and DELETE (Delete). POST, PUT and PATCH support the query"+
+			" parameter dry_run=true for validating a config without"+
+			" applying it.",
 		m.HandleStreamCRUD,
 	)
 	m.manager.RegisterEndpoint(
@@ -203,6 +206,54 @@ func (m *Type) HandleStreamsCRUD(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// dryRunResult is the response body of a dry run request.
+type dryRunResult struct {
+	Errors   []string `json:"errors"`
+	Warnings []string `json:"warnings"`
+}
+
+// writeDryRun validates a stream config without creating or updating the
+// stream, and writes the errors and warnings found as a JSON response. Lint
+// messages of the raw config are reported as warnings, and components that fail
+// to construct are reported as errors, along with parseErr if the config failed
+// to parse and stateErr if the stream is in the wrong state for the request.
+func (m *Type) writeDryRun(
+	w http.ResponseWriter,
+	id string,
+	confBytes []byte,
+	conf stream.Config,
+	parseErr, stateErr error,
+) error {
+	res := dryRunResult{
+		Errors:   []string{},
+		Warnings: []string{},
+	}
+
+	if stateErr != nil {
+		res.Errors = append(res.Errors, stateErr.Error())
+	}
+	if parseErr != nil {
+		res.Errors = append(res.Errors, fmt.Sprintf("failed to parse config: %v", parseErr))
+	} else {
+		if lints, err := config.Lint(confBytes, stream.Config{}); err == nil {
+			res.Warnings = append(res.Warnings, lints...)
+		}
+		for _, err := range m.DryRun(id, conf) {
+			res.Errors = append(res.Errors, err.Error())
+		}
+	}
+
+	resBytes, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+	if len(res.Errors) > 0 {
+		w.WriteHeader(http.StatusBadRequest)
+	}
+	w.Write(resBytes)
+	return nil
+}
+
 // HandleStreamCRUD is an http.HandleFunc for performing CRUD operations on
 // individual streams.
 func (m *Type) HandleStreamCRUD(w http.ResponseWriter, r *http.Request) {
@@ -227,8 +278,8 @@ func (m *Type) HandleStreamCRUD(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var confBytes []byte
 	readConfig := func() (confOut stream.Config, err error) {
-		var confBytes []byte
 		if confBytes, err = ioutil.ReadAll(r.Body); err != nil {
 			return
 		}
@@ -238,8 +289,7 @@ func (m *Type) HandleStreamCRUD(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	patchConfig := func(confIn stream.Config) (confOut stream.Config, err error) {
-		var patchBytes []byte
-		if patchBytes, err = ioutil.ReadAll(r.Body); err != nil {
+		if confBytes, err = ioutil.ReadAll(r.Body); err != nil {
 			return
 		}
 
@@ -259,7 +309,7 @@ func (m *Type) HandleStreamCRUD(w http.ResponseWriter, r *http.Request) {
 			Pipeline: aliasedPipe(confIn.Pipeline),
 			Output:   aliasedOut(confIn.Output),
 		}
-		if err = json.Unmarshal(patchBytes, &aliasedConf); err != nil {
+		if err = json.Unmarshal(confBytes, &aliasedConf); err != nil {
 			return
 		}
 		confOut = stream.Config{
@@ -276,9 +326,20 @@ func (m *Type) HandleStreamCRUD(w http.ResponseWriter, r *http.Request) {
 		deadline = time.Now().Add(m.apiTimeout)
 	}
 
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+
 	var conf stream.Config
 	switch r.Method {
 	case "POST":
+		if dryRun {
+			var stateErr error
+			if _, err := m.Read(id); err == nil {
+				stateErr = ErrStreamExists
+			}
+			conf, parseErr := readConfig()
+			serverErr = m.writeDryRun(w, id, confBytes, conf, parseErr, stateErr)
+			return
+		}
 		if conf, requestErr = readConfig(); requestErr != nil {
 			return
 		}
@@ -306,6 +367,12 @@ func (m *Type) HandleStreamCRUD(w http.ResponseWriter, r *http.Request) {
 			w.Write(bodyBytes)
 		}
 	case "PUT":
+		if dryRun {
+			_, stateErr := m.Read(id)
+			conf, parseErr := readConfig()
+			serverErr = m.writeDryRun(w, id, confBytes, conf, parseErr, stateErr)
+			return
+		}
 		if conf, requestErr = readConfig(); requestErr != nil {
 			return
 		}
@@ -315,6 +382,11 @@ func (m *Type) HandleStreamCRUD(w http.ResponseWriter, r *http.Request) {
 	case "PATCH":
 		var info *StreamStatus
 		if info, serverErr = m.Read(id); serverErr == nil {
+			if dryRun {
+				conf, parseErr := patchConfig(info.Config())
+				serverErr = m.writeDryRun(w, id, confBytes, conf, parseErr, nil)
+				return
+			}
 			if conf, requestErr = patchConfig(info.Config()); requestErr != nil {
 				return
 			}
@@ -33,6 +33,7 @@ import (
 
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/processor"
 	"github.com/Jeffail/benthos/lib/stream"
 	"github.com/Jeffail/benthos/lib/types"
 	"github.com/Jeffail/gabs"
@@ -604,3 +605,89 @@ func TestTypeAPIGetStats(t *testing.T) {
 		t.Logf("Metrics: %v", stats)
 	}
 }
+
+type dryRunBody struct {
+	Errors   []string `json:"errors"`
+	Warnings []string `json:"warnings"`
+}
+
+func parseDryRunBody(data *bytes.Buffer) dryRunBody {
+	result := dryRunBody{}
+	if err := json.Unmarshal(data.Bytes(), &result); err != nil {
+		panic(err)
+	}
+	return result
+}
+
+func TestTypeAPIDryRun(t *testing.T) {
+	mgr := New(
+		OptSetLogger(log.New(os.Stdout, log.Config{LogLevel: "NONE"})),
+		OptSetStats(metrics.DudType{}),
+		OptSetManager(types.DudMgr{}),
+		OptSetAPITimeout(time.Millisecond*100),
+	)
+
+	r := router(mgr)
+
+	request, _ := http.NewRequest("POST", "/streams/foo?dry_run=true", bytes.NewReader([]byte(`
+input:
+  type: http_server
+  http_server:
+    path: /foo/post
+    pathh: /nope
+output:
+  type: http_server
+`)))
+	response := httptest.NewRecorder()
+	r.ServeHTTP(response, request)
+	if exp, act := http.StatusOK, response.Code; exp != act {
+		t.Errorf("Unexpected result: %v != %v", act, exp)
+	}
+	res := parseDryRunBody(response.Body)
+	if exp, act := []string{}, res.Errors; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Unexpected errors: %v != %v", act, exp)
+	}
+	if exp, act := []string{"line 6: input.http_server.pathh: field not recognised"}, res.Warnings; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Unexpected warnings: %v != %v", act, exp)
+	}
+	if _, err := mgr.Read("foo"); err != ErrStreamDoesNotExist {
+		t.Errorf("Wrong error returned: %v != %v", err, ErrStreamDoesNotExist)
+	}
+
+	badConf := harmlessConf()
+	badConf.Input.Type = "does_not_exist"
+	badConf.Pipeline.Processors = append(badConf.Pipeline.Processors, processor.NewConfig())
+	badConf.Pipeline.Processors[0].Type = "does_not_exist"
+
+	request = genRequest("POST", "/streams/foo?dry_run=true", badConf)
+	response = httptest.NewRecorder()
+	r.ServeHTTP(response, request)
+	if exp, act := http.StatusBadRequest, response.Code; exp != act {
+		t.Errorf("Unexpected result: %v != %v", act, exp)
+	}
+	res = parseDryRunBody(response.Body)
+	if exp, act := 2, len(res.Errors); exp != act {
+		t.Errorf("Wrong count of errors: %v != %v: %v", act, exp, res.Errors)
+	}
+
+	request = genRequest("PUT", "/streams/foo?dry_run=true", harmlessConf())
+	response = httptest.NewRecorder()
+	r.ServeHTTP(response, request)
+	if exp, act := http.StatusBadRequest, response.Code; exp != act {
+		t.Errorf("Unexpected result: %v != %v", act, exp)
+	}
+	res = parseDryRunBody(response.Body)
+	if exp, act := []string{ErrStreamDoesNotExist.Error()}, res.Errors; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Unexpected errors: %v != %v", act, exp)
+	}
+
+	request, _ = http.NewRequest("POST", "/streams/foo?dry_run=true", bytes.NewReader([]byte(`input: [ not valid`)))
+	response = httptest.NewRecorder()
+	r.ServeHTTP(response, request)
+	if exp, act := http.StatusBadRequest, response.Code; exp != act {
+		t.Errorf("Unexpected result: %v != %v", act, exp)
+	}
+	if res = parseDryRunBody(response.Body); len(res.Errors) != 1 {
+		t.Errorf("Wrong count of errors: %v", res.Errors)
+	}
+}
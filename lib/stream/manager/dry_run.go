@@ -0,0 +1,193 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/buffer"
+	"github.com/Jeffail/benthos/lib/input"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/output"
+	"github.com/Jeffail/benthos/lib/pipeline"
+	"github.com/Jeffail/benthos/lib/processor"
+	"github.com/Jeffail/benthos/lib/processor/condition"
+	"github.com/Jeffail/benthos/lib/stream"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// dryRunMgr wraps a manager in order to provide resources to components created
+// for a dry run, whilst preventing them from registering HTTP endpoints or
+// consuming from pipes that belong to running streams.
+type dryRunMgr struct {
+	types.Manager
+}
+
+func (d dryRunMgr) RegisterEndpoint(path, desc string, h http.HandlerFunc) {}
+
+func (d dryRunMgr) GetPipe(name string) (<-chan types.Transaction, error) {
+	return nil, types.ErrPipeNotFound
+}
+
+func (d dryRunMgr) SetPipe(name string, t <-chan types.Transaction) {}
+
+func (d dryRunMgr) UnsetPipe(name string, t <-chan types.Transaction) {}
+
+//------------------------------------------------------------------------------
+
+// validateInput checks an input config and the configs of any inputs nested
+// within it without constructing them, since inputs begin connecting as soon as
+// they are created. The processors and conditions of each input are
+// constructed, as they do not consume data until started.
+func validateInput(conf input.Config, mgr types.Manager, logger log.Modular, stats metrics.Type) error {
+	if _, exists := input.Constructors[conf.Type]; !exists {
+		return types.ErrInvalidInputType
+	}
+	if _, err := input.SanitiseConfig(conf); err != nil {
+		return err
+	}
+	for _, procConf := range conf.Processors {
+		if _, err := processor.New(procConf, mgr, logger, stats); err != nil {
+			return fmt.Errorf("failed to create processor '%v': %v", procConf.Type, err)
+		}
+	}
+
+	switch conf.Type {
+	case input.TypeBroker:
+		if len(conf.Broker.Inputs)*conf.Broker.Copies <= 0 {
+			return input.ErrBrokerNoInputs
+		}
+		for i, child := range conf.Broker.Inputs {
+			if err := validateInput(child, mgr, logger, stats); err != nil {
+				return fmt.Errorf("broker input %v: %v", i, err)
+			}
+		}
+	case input.TypeDynamic:
+		for k, child := range conf.Dynamic.Inputs {
+			if err := validateInput(child, mgr, logger, stats); err != nil {
+				return fmt.Errorf("dynamic input '%v': %v", k, err)
+			}
+		}
+	case input.TypeReadUntil:
+		if conf.ReadUntil.Input == nil {
+			return errors.New("cannot create read_until input without a child")
+		}
+		if err := validateInput(*conf.ReadUntil.Input, mgr, logger, stats); err != nil {
+			return fmt.Errorf("read_until input: %v", err)
+		}
+		if _, err := condition.New(conf.ReadUntil.Condition, mgr, logger, stats); err != nil {
+			return fmt.Errorf("failed to create condition '%v': %v", conf.ReadUntil.Condition.Type, err)
+		}
+	}
+	return nil
+}
+
+// closeDryRun shuts down a pipeline or output constructed for a dry run. The
+// component is closed before being given an already closed transaction channel
+// to consume, which allows it to exit without connecting or reading data, and
+// then waits for it to finish.
+func closeDryRun(name string, c interface {
+	types.Consumer
+	types.Closable
+}, logger log.Modular, timeout time.Duration) {
+	tChan := make(chan types.Transaction)
+	close(tChan)
+
+	c.CloseAsync()
+	if err := c.Consume(tChan); err != nil {
+		logger.Warnf("Failed to close dry run %v: %v\n", name, err)
+		return
+	}
+	if err := c.WaitForClose(timeout); err != nil {
+		logger.Warnf("Failed to close dry run %v: %v\n", name, err)
+	}
+}
+
+// DryRunConfig validates a stream config without running the stream, and
+// returns an error for each component that is invalid. Components are given
+// resources from mgr, but cannot register endpoints or use its inproc pipes.
+// The timeout bounds how long each constructed component may take to close.
+//
+// Inputs are never constructed, as they begin connecting as soon as they are
+// created. Instead their types and configs are checked, along with those of
+// any child inputs, and their processors are constructed. Buffers are only
+// checked for a recognised type, as some allocate resources on disk when
+// constructed. Pipelines and outputs are constructed and then closed before
+// they process or write any data.
+func DryRunConfig(
+	conf stream.Config,
+	mgr types.Manager,
+	logger log.Modular,
+	timeout time.Duration,
+) []error {
+	var errs []error
+
+	mgr = dryRunMgr{Manager: mgr}
+	cLogger := log.Noop()
+	stats := metrics.Noop()
+
+	if err := validateInput(conf.Input, mgr, cLogger, stats); err != nil {
+		errs = append(errs, fmt.Errorf("input: %v", err))
+	}
+
+	if _, exists := buffer.Constructors[conf.Buffer.Type]; !exists {
+		errs = append(errs, fmt.Errorf("buffer: %v", types.ErrInvalidBufferType))
+	}
+
+	if pipe, err := pipeline.New(conf.Pipeline, mgr, cLogger, stats); err != nil {
+		errs = append(errs, fmt.Errorf("pipeline: %v", err))
+	} else {
+		closeDryRun("pipeline", pipe, logger, timeout)
+	}
+
+	if out, err := output.New(conf.Output, mgr, cLogger, stats); err != nil {
+		errs = append(errs, fmt.Errorf("output: %v", err))
+	} else {
+		closeDryRun("output", out, logger, timeout)
+	}
+
+	if conf.DeadLetter.Enabled {
+		if out, err := output.New(conf.DeadLetter.Output, mgr, cLogger, stats); err != nil {
+			errs = append(errs, fmt.Errorf("dead_letter: %v", err))
+		} else {
+			closeDryRun("dead_letter output", out, logger, timeout)
+		}
+	}
+
+	return errs
+}
+
+// DryRun validates a stream config as DryRunConfig does, using the resources
+// that a stream of the given id would be created with.
+func (m *Type) DryRun(id string, conf stream.Config) []error {
+	return DryRunConfig(
+		conf, namespacedMgr(id, m.manager),
+		m.logger.NewModule("."+id), m.apiTimeout,
+	)
+}
+
+//------------------------------------------------------------------------------
@@ -25,6 +25,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/Jeffail/benthos/lib/stream"
@@ -34,6 +35,48 @@ import (
 
 //------------------------------------------------------------------------------
 
+// isStreamFile returns whether a file path has the extension of a stream
+// config.
+func isStreamFile(path string) bool {
+	return strings.HasSuffix(path, ".yaml") || strings.HasSuffix(path, ".json")
+}
+
+// streamIDFromPath returns the id of a stream from the path of its config file
+// within a directory, which is the path relative to the directory less the
+// extension, with path separators replaced by underscores.
+func streamIDFromPath(dir, path string) string {
+	id := strings.TrimPrefix(path, dir)
+	id = strings.Trim(id, string(filepath.Separator))
+	id = strings.Replace(id, string(filepath.Separator), "_", -1)
+
+	if strings.HasSuffix(id, ".yaml") {
+		return strings.TrimSuffix(id, ".yaml")
+	}
+	return strings.TrimSuffix(id, ".json")
+}
+
+// readStreamFile reads and parses a stream config file.
+func readStreamFile(replaceEnvVars bool, path string) (stream.Config, error) {
+	conf := stream.NewConfig()
+
+	file, err := os.Open(path)
+	if err != nil {
+		return conf, fmt.Errorf("failed to read stream file '%v': %v", path, err)
+	}
+	defer file.Close()
+
+	streamBytes, err := ioutil.ReadAll(file)
+	if err != nil {
+		return conf, err
+	}
+	if replaceEnvVars {
+		streamBytes = text.ReplaceEnvVariables(streamBytes)
+	}
+
+	err = yaml.Unmarshal(streamBytes, &conf)
+	return conf, err
+}
+
 // LoadStreamConfigsFromDirectory reads a map of stream ids to configurations
 // by walking a directory of .json and .yaml files.
 func LoadStreamConfigsFromDirectory(replaceEnvVars bool, dir string) (map[string]stream.Config, error) {
@@ -54,44 +97,19 @@ func LoadStreamConfigsFromDirectory(replaceEnvVars bool, dir string) (map[string
 		if werr != nil {
 			return werr
 		}
-		if info.IsDir() ||
-			(!strings.HasSuffix(info.Name(), ".yaml") &&
-				!strings.HasSuffix(info.Name(), ".json")) {
+		if info.IsDir() || !isStreamFile(path) {
 			return nil
 		}
 
-		id := strings.TrimPrefix(path, dir)
-		id = strings.Trim(id, string(filepath.Separator))
-		id = strings.Replace(id, string(filepath.Separator), "_", -1)
-
-		if strings.HasSuffix(info.Name(), ".yaml") {
-			id = strings.TrimSuffix(id, ".yaml")
-		} else {
-			id = strings.TrimSuffix(id, ".json")
-		}
-
+		id := streamIDFromPath(dir, path)
 		if _, exists := streamMap[id]; exists {
 			return fmt.Errorf("stream id (%v) collision from file: %v", id, path)
 		}
 
-		file, openerr := os.Open(path)
-		if openerr != nil {
-			return fmt.Errorf("failed to read stream file '%v': %v", path, openerr)
-		}
-		defer file.Close()
-
-		streamBytes, readerr := ioutil.ReadAll(file)
+		conf, readerr := readStreamFile(replaceEnvVars, path)
 		if readerr != nil {
 			return readerr
 		}
-		if replaceEnvVars {
-			streamBytes = text.ReplaceEnvVariables(streamBytes)
-		}
-
-		conf := stream.NewConfig()
-		if readerr = yaml.Unmarshal(streamBytes, &conf); readerr != nil {
-			return readerr
-		}
 
 		streamMap[id] = conf
 		return nil
@@ -101,3 +119,37 @@ func LoadStreamConfigsFromDirectory(replaceEnvVars bool, dir string) (map[string
 }
 
 //------------------------------------------------------------------------------
+
+// CreateFromDirectory creates a stream for each config file within a directory,
+// read as LoadStreamConfigsFromDirectory does, and returns the number of
+// streams created. Streams created this way are not written to the store set
+// with OptSetStore, as their configs are already kept within the directory.
+//
+// Streams that already exist, such as those created by a DirectoryWatcher of
+// the same directory, are skipped and not counted.
+func (m *Type) CreateFromDirectory(replaceEnvVars bool, dir string) (int, error) {
+	confs, err := LoadStreamConfigsFromDirectory(replaceEnvVars, dir)
+	if err != nil {
+		return 0, err
+	}
+
+	ids := make([]string, 0, len(confs))
+	for id := range confs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	created := 0
+	for _, id := range ids {
+		if err = m.create(id, confs[id], false); err == ErrStreamExists {
+			continue
+		}
+		if err != nil {
+			return created, fmt.Errorf("failed to create stream (%v): %v", id, err)
+		}
+		created++
+	}
+	return created, nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,288 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/Jeffail/benthos/lib/stream"
+	"github.com/Jeffail/benthos/lib/types"
+	yaml "gopkg.in/yaml.v2"
+)
+
+//------------------------------------------------------------------------------
+
+// Store is a persistence backend for the configs of streams managed by a stream
+// manager, allowing streams to be restored after a restart.
+type Store interface {
+	// Set persists the config of a stream under its id, replacing any config
+	// previously stored under the same id.
+	Set(id string, conf stream.Config) error
+
+	// Delete removes the config of a stream by its id. Deleting an id that
+	// does not exist is not an error.
+	Delete(id string) error
+
+	// ReadAll returns the configs of all stored streams by their ids.
+	ReadAll() (map[string]stream.Config, error)
+}
+
+// marshalStreamConfig returns the sanitised form of a stream config as YAML.
+func marshalStreamConfig(conf stream.Config) ([]byte, error) {
+	sanit, err := conf.Sanitised()
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(sanit)
+}
+
+// unmarshalStreamConfig parses a stream config from YAML or JSON.
+func unmarshalStreamConfig(confBytes []byte) (stream.Config, error) {
+	conf := stream.NewConfig()
+	err := yaml.Unmarshal(confBytes, &conf)
+	return conf, err
+}
+
+//------------------------------------------------------------------------------
+
+// DirectoryStore is a Store that writes the sanitised config of each stream as
+// a YAML file within a directory, where the filename is the id of the stream.
+type DirectoryStore struct {
+	dir string
+	mut sync.Mutex
+}
+
+// NewDirectoryStore creates a DirectoryStore for a directory, creating the
+// directory if it does not already exist.
+func NewDirectoryStore(dir string) (*DirectoryStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &DirectoryStore{dir: dir}, nil
+}
+
+// path returns the file path of a stream id, escaping any characters that are
+// not safe for a filename.
+func (d *DirectoryStore) path(id string) string {
+	return filepath.Join(d.dir, url.PathEscape(id)+".yaml")
+}
+
+// Set writes the config of a stream to a file. The file is written in full to a
+// temporary path before replacing any existing file.
+func (d *DirectoryStore) Set(id string, conf stream.Config) error {
+	confBytes, err := marshalStreamConfig(conf)
+	if err != nil {
+		return err
+	}
+
+	d.mut.Lock()
+	defer d.mut.Unlock()
+
+	path := d.path(id)
+	tmpPath := path + ".tmp"
+	if err = ioutil.WriteFile(tmpPath, confBytes, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// Delete removes the file of a stream.
+func (d *DirectoryStore) Delete(id string) error {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+
+	if err := os.Remove(d.path(id)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// ReadAll reads the config of each stream file within the directory.
+func (d *DirectoryStore) ReadAll() (map[string]stream.Config, error) {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+
+	infos, err := ioutil.ReadDir(d.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	confs := map[string]stream.Config{}
+	for _, info := range infos {
+		name := info.Name()
+		if info.IsDir() || !strings.HasSuffix(name, ".yaml") {
+			continue
+		}
+		id, err := url.PathUnescape(strings.TrimSuffix(name, ".yaml"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse stream id from file '%v': %v", name, err)
+		}
+		confBytes, err := ioutil.ReadFile(filepath.Join(d.dir, name))
+		if err != nil {
+			return nil, err
+		}
+		if confs[id], err = unmarshalStreamConfig(confBytes); err != nil {
+			return nil, fmt.Errorf("failed to parse stream file '%v': %v", name, err)
+		}
+	}
+	return confs, nil
+}
+
+//------------------------------------------------------------------------------
+
+// CacheStore is a Store that writes the sanitised config of each stream to a
+// cache resource. Since caches cannot be iterated the ids of all stored streams
+// are kept as a JSON array under an index key.
+type CacheStore struct {
+	cache  types.Cache
+	prefix string
+	mut    sync.Mutex
+}
+
+// NewCacheStore creates a CacheStore for a cache, where all keys written are
+// prefixed with prefix.
+func NewCacheStore(cache types.Cache, prefix string) *CacheStore {
+	return &CacheStore{
+		cache:  cache,
+		prefix: prefix,
+	}
+}
+
+func (c *CacheStore) indexKey() string {
+	return c.prefix + "index"
+}
+
+func (c *CacheStore) streamKey(id string) string {
+	return c.prefix + "stream:" + id
+}
+
+// readIndex returns the ids of all stored streams.
+func (c *CacheStore) readIndex() ([]string, error) {
+	indexBytes, err := c.cache.Get(c.indexKey())
+	if err == types.ErrKeyNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	if err = json.Unmarshal(indexBytes, &ids); err != nil {
+		return nil, fmt.Errorf("failed to parse stream index: %v", err)
+	}
+	return ids, nil
+}
+
+func (c *CacheStore) writeIndex(ids []string) error {
+	indexBytes, err := json.Marshal(ids)
+	if err != nil {
+		return err
+	}
+	return c.cache.Set(c.indexKey(), indexBytes)
+}
+
+// Set writes the config of a stream to the cache and adds its id to the index.
+func (c *CacheStore) Set(id string, conf stream.Config) error {
+	confBytes, err := marshalStreamConfig(conf)
+	if err != nil {
+		return err
+	}
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if err = c.cache.Set(c.streamKey(id), confBytes); err != nil {
+		return err
+	}
+
+	var ids []string
+	if ids, err = c.readIndex(); err != nil {
+		return err
+	}
+	for _, existing := range ids {
+		if existing == id {
+			return nil
+		}
+	}
+	return c.writeIndex(append(ids, id))
+}
+
+// Delete removes the config of a stream from the cache and its id from the
+// index.
+func (c *CacheStore) Delete(id string) error {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	ids, err := c.readIndex()
+	if err != nil {
+		return err
+	}
+	newIDs := make([]string, 0, len(ids))
+	for _, existing := range ids {
+		if existing != id {
+			newIDs = append(newIDs, existing)
+		}
+	}
+	if len(newIDs) != len(ids) {
+		if err = c.writeIndex(newIDs); err != nil {
+			return err
+		}
+	}
+	if err = c.cache.Delete(c.streamKey(id)); err != nil && err != types.ErrKeyNotFound {
+		return err
+	}
+	return nil
+}
+
+// ReadAll reads the config of each stream within the index from the cache.
+// Streams within the index that are missing from the cache are ignored.
+func (c *CacheStore) ReadAll() (map[string]stream.Config, error) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	ids, err := c.readIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	confs := map[string]stream.Config{}
+	for _, id := range ids {
+		confBytes, err := c.cache.Get(c.streamKey(id))
+		if err == types.ErrKeyNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if confs[id], err = unmarshalStreamConfig(confBytes); err != nil {
+			return nil, fmt.Errorf("failed to parse stream '%v': %v", id, err)
+		}
+	}
+	return confs, nil
+}
+
+//------------------------------------------------------------------------------
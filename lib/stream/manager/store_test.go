@@ -0,0 +1,258 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package manager
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/cache"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/stream"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+func testStore(t *testing.T, store Store) {
+	fooConf := harmlessConf()
+	fooConf.Buffer.Type = "memory"
+	barConf := harmlessConf()
+	barConf.Input.HTTPServer.Path = "/bar/post"
+
+	ids := []string{"foo", "bar/baz qux"}
+	confs := []stream.Config{fooConf, barConf}
+
+	for i, id := range ids {
+		if err := store.Set(id, confs[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	actConfs, err := store.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := len(ids), len(actConfs); exp != act {
+		t.Fatalf("Wrong count of stored streams: %v != %v", act, exp)
+	}
+	for i, id := range ids {
+		actConf, exists := actConfs[id]
+		if !exists {
+			t.Errorf("Stream '%v' not stored", id)
+			continue
+		}
+		exp, _ := confs[i].Sanitised()
+		act, _ := actConf.Sanitised()
+		if !reflect.DeepEqual(exp, act) {
+			t.Errorf("Wrong config for stream '%v': %v != %v", id, act, exp)
+		}
+	}
+
+	if err = store.Delete("foo"); err != nil {
+		t.Fatal(err)
+	}
+	if err = store.Delete("foo"); err != nil {
+		t.Error(err)
+	}
+	if actConfs, err = store.ReadAll(); err != nil {
+		t.Fatal(err)
+	}
+	if _, exists := actConfs["foo"]; exists {
+		t.Error("Deleted stream still stored")
+	}
+	if _, exists := actConfs["bar/baz qux"]; !exists {
+		t.Error("Stream missing from store")
+	}
+}
+
+func TestDirectoryStore(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "streams_store_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(testDir)
+
+	store, err := NewDirectoryStore(testDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStore(t, store)
+}
+
+func TestCacheStore(t *testing.T) {
+	memCache, err := cache.NewMemory(
+		cache.NewConfig(), types.DudMgr{},
+		log.New(os.Stdout, log.Config{LogLevel: "NONE"}),
+		metrics.DudType{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStore(t, NewCacheStore(memCache, "benthos_streams_"))
+}
+
+func TestTypeStoreRestore(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "streams_store_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(testDir)
+
+	store, err := NewDirectoryStore(testDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mgr := New(
+		OptSetLogger(log.New(os.Stdout, log.Config{LogLevel: "NONE"})),
+		OptSetStats(metrics.DudType{}),
+		OptSetManager(types.DudMgr{}),
+		OptSetStore(store),
+	)
+
+	fooConf := harmlessConf()
+	barConf := harmlessConf()
+	barConf.Buffer.Type = "memory"
+
+	if err = mgr.Create("foo", fooConf); err != nil {
+		t.Fatal(err)
+	}
+	if err = mgr.Create("bar", harmlessConf()); err != nil {
+		t.Fatal(err)
+	}
+	if err = mgr.Update("bar", barConf, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err = mgr.Create("baz", harmlessConf()); err != nil {
+		t.Fatal(err)
+	}
+	if err = mgr.Delete("baz", time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err = mgr.Stop(time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	mgr = New(
+		OptSetLogger(log.New(os.Stdout, log.Config{LogLevel: "NONE"})),
+		OptSetStats(metrics.DudType{}),
+		OptSetManager(types.DudMgr{}),
+		OptSetStore(store),
+	)
+	defer mgr.Stop(time.Second)
+
+	// Streams that already exist are not replaced by those stored.
+	if err = mgr.Create("foo", fooConf); err != nil {
+		t.Fatal(err)
+	}
+
+	var restored int
+	if restored, err = mgr.Restore(); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := 1, restored; exp != act {
+		t.Errorf("Wrong count of restored streams: %v != %v", act, exp)
+	}
+
+	if _, err = mgr.Read("baz"); err != ErrStreamDoesNotExist {
+		t.Errorf("Wrong error returned: %v != %v", err, ErrStreamDoesNotExist)
+	}
+	info, err := mgr.Read("bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp, _ := barConf.Sanitised()
+	act, _ := info.Config().Sanitised()
+	if !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong restored config: %v != %v", act, exp)
+	}
+}
+
+func TestTypeStoreSkipsDirectoryAndFailedUpdates(t *testing.T) {
+	storeDir, err := ioutil.TempDir("", "streams_store_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(storeDir)
+
+	streamsDir, err := ioutil.TempDir("", "streams_store_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(streamsDir)
+
+	if err = ioutil.WriteFile(
+		filepath.Join(streamsDir, "bar.yaml"),
+		[]byte("input:\n  type: http_server\noutput:\n  type: http_server\n"),
+		0666,
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := NewDirectoryStore(storeDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mgr := New(
+		OptSetLogger(log.New(os.Stdout, log.Config{LogLevel: "NONE"})),
+		OptSetStats(metrics.DudType{}),
+		OptSetManager(types.DudMgr{}),
+		OptSetStore(store),
+	)
+	defer mgr.Stop(time.Second)
+
+	created, err := mgr.CreateFromDirectory(false, streamsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := 1, created; exp != act {
+		t.Errorf("Wrong count of created streams: %v != %v", act, exp)
+	}
+
+	fooConf := harmlessConf()
+	if err = mgr.Create("foo", fooConf); err != nil {
+		t.Fatal(err)
+	}
+
+	badConf := harmlessConf()
+	badConf.Input.Type = "does_not_exist"
+	if err = mgr.Update("foo", badConf, time.Second); err == nil {
+		t.Error("Expected error from bad update")
+	}
+
+	confs, err := store.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, exists := confs["bar"]; exists {
+		t.Error("Stream created from a directory was persisted")
+	}
+	exp, _ := fooConf.Sanitised()
+	act, _ := confs["foo"].Sanitised()
+	if !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong stored config after failed update: %v != %v", act, exp)
+	}
+}
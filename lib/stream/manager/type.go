@@ -27,6 +27,7 @@ import (
 	"os"
 	"path"
 	"reflect"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -168,6 +169,7 @@ type Type struct {
 	stats      metrics.Type
 	logger     log.Modular
 	apiTimeout time.Duration
+	store      Store
 
 	inputPipeCtors    []StreamPipeConstructorFunc
 	pipelineProcCtors []StreamProcConstructorFunc
@@ -225,6 +227,15 @@ func OptSetAPITimeout(tout time.Duration) func(*Type) {
 	}
 }
 
+// OptSetStore sets a persistence backend that the config of each stream is
+// written to when the stream is created or updated, and removed from when the
+// stream is deleted. Stored streams can be restored with Restore.
+func OptSetStore(store Store) func(*Type) {
+	return func(t *Type) {
+		t.store = store
+	}
+}
+
 // OptAddInputPipelines adds pipeline constructors that will be called for every
 // new stream and attached to the input component. The constructor is given the
 // name of the stream as an argument.
@@ -262,9 +273,39 @@ var (
 
 //------------------------------------------------------------------------------
 
+// persist writes the config of a stream to the store, if one is set. Failing to
+// persist a stream does not affect the running stream and is therefore logged
+// rather than returned. Must be called with m.lock held so that writes to the
+// store are made in the same order as changes to the streams.
+func (m *Type) persist(id string, conf stream.Config) {
+	if m.store == nil {
+		return
+	}
+	if err := m.store.Set(id, conf); err != nil {
+		m.logger.Errorf("Failed to persist stream '%v': %v\n", id, err)
+	}
+}
+
+// unpersist removes the config of a stream from the store, if one is set. Must
+// be called with m.lock held.
+func (m *Type) unpersist(id string) {
+	if m.store == nil {
+		return
+	}
+	if err := m.store.Delete(id); err != nil {
+		m.logger.Errorf("Failed to remove persisted stream '%v': %v\n", id, err)
+	}
+}
+
 // Create attempts to construct and run a new stream under a unique ID. If the
 // ID already exists an error is returned.
 func (m *Type) Create(id string, conf stream.Config) error {
+	return m.create(id, conf, true)
+}
+
+// create constructs and runs a new stream, and writes its config to the store
+// if persist is true.
+func (m *Type) create(id string, conf stream.Config, persist bool) error {
 	m.lock.Lock()
 	defer m.lock.Unlock()
 
@@ -324,6 +365,9 @@ func (m *Type) Create(id string, conf stream.Config) error {
 
 	wrapper = NewStreamStatus(conf, strm, strmLogger, strmFlatMetrics)
 	m.streams[id] = wrapper
+	if persist {
+		m.persist(id, conf)
+	}
 	return nil
 }
 
@@ -346,8 +390,15 @@ func (m *Type) Read(id string) (*StreamStatus, error) {
 }
 
 // Update attempts to stop an existing stream and replace it with a new version
-// of the same stream.
+// of the same stream. If the new version fails to be created the stream is
+// left deleted, but its previous config is kept within the store.
 func (m *Type) Update(id string, conf stream.Config, timeout time.Duration) error {
+	return m.update(id, conf, timeout, true)
+}
+
+// update replaces a stream with a new version, and writes the new config to the
+// store if persist is true.
+func (m *Type) update(id string, conf stream.Config, timeout time.Duration, persist bool) error {
 	m.lock.Lock()
 	wrapper, exists := m.streams[id]
 	closed := m.closed
@@ -364,16 +415,22 @@ func (m *Type) Update(id string, conf stream.Config, timeout time.Duration) erro
 		return nil
 	}
 
-	if err := m.Delete(id, timeout); err != nil {
+	if err := m.delete(id, timeout, false); err != nil {
 		return err
 	}
-	return m.Create(id, conf)
+	return m.create(id, conf, persist)
 }
 
 // Delete attempts to stop and remove a stream by its ID. Returns an error if
 // the stream was not found, or if clean shutdown fails in the specified period
 // of time.
 func (m *Type) Delete(id string, timeout time.Duration) error {
+	return m.delete(id, timeout, true)
+}
+
+// delete stops and removes a stream, and removes its config from the store if
+// persist is true.
+func (m *Type) delete(id string, timeout time.Duration, persist bool) error {
 	m.lock.Lock()
 	if m.closed {
 		m.lock.Unlock()
@@ -392,11 +449,53 @@ func (m *Type) Delete(id string, timeout time.Duration) error {
 
 	m.lock.Lock()
 	delete(m.streams, id)
+	if persist {
+		m.unpersist(id)
+	}
 	m.lock.Unlock()
 
 	return nil
 }
 
+// Restore creates a stream for each config within the store set with
+// OptSetStore. Stored streams with an id that already exists are skipped. The
+// number of streams created is returned, along with an error if the store could
+// not be read or any stream failed to be created.
+func (m *Type) Restore() (int, error) {
+	if m.store == nil {
+		return 0, nil
+	}
+	confs, err := m.store.ReadAll()
+	if err != nil {
+		return 0, err
+	}
+
+	ids := make([]string, 0, len(confs))
+	for id := range confs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	restored := 0
+	failed := []string{}
+	for _, id := range ids {
+		if err = m.create(id, confs[id], false); err != nil {
+			if err == ErrStreamExists {
+				m.logger.Infof("Skipping restore of stream '%v' as it already exists\n", id)
+				continue
+			}
+			m.logger.Errorf("Failed to restore stream '%v': %v\n", id, err)
+			failed = append(failed, id)
+			continue
+		}
+		restored++
+	}
+	if len(failed) > 0 {
+		return restored, fmt.Errorf("failed to restore the following streams: %v", failed)
+	}
+	return restored, nil
+}
+
 //------------------------------------------------------------------------------
 
 // Stop attempts to gracefully shut down all active streams and close the
@@ -0,0 +1,209 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package manager
+
+import (
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/watch"
+)
+
+//------------------------------------------------------------------------------
+
+// DirectoryWatcher watches a directory of stream config files, following the
+// same layout as LoadStreamConfigsFromDirectory, and applies any changes made
+// to them to a stream manager. Files that are created result in new streams,
+// files that are modified result in their streams being updated, and files that
+// are removed result in their streams being deleted.
+//
+// A file that fails to parse, or whose config fails a dry run, is logged and
+// ignored, leaving any existing stream of the same id running. If a stream
+// fails to be created from an updated file regardless then the stream is
+// restored with its previous config.
+//
+// Changes applied from files are not written to the store set with
+// OptSetStore, as the configs of these streams are kept within the directory.
+type DirectoryWatcher struct {
+	running        int32
+	mgr            *Type
+	dir            string
+	replaceEnvVars bool
+	period         time.Duration
+	timeout        time.Duration
+
+	watcher    *watch.Dir
+	closeChan  chan struct{}
+	closedChan chan struct{}
+}
+
+// NewDirectoryWatcher creates a DirectoryWatcher that applies changes made to
+// the stream config files within dir to a stream manager. Files that exist when
+// the watcher is created are not applied, and should be loaded with
+// CreateFromDirectory afterwards, which ensures that no change is missed
+// between loading the files and watching them.
+func NewDirectoryWatcher(
+	mgr *Type,
+	replaceEnvVars bool,
+	dir string,
+	opts ...func(*DirectoryWatcher),
+) *DirectoryWatcher {
+	d := &DirectoryWatcher{
+		running:        1,
+		mgr:            mgr,
+		dir:            filepath.Clean(dir),
+		replaceEnvVars: replaceEnvVars,
+		period:         time.Second,
+		timeout:        mgr.apiTimeout,
+		closeChan:      make(chan struct{}),
+		closedChan:     make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	d.watcher = watch.NewDir(
+		d.dir,
+		watch.OptDirSetPeriod(d.period),
+		watch.OptDirSetFilter(isStreamFile),
+	)
+	go d.loop()
+	return d
+}
+
+//------------------------------------------------------------------------------
+
+// OptWatcherSetPeriod sets the period between each poll of the directory. A
+// change to a file is applied once the file has remained the same for a full
+// period, and therefore this also determines how changes are debounced.
+func OptWatcherSetPeriod(period time.Duration) func(*DirectoryWatcher) {
+	return func(d *DirectoryWatcher) {
+		d.period = period
+	}
+}
+
+// OptWatcherSetTimeout sets the timeout for stopping streams when they are
+// updated or deleted.
+func OptWatcherSetTimeout(tout time.Duration) func(*DirectoryWatcher) {
+	return func(d *DirectoryWatcher) {
+		d.timeout = tout
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func (d *DirectoryWatcher) apply(event watch.Event) {
+	id := streamIDFromPath(d.dir, event.Path)
+	logger := d.mgr.logger
+
+	if event.Op == watch.OpRemove {
+		if err := d.mgr.delete(id, d.timeout, false); err != nil && err != ErrStreamDoesNotExist {
+			logger.Errorf("Failed to delete stream '%v' after its file was removed: %v\n", id, err)
+			return
+		}
+		logger.Infof("Deleted stream '%v' as its file was removed: %v\n", id, event.Path)
+		return
+	}
+
+	conf, err := readStreamFile(d.replaceEnvVars, event.Path)
+	if err == nil {
+		_, err = conf.Sanitised()
+	}
+	if err != nil {
+		logger.Errorf("Failed to parse stream '%v' file, leaving the stream unchanged: %v\n", id, err)
+		return
+	}
+	if errs := d.mgr.DryRun(id, conf); len(errs) > 0 {
+		for _, err = range errs {
+			logger.Errorf("Invalid stream '%v' file, leaving the stream unchanged: %v\n", id, err)
+		}
+		return
+	}
+
+	prev, err := d.mgr.Read(id)
+	if err == ErrStreamDoesNotExist {
+		if err = d.mgr.create(id, conf, false); err == nil {
+			logger.Infof("Created stream '%v' from file: %v\n", id, event.Path)
+			return
+		}
+		if err != ErrStreamExists {
+			logger.Errorf("Failed to create stream '%v' from file: %v\n", id, err)
+			return
+		}
+		// The stream has been created from the directory in the meantime, in
+		// which case it is updated instead.
+		if prev, err = d.mgr.Read(id); err != nil {
+			logger.Errorf("Failed to update stream '%v' from file: %v\n", id, err)
+			return
+		}
+	}
+	if err = d.mgr.update(id, conf, d.timeout, false); err != nil {
+		logger.Errorf("Failed to update stream '%v' from file: %v\n", id, err)
+
+		// An update that fails to create the new stream leaves the stream
+		// deleted, in which case the previous config is restored.
+		if _, err = d.mgr.Read(id); err == ErrStreamDoesNotExist {
+			if err = d.mgr.create(id, prev.Config(), false); err != nil {
+				logger.Errorf("Failed to restore stream '%v': %v\n", id, err)
+			} else {
+				logger.Infof("Restored stream '%v' with its previous config\n", id)
+			}
+		}
+		return
+	}
+	logger.Infof("Updated stream '%v' from file: %v\n", id, event.Path)
+}
+
+func (d *DirectoryWatcher) loop() {
+	defer close(d.closedChan)
+	for {
+		select {
+		case event := <-d.watcher.EventChan():
+			d.apply(event)
+		case <-d.closeChan:
+			return
+		}
+	}
+}
+
+// CloseAsync stops watching the directory. Streams that have already been
+// created are not affected.
+func (d *DirectoryWatcher) CloseAsync() {
+	if atomic.CompareAndSwapInt32(&d.running, 1, 0) {
+		d.watcher.CloseAsync()
+		close(d.closeChan)
+	}
+}
+
+// WaitForClose blocks until the watcher has stopped, including any change that
+// is currently being applied.
+func (d *DirectoryWatcher) WaitForClose(timeout time.Duration) error {
+	started := time.Now()
+	select {
+	case <-d.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return d.watcher.WaitForClose(timeout - time.Since(started))
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,169 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package manager
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/stream"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestDirectoryWatcher(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "streams_watch_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(testDir)
+
+	mgr := New()
+	watcher := NewDirectoryWatcher(
+		mgr, false, testDir, OptWatcherSetPeriod(time.Millisecond*10),
+	)
+	defer func() {
+		watcher.CloseAsync()
+		if err := watcher.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+		if err := mgr.Stop(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	writeConf := func(path string, conf stream.Config) {
+		confBytes, err := yaml.Marshal(conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = ioutil.WriteFile(path, confBytes, 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	awaitStream := func(id string, check func(*StreamStatus, error) bool) {
+		for i := 0; i < 100; i++ {
+			if check(mgr.Read(id)) {
+				return
+			}
+			<-time.After(time.Millisecond * 10)
+		}
+		t.Fatalf("Timed out waiting for stream '%v'", id)
+	}
+
+	fooPath := filepath.Join(testDir, "foo.yaml")
+	fooConf := harmlessConf()
+	writeConf(fooPath, fooConf)
+
+	awaitStream("foo", func(s *StreamStatus, err error) bool {
+		return err == nil
+	})
+
+	fooConf.Input.HTTPServer.Path = "/foo/post"
+	writeConf(fooPath, fooConf)
+
+	awaitStream("foo", func(s *StreamStatus, err error) bool {
+		return err == nil && s.Config().Input.HTTPServer.Path == "/foo/post"
+	})
+
+	fooStatus, err := mgr.Read("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A file that cannot be parsed must not affect the running stream.
+	if err = ioutil.WriteFile(fooPath, []byte("input: [ not valid"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	badConf := harmlessConf()
+	badConf.Input.Type = "does_not_exist"
+	badPath := filepath.Join(testDir, "bad.yaml")
+	writeConf(badPath, badConf)
+
+	<-time.After(time.Millisecond * 100)
+
+	// Neither must a file with a config that fails a dry run, which must not
+	// stop the running stream.
+	badConf.Input.HTTPServer.Path = "/bad/post"
+	writeConf(fooPath, badConf)
+
+	<-time.After(time.Millisecond * 100)
+	awaitStream("foo", func(s *StreamStatus, err error) bool {
+		return err == nil && s.Config().Input.HTTPServer.Path == "/foo/post"
+	})
+	if s, _ := mgr.Read("foo"); s != fooStatus {
+		t.Error("Expected stream to keep running without being replaced")
+	}
+	if _, err = mgr.Read("bad"); err != ErrStreamDoesNotExist {
+		t.Errorf("Wrong error returned: %v != %v", err, ErrStreamDoesNotExist)
+	}
+
+	if err = os.Remove(fooPath); err != nil {
+		t.Fatal(err)
+	}
+
+	awaitStream("foo", func(s *StreamStatus, err error) bool {
+		return err == ErrStreamDoesNotExist
+	})
+}
+
+func TestDirectoryWatcherBeforeLoad(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "streams_watch_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(testDir)
+
+	mgr := New()
+	watcher := NewDirectoryWatcher(
+		mgr, false, testDir, OptWatcherSetPeriod(time.Millisecond),
+	)
+	defer func() {
+		watcher.CloseAsync()
+		if err := watcher.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+		if err := mgr.Stop(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	// A file written between watching and loading the directory is picked up
+	// by both, which must not fail either.
+	confBytes, err := yaml.Marshal(harmlessConf())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(filepath.Join(testDir, "foo.yaml"), confBytes, 0666); err != nil {
+		t.Fatal(err)
+	}
+	<-time.After(time.Millisecond * 5)
+
+	if _, err = mgr.CreateFromDirectory(false, testDir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = mgr.Read("foo"); err != nil {
+		t.Error(err)
+	}
+}
@@ -22,6 +22,7 @@ package stream
 
 import (
 	"bytes"
+	"fmt"
 	"runtime/pprof"
 	"time"
 
@@ -31,6 +32,7 @@ import (
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/output"
 	"github.com/Jeffail/benthos/lib/pipeline"
+	"github.com/Jeffail/benthos/lib/processor"
 	"github.com/Jeffail/benthos/lib/types"
 )
 
@@ -45,6 +47,10 @@ type Type struct {
 	pipelineLayer pipeline.Type
 	outputLayer   output.Type
 
+	deadLetterPipe  types.Pipeline
+	deadLetterLayer output.Type
+	deadLetterChan  chan types.Transaction
+
 	complementaryInputPipes  []types.PipelineConstructorFunc
 	complementaryProcs       []types.ProcessorConstructorFunc
 	complementaryOutputPipes []types.PipelineConstructorFunc
@@ -133,8 +139,66 @@ func OptOnClose(onClose func()) func(*Type) {
 
 //------------------------------------------------------------------------------
 
+// newDeadLetterPipe returns a constructor for a dead letter pipeline that
+// protects a named component of the stream, with a thread for each parallel
+// consumer of that component.
+func (t *Type) newDeadLetterPipe(component string, threads int) types.PipelineConstructorFunc {
+	return func() (types.Pipeline, error) {
+		return pipeline.NewDeadLetter(
+			component,
+			t.conf.DeadLetter.MaxRetries,
+			time.Duration(t.conf.DeadLetter.RetryPeriodMS)*time.Millisecond,
+			threads,
+			t.deadLetterChan,
+			t.logger, t.stats,
+		), nil
+	}
+}
+
+// newOutputProcsPipe returns a constructor for a pipeline that executes the
+// processors of an output config.
+func (t *Type) newOutputProcsPipe(conf output.Config) types.PipelineConstructorFunc {
+	return func() (types.Pipeline, error) {
+		processors := make([]types.Processor, len(conf.Processors))
+		for i, procConf := range conf.Processors {
+			var err error
+			processors[i], err = processor.New(procConf, t.manager, t.logger.NewModule("."+conf.Type), t.stats)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create processor '%v': %v", procConf.Type, err)
+			}
+		}
+		return pipeline.NewProcessor(t.logger, t.stats, processors...), nil
+	}
+}
+
 func (t *Type) start() (err error) {
 	// Constructors
+	outConf := t.conf.Output
+	outPipes := t.complementaryOutputPipes
+	if t.conf.DeadLetter.Enabled {
+		if t.deadLetterLayer, err = output.New(
+			t.conf.DeadLetter.Output, t.manager,
+			t.logger.NewModule(".dead_letter"),
+			metrics.Namespaced(t.stats, "dead_letter"),
+		); err != nil {
+			return
+		}
+		t.deadLetterChan = make(chan types.Transaction)
+		if err = t.deadLetterLayer.Consume(t.deadLetterChan); err != nil {
+			return
+		}
+
+		// Output processors would retry failed writes indefinitely, therefore
+		// they are placed in front of the dead letter pipeline rather than
+		// behind it.
+		outPipes = append([]types.PipelineConstructorFunc{}, outPipes...)
+		if len(outConf.Processors) > 0 {
+			outPipes = append(outPipes, t.newOutputProcsPipe(outConf))
+			outConf.Processors = nil
+		}
+		outPipes = append(outPipes, t.newDeadLetterPipe("output", 1))
+	}
+
 	if t.inputLayer, err = input.New(
 		t.conf.Input, t.manager, t.logger, t.stats, t.complementaryInputPipes...,
 	); err != nil {
@@ -153,9 +217,14 @@ func (t *Type) start() (err error) {
 		); err != nil {
 			return
 		}
+		if t.deadLetterLayer != nil {
+			if t.deadLetterPipe, err = t.newDeadLetterPipe("pipeline", t.conf.Pipeline.Threads)(); err != nil {
+				return
+			}
+		}
 	}
 	if t.outputLayer, err = output.New(
-		t.conf.Output, t.manager, t.logger, t.stats, t.complementaryOutputPipes...,
+		outConf, t.manager, t.logger, t.stats, outPipes...,
 	); err != nil {
 		return
 	}
@@ -170,6 +239,12 @@ func (t *Type) start() (err error) {
 		}
 		nextTranChan = t.bufferLayer.TransactionChan()
 	}
+	if t.deadLetterPipe != nil {
+		if err = t.deadLetterPipe.Consume(nextTranChan); err != nil {
+			return
+		}
+		nextTranChan = t.deadLetterPipe.TransactionChan()
+	}
 	if t.pipelineLayer != nil {
 		if err = t.pipelineLayer.Consume(nextTranChan); err != nil {
 			return
@@ -219,6 +294,17 @@ func (t *Type) stopGracefully(timeout time.Duration) (err error) {
 	}
 
 	// After this point we can start closing the remaining components.
+	if t.deadLetterPipe != nil {
+		t.deadLetterPipe.CloseAsync()
+		remaining = timeout - time.Since(started)
+		if remaining < 0 {
+			return types.ErrTimeout
+		}
+		if err = t.deadLetterPipe.WaitForClose(remaining); err != nil {
+			return
+		}
+	}
+
 	if t.pipelineLayer != nil {
 		t.pipelineLayer.CloseAsync()
 		remaining = timeout - time.Since(started)
@@ -239,6 +325,17 @@ func (t *Type) stopGracefully(timeout time.Duration) (err error) {
 		return
 	}
 
+	if t.deadLetterLayer != nil {
+		t.deadLetterLayer.CloseAsync()
+		remaining = timeout - time.Since(started)
+		if remaining < 0 {
+			return types.ErrTimeout
+		}
+		if err = t.deadLetterLayer.WaitForClose(remaining); err != nil {
+			return
+		}
+	}
+
 	return nil
 }
 
@@ -266,6 +363,17 @@ func (t *Type) stopOrdered(timeout time.Duration) (err error) {
 		}
 	}
 
+	if t.deadLetterPipe != nil {
+		t.deadLetterPipe.CloseAsync()
+		remaining = timeout - time.Since(started)
+		if remaining < 0 {
+			return types.ErrTimeout
+		}
+		if err = t.deadLetterPipe.WaitForClose(remaining); err != nil {
+			return
+		}
+	}
+
 	if t.pipelineLayer != nil {
 		t.pipelineLayer.CloseAsync()
 		remaining = timeout - time.Since(started)
@@ -286,6 +394,17 @@ func (t *Type) stopOrdered(timeout time.Duration) (err error) {
 		return
 	}
 
+	if t.deadLetterLayer != nil {
+		t.deadLetterLayer.CloseAsync()
+		remaining = timeout - time.Since(started)
+		if remaining < 0 {
+			return types.ErrTimeout
+		}
+		if err = t.deadLetterLayer.WaitForClose(remaining); err != nil {
+			return
+		}
+	}
+
 	return nil
 }
 
@@ -297,10 +416,16 @@ func (t *Type) stopUnordered(timeout time.Duration) (err error) {
 	if t.bufferLayer != nil {
 		t.bufferLayer.CloseAsync()
 	}
+	if t.deadLetterPipe != nil {
+		t.deadLetterPipe.CloseAsync()
+	}
 	if t.pipelineLayer != nil {
 		t.pipelineLayer.CloseAsync()
 	}
 	t.outputLayer.CloseAsync()
+	if t.deadLetterLayer != nil {
+		t.deadLetterLayer.CloseAsync()
+	}
 
 	started := time.Now()
 	if err = t.inputLayer.WaitForClose(timeout); err != nil {
@@ -319,6 +444,16 @@ func (t *Type) stopUnordered(timeout time.Duration) (err error) {
 		}
 	}
 
+	if t.deadLetterPipe != nil {
+		remaining = timeout - time.Since(started)
+		if remaining < 0 {
+			return types.ErrTimeout
+		}
+		if err = t.deadLetterPipe.WaitForClose(remaining); err != nil {
+			return
+		}
+	}
+
 	if t.pipelineLayer != nil {
 		remaining = timeout - time.Since(started)
 		if remaining < 0 {
@@ -337,6 +472,16 @@ func (t *Type) stopUnordered(timeout time.Duration) (err error) {
 		return
 	}
 
+	if t.deadLetterLayer != nil {
+		remaining = timeout - time.Since(started)
+		if remaining < 0 {
+			return types.ErrTimeout
+		}
+		if err = t.deadLetterLayer.WaitForClose(remaining); err != nil {
+			return
+		}
+	}
+
 	return nil
 }
 
@@ -120,6 +120,17 @@ func TestTypeCloseGracefully(t *testing.T) {
 		t.Fatal(err)
 	}
 
+	if err = strm.stopGracefully(time.Second); err != nil {
+		t.Error(err)
+	}
+	conf.DeadLetter.Enabled = true
+	conf.DeadLetter.Output.Type = output.TypeSTDOUT
+
+	strm, err = New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
 	if err = strm.stopGracefully(time.Second); err != nil {
 		t.Error(err)
 	}
@@ -159,6 +170,17 @@ func TestTypeCloseOrdered(t *testing.T) {
 		t.Fatal(err)
 	}
 
+	if err = strm.stopOrdered(time.Second); err != nil {
+		t.Error(err)
+	}
+	conf.DeadLetter.Enabled = true
+	conf.DeadLetter.Output.Type = output.TypeSTDOUT
+
+	strm, err = New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
 	if err = strm.stopOrdered(time.Second); err != nil {
 		t.Error(err)
 	}
@@ -198,6 +220,17 @@ func TestTypeCloseUnordered(t *testing.T) {
 		t.Fatal(err)
 	}
 
+	if err = strm.stopUnordered(time.Second); err != nil {
+		t.Error(err)
+	}
+	conf.DeadLetter.Enabled = true
+	conf.DeadLetter.Output.Type = output.TypeSTDOUT
+
+	strm, err = New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
 	if err = strm.stopUnordered(time.Second); err != nil {
 		t.Error(err)
 	}
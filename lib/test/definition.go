@@ -0,0 +1,378 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/manager"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/processor"
+	"github.com/Jeffail/benthos/lib/processor/condition"
+	"github.com/Jeffail/benthos/lib/types"
+	yaml "gopkg.in/yaml.v2"
+)
+
+//------------------------------------------------------------------------------
+
+// Definition contains a list of test cases for the sections of a config.
+type Definition struct {
+	Tests []Case `json:"tests" yaml:"tests"`
+}
+
+// Case is a single test, where an input message is sent through a target
+// section of a config and the result is checked against expectations. Either
+// TargetProcessors or TargetCondition must be set to a dot separated path
+// within the config, such as `pipeline.processors` or
+// `resources.conditions.foo`. TargetProcessors can point to either a list of
+// processors or a single processor.
+type Case struct {
+	Name             string          `json:"name" yaml:"name"`
+	TargetProcessors string          `json:"target_processors" yaml:"target_processors"`
+	TargetCondition  string          `json:"target_condition" yaml:"target_condition"`
+	Input            InputMessage    `json:"input" yaml:"input"`
+	Output           []OutputMessage `json:"output" yaml:"output"`
+	ConditionResult  *bool           `json:"condition_result" yaml:"condition_result"`
+}
+
+// InputMessage describes a message to be sent through a test target.
+type InputMessage struct {
+	Metadata map[string]string `json:"metadata" yaml:"metadata"`
+	Parts    []string          `json:"parts" yaml:"parts"`
+}
+
+// OutputMessage describes the expectations of a message resulting from a
+// processors test target.
+type OutputMessage struct {
+	MetadataEquals map[string]string `json:"metadata_equals" yaml:"metadata_equals"`
+	Parts          []OutputPart      `json:"parts" yaml:"parts"`
+}
+
+// OutputPart describes the expectations of a message part resulting from a
+// processors test target, any fields that are not set are not checked.
+type OutputPart struct {
+	ContentEquals *string           `json:"content_equals" yaml:"content_equals"`
+	JSONEquals    interface{}       `json:"json_equals" yaml:"json_equals"`
+	Condition     *condition.Config `json:"condition" yaml:"condition"`
+}
+
+//------------------------------------------------------------------------------
+
+// Failure describes a single failed expectation of a test case.
+type Failure struct {
+	Name   string
+	Reason string
+}
+
+func (f Failure) String() string {
+	return fmt.Sprintf("%v: %v", f.Name, f.Reason)
+}
+
+//------------------------------------------------------------------------------
+
+// getPath walks a generic config document along a dot separated path, where
+// numeric segments are indexes of arrays.
+func getPath(root interface{}, path string) (interface{}, error) {
+	current := root
+	for _, seg := range strings.Split(path, ".") {
+		switch t := current.(type) {
+		case map[interface{}]interface{}:
+			var exists bool
+			if current, exists = t[seg]; !exists {
+				return nil, fmt.Errorf("path '%v' not found in config", path)
+			}
+		case []interface{}:
+			i, err := strconv.Atoi(seg)
+			if err != nil || i < 0 || i >= len(t) {
+				return nil, fmt.Errorf("path '%v' not found in config", path)
+			}
+			current = t[i]
+		default:
+			return nil, fmt.Errorf("path '%v' not found in config", path)
+		}
+	}
+	return current, nil
+}
+
+// parseSection extracts a section of a generic config document and parses it
+// into a target config struct.
+func parseSection(root interface{}, path string, target interface{}) error {
+	section, err := getPath(root, path)
+	if err != nil {
+		return err
+	}
+	return parseValue(section, target)
+}
+
+// parseValue parses a generic config value into a target config struct.
+func parseValue(section interface{}, target interface{}) error {
+	sectionBytes, err := yaml.Marshal(section)
+	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(sectionBytes, target)
+}
+
+// toJSONValue converts a generic YAML value into a value that matches the
+// result of parsing the same structure as JSON.
+func toJSONValue(v interface{}) (interface{}, error) {
+	var convert func(v interface{}) interface{}
+	convert = func(v interface{}) interface{} {
+		switch t := v.(type) {
+		case map[interface{}]interface{}:
+			newMap := map[string]interface{}{}
+			for k, v := range t {
+				newMap[fmt.Sprintf("%v", k)] = convert(v)
+			}
+			return newMap
+		case []interface{}:
+			newSlice := make([]interface{}, len(t))
+			for i, v := range t {
+				newSlice[i] = convert(v)
+			}
+			return newSlice
+		}
+		return v
+	}
+	jBytes, err := json.Marshal(convert(v))
+	if err != nil {
+		return nil, err
+	}
+	var jValue interface{}
+	err = json.Unmarshal(jBytes, &jValue)
+	return jValue, err
+}
+
+func formatJSON(v interface{}) string {
+	jBytes, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(jBytes)
+}
+
+//------------------------------------------------------------------------------
+
+// caseRunner executes test cases against a generic config document.
+type caseRunner struct {
+	root  interface{}
+	log   log.Modular
+	stats metrics.Type
+}
+
+func (r *caseRunner) newManager() (*mockManager, error) {
+	mgrConf := manager.NewConfig()
+	if _, err := getPath(r.root, "resources"); err == nil {
+		if err = parseSection(r.root, "resources", &mgrConf); err != nil {
+			return nil, fmt.Errorf("failed to parse resources: %v", err)
+		}
+	}
+	return newMockManager(mgrConf, r.log, r.stats)
+}
+
+func (r *caseRunner) newInput(c Case) types.Message {
+	var parts [][]byte
+	for _, p := range c.Input.Parts {
+		parts = append(parts, []byte(p))
+	}
+	msg := message.New(parts)
+
+	var keys []string
+	for k := range c.Input.Metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		msg.SetMetadata(k, c.Input.Metadata[k])
+	}
+	return msg
+}
+
+// Run executes a test case and returns any failures.
+func (r *caseRunner) Run(c Case) ([]Failure, error) {
+	mgr, err := r.newManager()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(c.TargetCondition) > 0 {
+		return r.runCondition(c, mgr)
+	}
+	if len(c.TargetProcessors) > 0 {
+		return r.runProcessors(c, mgr)
+	}
+	return nil, errors.New("a target_processors or target_condition must be set")
+}
+
+func (r *caseRunner) runCondition(c Case, mgr types.Manager) ([]Failure, error) {
+	conf := condition.NewConfig()
+	if err := parseSection(r.root, c.TargetCondition, &conf); err != nil {
+		return nil, fmt.Errorf("failed to parse target condition: %v", err)
+	}
+	cond, err := condition.New(conf, mgr, r.log, r.stats)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create target condition: %v", err)
+	}
+	if c.ConditionResult == nil {
+		return nil, errors.New("a condition_result must be set for condition targets")
+	}
+
+	if exp, act := *c.ConditionResult, cond.Check(r.newInput(c)); exp != act {
+		return []Failure{{
+			Name:   c.Name,
+			Reason: fmt.Sprintf("condition_result: expected %v, got %v", exp, act),
+		}}, nil
+	}
+	return nil, nil
+}
+
+func (r *caseRunner) runProcessors(c Case, mgr types.Manager) ([]Failure, error) {
+	section, err := getPath(r.root, c.TargetProcessors)
+	if err != nil {
+		return nil, err
+	}
+
+	// The target can either be a list of processors or a single processor.
+	if _, isMap := section.(map[interface{}]interface{}); isMap {
+		section = []interface{}{section}
+	}
+
+	var confs []processor.Config
+	if err = parseValue(section, &confs); err != nil {
+		return nil, fmt.Errorf("failed to parse target processors: %v", err)
+	}
+
+	var procs []processor.Type
+	for i, conf := range confs {
+		proc, pErr := processor.New(conf, mgr, r.log, r.stats)
+		if pErr != nil {
+			return nil, fmt.Errorf("failed to create target processor '%v': %v", i, pErr)
+		}
+		procs = append(procs, proc)
+	}
+
+	resultMsgs := []types.Message{r.newInput(c)}
+	for i := 0; len(resultMsgs) > 0 && i < len(procs); i++ {
+		var nextResultMsgs []types.Message
+		for _, m := range resultMsgs {
+			rMsgs, _ := procs[i].ProcessMessage(m)
+			nextResultMsgs = append(nextResultMsgs, rMsgs...)
+		}
+		resultMsgs = nextResultMsgs
+	}
+
+	var failures []Failure
+	fail := func(reason string, args ...interface{}) {
+		failures = append(failures, Failure{
+			Name:   c.Name,
+			Reason: fmt.Sprintf(reason, args...),
+		})
+	}
+
+	if exp, act := len(c.Output), len(resultMsgs); exp != act {
+		fail("expected %v resulting messages, got %v", exp, act)
+		return failures, nil
+	}
+
+	for i, exp := range c.Output {
+		msg := resultMsgs[i]
+
+		var keys []string
+		for k := range exp.MetadataEquals {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if expV, actV := exp.MetadataEquals[k], msg.GetMetadata(k); expV != actV {
+				fail("message %v metadata_equals %v: expected '%v', got '%v'", i, k, expV, actV)
+			}
+		}
+
+		if exp.Parts == nil {
+			continue
+		}
+		if expLen, actLen := len(exp.Parts), msg.Len(); expLen != actLen {
+			fail("message %v: expected %v parts, got %v", i, expLen, actLen)
+			continue
+		}
+
+		for j, expPart := range exp.Parts {
+			for _, reason := range r.checkPart(msg, j, expPart, mgr) {
+				fail("message %v part %v %v", i, j, reason)
+			}
+		}
+	}
+
+	return failures, nil
+}
+
+func (r *caseRunner) checkPart(msg types.Message, index int, exp OutputPart, mgr types.Manager) []string {
+	var reasons []string
+	content := msg.Get(index)
+
+	if exp.ContentEquals != nil && *exp.ContentEquals != string(content) {
+		reasons = append(reasons, "content_equals:\n"+diff(*exp.ContentEquals, string(content)))
+	}
+
+	if exp.JSONEquals != nil {
+		expJSON, err := toJSONValue(exp.JSONEquals)
+		if err != nil {
+			reasons = append(reasons, fmt.Sprintf("json_equals: failed to parse expected value: %v", err))
+		} else {
+			var actJSON interface{}
+			dec := json.NewDecoder(bytes.NewReader(content))
+			if err = dec.Decode(&actJSON); err != nil {
+				reasons = append(reasons, fmt.Sprintf("json_equals: failed to parse content as JSON: %v", err))
+			} else if !reflect.DeepEqual(expJSON, actJSON) {
+				reasons = append(reasons, "json_equals:\n"+diff(formatJSON(expJSON), formatJSON(actJSON)))
+			}
+		}
+	}
+
+	if exp.Condition != nil {
+		cond, err := condition.New(*exp.Condition, mgr, r.log, r.stats)
+		if err != nil {
+			reasons = append(reasons, fmt.Sprintf("condition: failed to create condition: %v", err))
+		} else {
+			partMsg := message.New([][]byte{content})
+			msg.IterMetadata(func(k, v string) error {
+				partMsg.SetMetadata(k, v)
+				return nil
+			})
+			if !cond.Check(partMsg) {
+				reasons = append(reasons, fmt.Sprintf("condition: %v condition failed for content: %s", exp.Condition.Type, content))
+			}
+		}
+	}
+
+	return reasons
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,77 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package test
+
+import (
+	"bytes"
+	"strings"
+)
+
+//------------------------------------------------------------------------------
+
+// diff returns a line based diff of an expected and actual string, where lines
+// only found in the expected string are prefixed with a minus and lines only
+// found in the actual string are prefixed with a plus.
+func diff(expected, actual string) string {
+	exp := strings.Split(expected, "\n")
+	act := strings.Split(actual, "\n")
+
+	// lcs[i][j] is the length of the longest common subsequence of exp[i:] and
+	// act[j:].
+	lcs := make([][]int, len(exp)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(act)+1)
+	}
+	for i := len(exp) - 1; i >= 0; i-- {
+		for j := len(act) - 1; j >= 0; j-- {
+			if exp[i] == act[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("--- expected\n+++ actual\n")
+
+	i, j := 0, 0
+	for i < len(exp) || j < len(act) {
+		switch {
+		case i < len(exp) && j < len(act) && exp[i] == act[j]:
+			buf.WriteString(" " + exp[i] + "\n")
+			i++
+			j++
+		case j >= len(act) || (i < len(exp) && lcs[i+1][j] >= lcs[i][j+1]):
+			buf.WriteString("-" + exp[i] + "\n")
+			i++
+		default:
+			buf.WriteString("+" + act[j] + "\n")
+			j++
+		}
+	}
+
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,59 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package test
+
+import (
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		exp, act string
+		diff     string
+	}{
+		{
+			exp:  "foo",
+			act:  "bar",
+			diff: "--- expected\n+++ actual\n-foo\n+bar",
+		},
+		{
+			exp:  "foo\nbar\nbaz",
+			act:  "foo\nbuz\nbaz",
+			diff: "--- expected\n+++ actual\n foo\n-bar\n+buz\n baz",
+		},
+		{
+			exp:  "foo\nbar",
+			act:  "foo\nbar\nbaz",
+			diff: "--- expected\n+++ actual\n foo\n bar\n+baz",
+		},
+		{
+			exp:  "foo\nbar\nbaz",
+			act:  "bar",
+			diff: "--- expected\n+++ actual\n-foo\n bar\n-baz",
+		},
+	}
+
+	for _, test := range tests {
+		if act := diff(test.exp, test.act); act != test.diff {
+			t.Errorf("Wrong diff result:\n%v\n!=\n%v", act, test.diff)
+		}
+	}
+}
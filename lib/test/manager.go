@@ -0,0 +1,108 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package test
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Jeffail/benthos/lib/cache"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/manager"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/processor/condition"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// mockManager is a types.Manager that provides the condition resources of a
+// config and replaces each cache resource with an empty memory cache, so that
+// tests do not depend on external services or on each other.
+type mockManager struct {
+	caches     map[string]types.Cache
+	conditions map[string]types.Condition
+}
+
+func newMockManager(conf manager.Config, log log.Modular, stats metrics.Type) (*mockManager, error) {
+	m := &mockManager{
+		caches:     map[string]types.Cache{},
+		conditions: map[string]types.Condition{},
+	}
+
+	for k := range conf.Caches {
+		newCache, err := cache.NewMemory(cache.NewConfig(), m, log, stats)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create cache resource '%v': %v", k, err)
+		}
+		m.caches[k] = newCache
+	}
+
+	// Condition resources might refer to other condition resources, and so we
+	// create placeholders during construction.
+	for k := range conf.Conditions {
+		m.conditions[k] = nil
+	}
+	for k, newConf := range conf.Conditions {
+		newCond, err := condition.New(newConf, m, log, stats)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to create condition resource '%v' of type '%v': %v",
+				k, newConf.Type, err,
+			)
+		}
+		m.conditions[k] = newCond
+	}
+
+	return m, nil
+}
+
+// RegisterEndpoint is a noop.
+func (m *mockManager) RegisterEndpoint(path, desc string, h http.HandlerFunc) {}
+
+// GetCache attempts to find a cache resource by its name.
+func (m *mockManager) GetCache(name string) (types.Cache, error) {
+	if c, exists := m.caches[name]; exists {
+		return c, nil
+	}
+	return nil, types.ErrCacheNotFound
+}
+
+// GetCondition attempts to find a condition resource by its name.
+func (m *mockManager) GetCondition(name string) (types.Condition, error) {
+	if c, exists := m.conditions[name]; exists {
+		return c, nil
+	}
+	return nil, types.ErrConditionNotFound
+}
+
+// GetPipe returns an error as pipes are not supported in tests.
+func (m *mockManager) GetPipe(name string) (<-chan types.Transaction, error) {
+	return nil, types.ErrPipeNotFound
+}
+
+// SetPipe is a noop.
+func (m *mockManager) SetPipe(name string, t <-chan types.Transaction) {}
+
+// UnsetPipe is a noop.
+func (m *mockManager) UnsetPipe(name string, t <-chan types.Transaction) {}
+
+//------------------------------------------------------------------------------
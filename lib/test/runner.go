@@ -0,0 +1,168 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package test
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/util/text"
+	yaml "gopkg.in/yaml.v2"
+)
+
+//------------------------------------------------------------------------------
+
+// DefinitionSuffix is the suffix of test definition files. A definition file
+// targets the config file found at the same path without the suffix and with a
+// .yaml, .yml or .json extension.
+const DefinitionSuffix = "_benthos_test.yaml"
+
+func readFile(path string, replaceEnvs bool) ([]byte, error) {
+	fileBytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if replaceEnvs {
+		fileBytes = text.ReplaceEnvVariables(fileBytes)
+	}
+	return fileBytes, nil
+}
+
+// GetDefinitionPaths returns the paths of all test definition files found
+// within a list of files and directories, directories are walked recursively.
+func GetDefinitionPaths(paths []string) ([]string, error) {
+	var defPaths []string
+	for _, path := range paths {
+		if err := filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() && strings.HasSuffix(p, DefinitionSuffix) {
+				defPaths = append(defPaths, p)
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(defPaths)
+	return defPaths, nil
+}
+
+// GetConfigPath returns the path of the config file targeted by a test
+// definition file.
+func GetConfigPath(defPath string) (string, error) {
+	base := strings.TrimSuffix(defPath, DefinitionSuffix)
+	for _, ext := range []string{".yaml", ".yml", ".json"} {
+		if _, err := os.Stat(base + ext); err == nil {
+			return base + ext, nil
+		}
+	}
+	return "", fmt.Errorf("no config file found for test definition '%v'", defPath)
+}
+
+// RunDefinition runs all test cases of a definition file against its target
+// config and returns any failures.
+func RunDefinition(defPath string, replaceEnvs bool) ([]Failure, error) {
+	defBytes, err := readFile(defPath, replaceEnvs)
+	if err != nil {
+		return nil, err
+	}
+	var def Definition
+	if err = yaml.Unmarshal(defBytes, &def); err != nil {
+		return nil, fmt.Errorf("failed to parse test definition: %v", err)
+	}
+
+	confPath, err := GetConfigPath(defPath)
+	if err != nil {
+		return nil, err
+	}
+	confBytes, err := readFile(confPath, replaceEnvs)
+	if err != nil {
+		return nil, err
+	}
+	var root interface{}
+	if err = yaml.Unmarshal(confBytes, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse config '%v': %v", confPath, err)
+	}
+
+	r := caseRunner{
+		root:  root,
+		log:   log.New(os.Stdout, log.Config{LogLevel: "NONE"}),
+		stats: metrics.DudType{},
+	}
+
+	var failures []Failure
+	for i, c := range def.Tests {
+		if len(c.Name) == 0 {
+			c.Name = fmt.Sprintf("test %v", i)
+		}
+		caseFailures, err := r.Run(c)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", c.Name, err)
+		}
+		failures = append(failures, caseFailures...)
+	}
+	return failures, nil
+}
+
+// RunAll runs all test definition files found within a list of files and
+// directories, printing the results to w. Returns true if all tests passed.
+func RunAll(paths []string, replaceEnvs bool, w io.Writer) bool {
+	defPaths, err := GetDefinitionPaths(paths)
+	if err != nil {
+		fmt.Fprintf(w, "Failed to find test definitions: %v\n", err)
+		return false
+	}
+	if len(defPaths) == 0 {
+		fmt.Fprintf(w, "No test definitions found with the suffix '%v'\n", DefinitionSuffix)
+		return false
+	}
+
+	passed := true
+	for _, defPath := range defPaths {
+		failures, err := RunDefinition(defPath, replaceEnvs)
+		if err != nil {
+			passed = false
+			fmt.Fprintf(w, "Test '%v' errored: %v\n", defPath, err)
+			continue
+		}
+		if len(failures) == 0 {
+			fmt.Fprintf(w, "Test '%v' succeeded\n", defPath)
+			continue
+		}
+		passed = false
+		fmt.Fprintf(w, "Test '%v' failed\n\n", defPath)
+		for _, f := range failures {
+			fmt.Fprintf(w, "%v\n\n", f)
+		}
+	}
+	return passed
+}
+
+//------------------------------------------------------------------------------
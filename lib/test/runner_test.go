@@ -0,0 +1,250 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+//------------------------------------------------------------------------------
+
+var testConfig = `
+pipeline:
+  processors:
+  - type: mapping
+    mapping:
+      mapping: root = content().uppercase()
+  - type: metadata
+    metadata:
+      operator: set
+      key: foo
+      value: bar
+resources:
+  caches:
+    dupes:
+      type: memcached
+  conditions:
+    is_foo:
+      type: text
+      text:
+        operator: equals
+        arg: foo
+    is_not_foo:
+      type: not
+      not:
+        type: resource
+        resource: is_foo
+`
+
+func writeTestFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "benthos_test_runner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err = ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestRunDefinitionPasses(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"foo.yaml": testConfig,
+		"foo_benthos_test.yaml": `
+tests:
+- name: upper case
+  target_processors: pipeline.processors
+  input:
+    metadata:
+      baz: qux
+    parts:
+    - hello world
+    - '{"doc":"value"}'
+  output:
+  - metadata_equals:
+      foo: bar
+      baz: qux
+    parts:
+    - content_equals: HELLO WORLD
+      condition:
+        type: text
+        text:
+          operator: prefix
+          arg: HELLO
+    - json_equals:
+        DOC: VALUE
+- name: single processor
+  target_processors: pipeline.processors.1
+  input:
+    parts: [ hello world ]
+  output:
+  - parts:
+    - content_equals: hello world
+- name: resource condition
+  target_condition: resources.conditions.is_not_foo
+  input:
+    parts: [ bar ]
+  condition_result: true
+`,
+	})
+	defer os.RemoveAll(dir)
+
+	failures, err := RunDefinition(filepath.Join(dir, "foo_benthos_test.yaml"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failures) > 0 {
+		t.Errorf("Unexpected failures: %v", failures)
+	}
+}
+
+func TestRunDefinitionFails(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"foo.yaml": testConfig,
+		"foo_benthos_test.yaml": `
+tests:
+- name: wrong content
+  target_processors: pipeline.processors
+  input:
+    parts: [ hello ]
+  output:
+  - metadata_equals:
+      foo: baz
+    parts:
+    - content_equals: hello
+- name: wrong json
+  target_processors: pipeline.processors
+  input:
+    parts: [ '{"a":"b","c":"d"}' ]
+  output:
+  - parts:
+    - json_equals:
+        A: B
+        C: E
+- name: wrong count
+  target_processors: pipeline.processors
+  input:
+    parts: [ hello ]
+  output: []
+- name: wrong condition
+  target_condition: resources.conditions.is_foo
+  input:
+    parts: [ bar ]
+  condition_result: true
+`,
+	})
+	defer os.RemoveAll(dir)
+
+	failures, err := RunDefinition(filepath.Join(dir, "foo_benthos_test.yaml"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []string{
+		"wrong content: message 0 metadata_equals foo: expected 'baz', got 'bar'",
+		"wrong content: message 0 part 0 content_equals:\n--- expected\n+++ actual\n-hello\n+HELLO",
+		"wrong json: message 0 part 0 json_equals:\n--- expected\n+++ actual\n {\n   \"A\": \"B\",\n-  \"C\": \"E\"\n+  \"C\": \"D\"\n }",
+		"wrong count: expected 0 resulting messages, got 1",
+		"wrong condition: condition_result: expected true, got false",
+	}
+	if len(failures) != len(exp) {
+		t.Fatalf("Wrong count of failures: %v != %v: %v", len(failures), len(exp), failures)
+	}
+	for i, f := range failures {
+		if act := f.String(); act != exp[i] {
+			t.Errorf("Wrong failure %v:\n%v\n!=\n%v", i, act, exp[i])
+		}
+	}
+}
+
+func TestRunDefinitionErrors(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"foo.yaml": testConfig,
+		"foo_benthos_test.yaml": `
+tests:
+- name: bad path
+  target_processors: pipeline.nope
+`,
+		"bar_benthos_test.yaml": `
+tests:
+- name: no config
+  target_processors: pipeline.processors
+`,
+	})
+	defer os.RemoveAll(dir)
+
+	if _, err := RunDefinition(filepath.Join(dir, "foo_benthos_test.yaml"), false); err == nil {
+		t.Error("Expected error from bad path")
+	}
+	if _, err := RunDefinition(filepath.Join(dir, "bar_benthos_test.yaml"), false); err == nil {
+		t.Error("Expected error from missing config")
+	}
+}
+
+func TestRunAll(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"a/foo.yaml": testConfig,
+		"a/foo_benthos_test.yaml": `
+tests:
+- name: passes
+  target_condition: resources.conditions.is_foo
+  input:
+    parts: [ foo ]
+  condition_result: true
+`,
+		"b/bar.json": `{"resources":{"conditions":{"is_bar":{"type":"text","text":{"operator":"equals","arg":"bar"}}}}}`,
+		"b/bar_benthos_test.yaml": `
+tests:
+- name: fails
+  target_condition: resources.conditions.is_bar
+  input:
+    parts: [ foo ]
+  condition_result: true
+`,
+	})
+	defer os.RemoveAll(dir)
+
+	var buf bytes.Buffer
+	if RunAll([]string{filepath.Join(dir, "a")}, false, &buf) != true {
+		t.Errorf("Expected tests to pass: %s", buf.Bytes())
+	}
+
+	buf.Reset()
+	if RunAll([]string{dir}, false, &buf) != false {
+		t.Errorf("Expected tests to fail: %s", buf.Bytes())
+	}
+	if exp, act := "fails: condition_result: expected true, got false", buf.String(); !strings.Contains(act, exp) {
+		t.Errorf("Expected output to contain '%v': %v", exp, act)
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -212,6 +212,19 @@ type Message interface {
 	// found by counting backwards from the last part starting at -1.
 	SetJSON(p int, jObj interface{}) error
 
+	// GetError returns the error that a message part has been flagged with
+	// during processing, or nil if the part has not failed. If the index is
+	// negative then the part is found by counting backwards from the last part
+	// starting at -1.
+	GetError(p int) error
+
+	// SetError flags a message part as having failed processing with an error,
+	// a nil error clears the flag. The flag remains with the part until it is
+	// cleared or the parts of the message are replaced. If the index is
+	// negative then the part is found by counting backwards from the last part
+	// starting at -1.
+	SetError(p int, err error)
+
 	// Append appends new message parts to the message and returns the index of
 	// last part to be added.
 	Append(b ...[]byte) int
@@ -0,0 +1,134 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package csv
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"unicode/utf8"
+)
+
+//------------------------------------------------------------------------------
+
+// ParseDelimiter returns the field delimiter described by a config string,
+// which must be exactly one character.
+func ParseDelimiter(delim string) (rune, error) {
+	if utf8.RuneCountInString(delim) != 1 {
+		return 0, errors.New("delimiter value must be exactly one character")
+	}
+	r, _ := utf8.DecodeRuneInString(delim)
+	return r, nil
+}
+
+// RecordToJSON converts a CSV record into an object keyed by column names,
+// falling back to column indexes for fields without a name. When there are no
+// column names at all the record is returned as an array.
+func RecordToJSON(columns, record []string) interface{} {
+	if len(columns) == 0 {
+		arr := make([]interface{}, len(record))
+		for i, v := range record {
+			arr[i] = v
+		}
+		return arr
+	}
+	obj := make(map[string]interface{}, len(record))
+	for i, v := range record {
+		if i < len(columns) {
+			obj[columns[i]] = v
+		} else {
+			obj[strconv.Itoa(i)] = v
+		}
+	}
+	return obj
+}
+
+//------------------------------------------------------------------------------
+
+// Row is a single row of a CSV document converted into a JSON document, along
+// with the error encountered whilst parsing it, if any.
+type Row struct {
+	Doc []byte
+	Err error
+}
+
+// Reader reads the rows of a CSV document as JSON documents.
+type Reader struct {
+	r       *csv.Reader
+	columns []string
+}
+
+// NewReader creates a Reader of the CSV document within r. When headerRow is
+// true the first row is read as the column names, unless columns is set, in
+// which case the header row is discarded. If the header row cannot be read
+// because the document is empty then io.EOF is returned.
+func NewReader(
+	r io.Reader,
+	delim rune,
+	lazyQuotes bool,
+	headerRow bool,
+	columns []string,
+) (*Reader, error) {
+	cr := csv.NewReader(r)
+	cr.Comma = delim
+	cr.LazyQuotes = lazyQuotes
+
+	if headerRow {
+		header, err := cr.Read()
+		if err != nil {
+			return nil, err
+		}
+		if len(columns) == 0 {
+			columns = header
+		}
+	}
+	return &Reader{
+		r:       cr,
+		columns: columns,
+	}, nil
+}
+
+// ReadRows reads up to n rows of the document, or all remaining rows if n is
+// zero or less. Rows that fail to parse are still returned, with Err set to the
+// parse error. Once the end of the document is reached io.EOF is returned along
+// with any rows read before it. Any other error aborts the read.
+func (r *Reader) ReadRows(n int) ([]Row, error) {
+	var rows []Row
+	for n <= 0 || len(rows) < n {
+		record, err := r.r.Read()
+		if err == io.EOF {
+			return rows, io.EOF
+		}
+		if _, isParseErr := err.(*csv.ParseError); err != nil && !isParseErr {
+			return nil, err
+		}
+		doc, jerr := json.Marshal(RecordToJSON(r.columns, record))
+		if jerr != nil {
+			return nil, jerr
+		}
+		rows = append(rows, Row{Doc: doc, Err: err})
+	}
+	return rows, nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,95 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package csv
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReaderHeaderRow(t *testing.T) {
+	doc := "id,name\n1,foo\n2,\"bar\n3,baz\n"
+
+	r, err := NewReader(strings.NewReader(doc), ',', false, true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := r.ReadRows(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := 1, len(rows); exp != act {
+		t.Fatalf("Wrong count of rows: %v != %v", act, exp)
+	}
+	if exp, act := `{"id":"1","name":"foo"}`, string(rows[0].Doc); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+	if rows[0].Err != nil {
+		t.Errorf("Unexpected row error: %v", rows[0].Err)
+	}
+
+	if rows, err = r.ReadRows(0); err != io.EOF {
+		t.Errorf("Wrong error: %v != %v", err, io.EOF)
+	}
+	if exp, act := 1, len(rows); exp != act {
+		t.Fatalf("Wrong count of rows: %v != %v", act, exp)
+	}
+	if rows[0].Err == nil {
+		t.Error("Expected parse error to be flagged on row")
+	}
+}
+
+func TestReaderColumns(t *testing.T) {
+	doc := "id,name\n1,foo,bar\n"
+
+	r, err := NewReader(strings.NewReader(doc), ',', false, false, []string{"a", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := r.ReadRows(0)
+	if err != io.EOF {
+		t.Errorf("Wrong error: %v != %v", err, io.EOF)
+	}
+
+	act := []string{}
+	for _, row := range rows {
+		act = append(act, string(row.Doc))
+	}
+	exp := []string{
+		`{"a":"id","b":"name"}`,
+		`{"2":"bar","a":"1","b":"foo"}`,
+	}
+	if !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+}
+
+func TestReaderEmpty(t *testing.T) {
+	if _, err := NewReader(strings.NewReader(""), ',', false, true, nil); err != io.EOF {
+		t.Errorf("Wrong error: %v != %v", err, io.EOF)
+	}
+	if _, err := ParseDelimiter("ab"); err == nil {
+		t.Error("Expected error from bad delimiter")
+	}
+}
@@ -0,0 +1,491 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package parquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/golang/snappy"
+)
+
+//------------------------------------------------------------------------------
+
+// ColumnConfig declares the name and type of a column within a Parquet schema.
+type ColumnConfig struct {
+	Name string `json:"name" yaml:"name"`
+	Type string `json:"type" yaml:"type"`
+}
+
+// Config contains configuration fields for a Parquet encoder.
+type Config struct {
+	Schema      []ColumnConfig `json:"schema" yaml:"schema"`
+	Compression string         `json:"compression" yaml:"compression"`
+}
+
+// NewConfig returns a Config with default values.
+func NewConfig() Config {
+	return Config{
+		Schema:      []ColumnConfig{},
+		Compression: "snappy",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Parquet physical types.
+const (
+	typeBoolean   int32 = 0
+	typeInt64     int32 = 2
+	typeDouble    int32 = 5
+	typeByteArray int32 = 6
+)
+
+// Parquet enum values used within file metadata.
+const (
+	repetitionOptional int32 = 1
+	convertedUTF8      int32 = 0
+	encodingPlain      int32 = 0
+	encodingRLE        int32 = 3
+	pageTypeData       int32 = 0
+)
+
+// Parquet compression codecs.
+const (
+	codecUncompressed int32 = 0
+	codecSnappy       int32 = 1
+	codecGzip         int32 = 2
+)
+
+type column struct {
+	name     string
+	typeName string
+	physical int32
+}
+
+func newColumn(name, typeName string) (column, error) {
+	c := column{
+		name:     name,
+		typeName: typeName,
+	}
+	switch typeName {
+	case "boolean":
+		c.physical = typeBoolean
+	case "int64":
+		c.physical = typeInt64
+	case "double":
+		c.physical = typeDouble
+	case "utf8":
+		c.physical = typeByteArray
+	default:
+		return c, fmt.Errorf("column type not recognised: %v", typeName)
+	}
+	return c, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Encoder converts batches of JSON documents into Parquet files, where each
+// document is a row and each top level field is a column. All columns are
+// optional, and therefore fields missing from a document are written as nulls.
+//
+// If a schema is not provided it is inferred from the first document of the
+// first batch that is successfully encoded, and is then reused for all
+// subsequent batches.
+//
+// A batch containing a document that cannot be encoded, either because it is
+// not a JSON object or because a field does not match the type of its column,
+// fails to encode as a whole, and the error identifies the offending row.
+type Encoder struct {
+	codec   int32
+	columns []column
+}
+
+// NewEncoder creates a new Parquet encoder.
+func NewEncoder(conf Config) (*Encoder, error) {
+	e := &Encoder{}
+
+	switch conf.Compression {
+	case "none", "":
+		e.codec = codecUncompressed
+	case "snappy":
+		e.codec = codecSnappy
+	case "gzip":
+		e.codec = codecGzip
+	default:
+		return nil, fmt.Errorf("compression type not recognised: %v", conf.Compression)
+	}
+
+	names := map[string]struct{}{}
+	for _, c := range conf.Schema {
+		if len(c.Name) == 0 {
+			return nil, errors.New("schema columns must have a name")
+		}
+		if _, exists := names[c.Name]; exists {
+			return nil, fmt.Errorf("duplicate schema column: %v", c.Name)
+		}
+		names[c.Name] = struct{}{}
+
+		col, err := newColumn(c.Name, c.Type)
+		if err != nil {
+			return nil, err
+		}
+		e.columns = append(e.columns, col)
+	}
+	return e, nil
+}
+
+//------------------------------------------------------------------------------
+
+// inferColumns derives a schema from the fields of a document, sorted by name.
+func inferColumns(doc map[string]interface{}) []column {
+	names := make([]string, 0, len(doc))
+	for k := range doc {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	columns := make([]column, len(names))
+	for i, name := range names {
+		typeName := "utf8"
+		switch t := doc[name].(type) {
+		case bool:
+			typeName = "boolean"
+		case json.Number:
+			if strings.ContainsAny(t.String(), ".eE") {
+				typeName = "double"
+			} else {
+				typeName = "int64"
+			}
+		}
+		columns[i], _ = newColumn(name, typeName)
+	}
+	return columns
+}
+
+func parseDocument(part []byte) (map[string]interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(part))
+	dec.UseNumber()
+
+	var doc map[string]interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	if doc == nil {
+		return nil, errors.New("document is not a JSON object")
+	}
+	return doc, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Encode writes a slice of JSON documents as rows of a single Parquet file.
+func (e *Encoder) Encode(parts [][]byte) ([]byte, error) {
+	if len(parts) == 0 {
+		return nil, errors.New("no rows to encode")
+	}
+
+	docs := make([]map[string]interface{}, len(parts))
+	for i, part := range parts {
+		var err error
+		if docs[i], err = parseDocument(part); err != nil {
+			return nil, fmt.Errorf("failed to parse row %v: %v", i, err)
+		}
+	}
+
+	// An inferred schema is only kept once a batch has been encoded with it, so
+	// that a batch which fails to encode does not fix the schema.
+	columns := e.columns
+	if len(columns) == 0 {
+		if columns = inferColumns(docs[0]); len(columns) == 0 {
+			return nil, errors.New("unable to infer schema from a document without fields")
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("PAR1")
+
+	chunks := make([]columnChunk, len(columns))
+	for i, col := range columns {
+		page, err := encodePage(col, docs)
+		if err != nil {
+			return nil, err
+		}
+		if chunks[i], err = e.writeChunk(&buf, col, page, int64(len(docs))); err != nil {
+			return nil, err
+		}
+	}
+
+	meta := e.fileMetadata(columns, chunks, int64(len(docs)))
+	buf.Write(meta)
+
+	var metaLen [4]byte
+	binary.LittleEndian.PutUint32(metaLen[:], uint32(len(meta)))
+	buf.Write(metaLen[:])
+	buf.WriteString("PAR1")
+
+	e.columns = columns
+	return buf.Bytes(), nil
+}
+
+//------------------------------------------------------------------------------
+
+// encodePage returns the uncompressed contents of a data page for a column,
+// consisting of definition levels followed by plain encoded non-null values.
+func encodePage(col column, docs []map[string]interface{}) ([]byte, error) {
+	defLevels := make([]bool, len(docs))
+	var values bytes.Buffer
+	var bits []bool
+
+	for i, doc := range docs {
+		v := doc[col.name]
+		if v == nil {
+			continue
+		}
+		defLevels[i] = true
+
+		switch col.physical {
+		case typeBoolean:
+			b, ok := v.(bool)
+			if !ok {
+				return nil, fmt.Errorf("row %v field '%v': expected boolean, found %T", i, col.name, v)
+			}
+			bits = append(bits, b)
+		case typeInt64:
+			n, ok := v.(json.Number)
+			if !ok {
+				return nil, fmt.Errorf("row %v field '%v': expected number, found %T", i, col.name, v)
+			}
+			iv, err := n.Int64()
+			if err != nil {
+				fv, ferr := n.Float64()
+				if ferr != nil || fv != math.Trunc(fv) {
+					return nil, fmt.Errorf("row %v field '%v': expected integer, found %v", i, col.name, n)
+				}
+				if fv < -(1<<63) || fv >= 1<<63 {
+					return nil, fmt.Errorf("row %v field '%v': integer %v out of range", i, col.name, n)
+				}
+				iv = int64(fv)
+			}
+			binary.Write(&values, binary.LittleEndian, iv)
+		case typeDouble:
+			n, ok := v.(json.Number)
+			if !ok {
+				return nil, fmt.Errorf("row %v field '%v': expected number, found %T", i, col.name, v)
+			}
+			fv, err := n.Float64()
+			if err != nil {
+				return nil, fmt.Errorf("row %v field '%v': %v", i, col.name, err)
+			}
+			binary.Write(&values, binary.LittleEndian, math.Float64bits(fv))
+		case typeByteArray:
+			var str []byte
+			switch t := v.(type) {
+			case string:
+				str = []byte(t)
+			case json.Number:
+				str = []byte(t.String())
+			default:
+				var err error
+				if str, err = json.Marshal(t); err != nil {
+					return nil, fmt.Errorf("row %v field '%v': %v", i, col.name, err)
+				}
+			}
+			binary.Write(&values, binary.LittleEndian, uint32(len(str)))
+			values.Write(str)
+		}
+	}
+
+	if col.physical == typeBoolean {
+		packed := make([]byte, (len(bits)+7)/8)
+		for i, b := range bits {
+			if b {
+				packed[i/8] |= 1 << uint(i%8)
+			}
+		}
+		values.Write(packed)
+	}
+
+	levels := encodeLevels(defLevels)
+
+	var page bytes.Buffer
+	binary.Write(&page, binary.LittleEndian, uint32(len(levels)))
+	page.Write(levels)
+	page.Write(values.Bytes())
+	return page.Bytes(), nil
+}
+
+// encodeLevels encodes definition levels with a maximum level of one using
+// run length encoded runs of the RLE/bit-packing hybrid encoding.
+func encodeLevels(levels []bool) []byte {
+	var buf bytes.Buffer
+	var tmp [binary.MaxVarintLen64]byte
+	for i := 0; i < len(levels); {
+		j := i + 1
+		for j < len(levels) && levels[j] == levels[i] {
+			j++
+		}
+		n := binary.PutUvarint(tmp[:], uint64(j-i)<<1)
+		buf.Write(tmp[:n])
+		if levels[i] {
+			buf.WriteByte(1)
+		} else {
+			buf.WriteByte(0)
+		}
+		i = j
+	}
+	return buf.Bytes()
+}
+
+//------------------------------------------------------------------------------
+
+type columnChunk struct {
+	col              column
+	offset           int64
+	numValues        int64
+	uncompressedSize int64
+	compressedSize   int64
+}
+
+func (e *Encoder) compress(page []byte) ([]byte, error) {
+	switch e.codec {
+	case codecSnappy:
+		return snappy.Encode(nil, page), nil
+	case codecGzip:
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(page); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	return page, nil
+}
+
+// writeChunk writes a column chunk consisting of a single data page.
+func (e *Encoder) writeChunk(buf *bytes.Buffer, col column, page []byte, numRows int64) (columnChunk, error) {
+	compressed, err := e.compress(page)
+	if err != nil {
+		return columnChunk{}, err
+	}
+
+	w := &compactWriter{}
+	w.structBegin()
+	w.i32Field(1, pageTypeData)
+	w.i32Field(2, int32(len(page)))
+	w.i32Field(3, int32(len(compressed)))
+	w.fieldHeader(5, thriftStruct)
+	w.structBegin()
+	w.i32Field(1, int32(numRows))
+	w.i32Field(2, encodingPlain)
+	w.i32Field(3, encodingRLE)
+	w.i32Field(4, encodingRLE)
+	w.structEnd()
+	w.structEnd()
+	header := w.buf.Bytes()
+
+	chunk := columnChunk{
+		col:              col,
+		offset:           int64(buf.Len()),
+		numValues:        numRows,
+		uncompressedSize: int64(len(header) + len(page)),
+		compressedSize:   int64(len(header) + len(compressed)),
+	}
+	buf.Write(header)
+	buf.Write(compressed)
+	return chunk, nil
+}
+
+func (e *Encoder) fileMetadata(columns []column, chunks []columnChunk, numRows int64) []byte {
+	w := &compactWriter{}
+	w.structBegin()
+	w.i32Field(1, 1)
+
+	w.fieldHeader(2, thriftList)
+	w.listBegin(thriftStruct, len(columns)+1)
+	w.structBegin()
+	w.stringField(4, "schema")
+	w.i32Field(5, int32(len(columns)))
+	w.structEnd()
+	for _, col := range columns {
+		w.structBegin()
+		w.i32Field(1, col.physical)
+		w.i32Field(3, repetitionOptional)
+		w.stringField(4, col.name)
+		if col.typeName == "utf8" {
+			w.i32Field(6, convertedUTF8)
+		}
+		w.structEnd()
+	}
+
+	w.i64Field(3, numRows)
+
+	var totalSize int64
+	for _, c := range chunks {
+		totalSize += c.uncompressedSize
+	}
+
+	w.fieldHeader(4, thriftList)
+	w.listBegin(thriftStruct, 1)
+	w.structBegin()
+	w.fieldHeader(1, thriftList)
+	w.listBegin(thriftStruct, len(chunks))
+	for _, c := range chunks {
+		w.structBegin()
+		w.i64Field(2, c.offset)
+		w.fieldHeader(3, thriftStruct)
+		w.structBegin()
+		w.i32Field(1, c.col.physical)
+		w.fieldHeader(2, thriftList)
+		w.listBegin(thriftI32, 2)
+		w.i32(encodingPlain)
+		w.i32(encodingRLE)
+		w.fieldHeader(3, thriftList)
+		w.listBegin(thriftBinary, 1)
+		w.binary([]byte(c.col.name))
+		w.i32Field(4, e.codec)
+		w.i64Field(5, c.numValues)
+		w.i64Field(6, c.uncompressedSize)
+		w.i64Field(7, c.compressedSize)
+		w.i64Field(9, c.offset)
+		w.structEnd()
+		w.structEnd()
+	}
+	w.i64Field(2, totalSize)
+	w.i64Field(3, numRows)
+	w.structEnd()
+
+	w.stringField(6, "benthos")
+	w.structEnd()
+	return w.buf.Bytes()
+}
+
+//------------------------------------------------------------------------------
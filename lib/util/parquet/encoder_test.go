@@ -0,0 +1,415 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package parquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/golang/snappy"
+)
+
+//------------------------------------------------------------------------------
+
+// compactReader decodes Thrift compact protocol structs into maps of field IDs
+// to values, which is just enough to verify the files we write.
+type compactReader struct {
+	r *bytes.Reader
+}
+
+func (c *compactReader) varint() uint64 {
+	v, err := binary.ReadUvarint(c.r)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func (c *compactReader) zigzag() int64 {
+	v := c.varint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (c *compactReader) value(typ byte) interface{} {
+	switch typ {
+	case thriftI32, thriftI64:
+		return c.zigzag()
+	case thriftBinary:
+		b := make([]byte, c.varint())
+		if _, err := c.r.Read(b); err != nil && len(b) > 0 {
+			panic(err)
+		}
+		return string(b)
+	case thriftList:
+		h, _ := c.r.ReadByte()
+		size := int(h >> 4)
+		if size == 15 {
+			size = int(c.varint())
+		}
+		list := make([]interface{}, size)
+		for i := range list {
+			list[i] = c.value(h & 0x0f)
+		}
+		return list
+	case thriftStruct:
+		return c.readStruct()
+	}
+	panic(fmt.Sprintf("unexpected type: %v", typ))
+}
+
+func (c *compactReader) readStruct() map[int16]interface{} {
+	fields := map[int16]interface{}{}
+	var lastID int16
+	for {
+		h, _ := c.r.ReadByte()
+		if h == 0 {
+			return fields
+		}
+		id := lastID + int16(h>>4)
+		if h>>4 == 0 {
+			id = int16(c.zigzag())
+		}
+		fields[id] = c.value(h & 0x0f)
+		lastID = id
+	}
+}
+
+func decodeFile(t *testing.T, file []byte) (schema []map[int16]interface{}, rows []map[string]interface{}) {
+	if !bytes.HasPrefix(file, []byte("PAR1")) || !bytes.HasSuffix(file, []byte("PAR1")) {
+		t.Fatal("Missing magic bytes")
+	}
+	metaLen := int(binary.LittleEndian.Uint32(file[len(file)-8:]))
+	meta := (&compactReader{bytes.NewReader(file[len(file)-8-metaLen : len(file)-8])}).readStruct()
+
+	for _, s := range meta[2].([]interface{}) {
+		schema = append(schema, s.(map[int16]interface{}))
+	}
+	numRows := int(meta[3].(int64))
+	rows = make([]map[string]interface{}, numRows)
+	for i := range rows {
+		rows[i] = map[string]interface{}{}
+	}
+
+	rowGroup := meta[4].([]interface{})[0].(map[int16]interface{})
+	for _, c := range rowGroup[1].([]interface{}) {
+		colMeta := c.(map[int16]interface{})[3].(map[int16]interface{})
+		name := colMeta[3].([]interface{})[0].(string)
+
+		r := bytes.NewReader(file[colMeta[9].(int64):])
+		header := (&compactReader{r}).readStruct()
+		compressed := make([]byte, header[3].(int64))
+		r.Read(compressed)
+
+		var page []byte
+		switch colMeta[4].(int64) {
+		case 0:
+			page = compressed
+		case 1:
+			var err error
+			if page, err = snappy.Decode(nil, compressed); err != nil {
+				t.Fatal(err)
+			}
+		case 2:
+			zr, err := gzip.NewReader(bytes.NewReader(compressed))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if page, err = ioutil.ReadAll(zr); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if exp, act := int(header[2].(int64)), len(page); exp != act {
+			t.Errorf("Wrong uncompressed size: %v != %v", act, exp)
+		}
+
+		pr := bytes.NewReader(page)
+		var levelsLen uint32
+		binary.Read(pr, binary.LittleEndian, &levelsLen)
+		levelsBytes := make([]byte, levelsLen)
+		pr.Read(levelsBytes)
+
+		var defined []bool
+		lr := bytes.NewReader(levelsBytes)
+		for lr.Len() > 0 {
+			runHeader, _ := binary.ReadUvarint(lr)
+			v, _ := lr.ReadByte()
+			for i := uint64(0); i < runHeader>>1; i++ {
+				defined = append(defined, v == 1)
+			}
+		}
+		if len(defined) != numRows {
+			t.Fatalf("Wrong count of definition levels: %v != %v", len(defined), numRows)
+		}
+
+		physical := colMeta[1].(int64)
+		var bits []byte
+		bitIndex := 0
+		for i, def := range defined {
+			if !def {
+				continue
+			}
+			switch int32(physical) {
+			case typeBoolean:
+				if bits == nil {
+					bits, _ = ioutil.ReadAll(pr)
+				}
+				rows[i][name] = bits[bitIndex/8]&(1<<uint(bitIndex%8)) != 0
+				bitIndex++
+			case typeInt64:
+				var v int64
+				binary.Read(pr, binary.LittleEndian, &v)
+				rows[i][name] = v
+			case typeDouble:
+				var v uint64
+				binary.Read(pr, binary.LittleEndian, &v)
+				rows[i][name] = math.Float64frombits(v)
+			case typeByteArray:
+				var l uint32
+				binary.Read(pr, binary.LittleEndian, &l)
+				b := make([]byte, l)
+				pr.Read(b)
+				rows[i][name] = string(b)
+			}
+		}
+	}
+	return
+}
+
+//------------------------------------------------------------------------------
+
+func TestEncoderInferred(t *testing.T) {
+	for _, codec := range []string{"none", "snappy", "gzip"} {
+		conf := NewConfig()
+		conf.Compression = codec
+
+		e, err := NewEncoder(conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		file, err := e.Encode([][]byte{
+			[]byte(`{"name":"foo","age":10,"score":1.5,"ok":true,"tags":["a"]}`),
+			[]byte(`{"name":"bar","age":20.0,"ok":false,"extra":"ignored"}`),
+			[]byte(`{"score":3,"ok":true}`),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		schema, rows := decodeFile(t, file)
+
+		expNames := []string{"schema", "age", "name", "ok", "score", "tags"}
+		var actNames []string
+		for _, s := range schema {
+			actNames = append(actNames, s[4].(string))
+		}
+		if !reflect.DeepEqual(expNames, actNames) {
+			t.Errorf("Wrong schema: %v != %v", actNames, expNames)
+		}
+
+		exp := []map[string]interface{}{
+			{"name": "foo", "age": int64(10), "score": 1.5, "ok": true, "tags": `["a"]`},
+			{"name": "bar", "age": int64(20), "ok": false},
+			{"score": float64(3), "ok": true},
+		}
+		if !reflect.DeepEqual(exp, rows) {
+			t.Errorf("Wrong rows for %v: %v != %v", codec, rows, exp)
+		}
+
+		// The inferred schema is reused for subsequent batches.
+		if file, err = e.Encode([][]byte{[]byte(`{"other":"field"}`)}); err != nil {
+			t.Fatal(err)
+		}
+		if schema, _ = decodeFile(t, file); len(schema) != len(expNames) {
+			t.Errorf("Schema was not reused: %v", schema)
+		}
+	}
+}
+
+func TestEncoderInferredAfterFailure(t *testing.T) {
+	e, err := NewEncoder(NewConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = e.Encode([][]byte{
+		[]byte(`{"id":1}`),
+		[]byte(`{"id":"nope"}`),
+	}); err == nil {
+		t.Fatal("Expected error from mismatched type")
+	}
+
+	// The failed batch must not fix the schema.
+	file, err := e.Encode([][]byte{[]byte(`{"name":"foo"}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, rows := decodeFile(t, file)
+
+	expNames := []string{"schema", "name"}
+	var actNames []string
+	for _, s := range schema {
+		actNames = append(actNames, s[4].(string))
+	}
+	if !reflect.DeepEqual(expNames, actNames) {
+		t.Errorf("Wrong schema: %v != %v", actNames, expNames)
+	}
+	exp := []map[string]interface{}{{"name": "foo"}}
+	if !reflect.DeepEqual(exp, rows) {
+		t.Errorf("Wrong rows: %v != %v", rows, exp)
+	}
+}
+
+func TestEncoderDeclared(t *testing.T) {
+	conf := NewConfig()
+	conf.Compression = "none"
+	conf.Schema = []ColumnConfig{
+		{Name: "id", Type: "int64"},
+		{Name: "value", Type: "utf8"},
+	}
+
+	e, err := NewEncoder(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := e.Encode([][]byte{
+		[]byte(`{"id":1,"value":"foo"}`),
+		[]byte(`{"id":2,"value":5}`),
+		[]byte(`{"value":{"nested":true}}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schema, rows := decodeFile(t, file)
+	if exp, act := int64(2), schema[0][5].(int64); exp != act {
+		t.Errorf("Wrong num children: %v != %v", act, exp)
+	}
+	if exp, act := int64(convertedUTF8), schema[2][6].(int64); exp != act {
+		t.Errorf("Wrong converted type: %v != %v", act, exp)
+	}
+
+	exp := []map[string]interface{}{
+		{"id": int64(1), "value": "foo"},
+		{"id": int64(2), "value": "5"},
+		{"value": `{"nested":true}`},
+	}
+	if !reflect.DeepEqual(exp, rows) {
+		t.Errorf("Wrong rows: %v != %v", rows, exp)
+	}
+
+	if _, err = e.Encode([][]byte{[]byte(`{"id":"nope"}`)}); err == nil {
+		t.Error("Expected error from mismatched type")
+	}
+	if _, err = e.Encode([][]byte{[]byte(`{"id":1.5}`)}); err == nil {
+		t.Error("Expected error from non integer")
+	}
+	if _, err = e.Encode([][]byte{[]byte(`{"id":1e19}`)}); err == nil {
+		t.Error("Expected error from out of range integer")
+	}
+	if _, err = e.Encode([][]byte{[]byte(`{"id":-1e19}`)}); err == nil {
+		t.Error("Expected error from out of range integer")
+	}
+	if _, err = e.Encode([][]byte{[]byte(`not json`)}); err == nil {
+		t.Error("Expected error from bad json")
+	}
+	if _, err = e.Encode([][]byte{[]byte(`[1,2]`)}); err == nil {
+		t.Error("Expected error from non object")
+	}
+}
+
+func TestEncoderManyRows(t *testing.T) {
+	conf := NewConfig()
+	e, err := NewEncoder(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var parts [][]byte
+	var exp []map[string]interface{}
+	for i := 0; i < 100; i++ {
+		if i%3 == 0 {
+			parts = append(parts, []byte(`{"n":null}`))
+			exp = append(exp, map[string]interface{}{})
+		} else {
+			parts = append(parts, []byte(fmt.Sprintf(`{"n":%v}`, i)))
+			exp = append(exp, map[string]interface{}{"n": int64(i)})
+		}
+	}
+	parts[0] = []byte(`{"n":0}`)
+	exp[0] = map[string]interface{}{"n": int64(0)}
+
+	file, err := e.Encode(parts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, rows := decodeFile(t, file); !reflect.DeepEqual(exp, rows) {
+		t.Errorf("Wrong rows: %v != %v", rows, exp)
+	}
+}
+
+func TestEncoderBadConfig(t *testing.T) {
+	conf := NewConfig()
+	conf.Compression = "nope"
+	if _, err := NewEncoder(conf); err == nil {
+		t.Error("Expected error from bad compression")
+	}
+
+	conf = NewConfig()
+	conf.Schema = []ColumnConfig{{Name: "foo", Type: "nope"}}
+	if _, err := NewEncoder(conf); err == nil {
+		t.Error("Expected error from bad column type")
+	}
+
+	conf = NewConfig()
+	conf.Schema = []ColumnConfig{{Name: "foo", Type: "utf8"}, {Name: "foo", Type: "int64"}}
+	if _, err := NewEncoder(conf); err == nil {
+		t.Error("Expected error from duplicate column")
+	}
+}
+
+func TestCompactWriter(t *testing.T) {
+	w := &compactWriter{}
+	w.structBegin()
+	w.i32Field(1, 3)
+	w.i64Field(20, -1)
+	w.stringField(21, "ab")
+	w.structEnd()
+
+	exp := []byte{
+		0x15, 0x06, // field 1, i32, zigzag(3) = 6
+		0x06, 0x28, 0x01, // field 20, i64 with long form id, zigzag(-1) = 1
+		0x18, 0x02, 'a', 'b', // field 21, binary delta 1
+		0x00,
+	}
+	if act := w.buf.Bytes(); !bytes.Equal(exp, act) {
+		t.Errorf("Wrong encoding: %x != %x", act, exp)
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,102 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package retries
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/util/throttle"
+)
+
+//------------------------------------------------------------------------------
+
+// Behaviours for when the retries of an operation are exhausted.
+const (
+	OnFailureNack = "nack"
+	OnFailureDrop = "drop"
+)
+
+// ErrInvalidOnFailure is returned when a config has an unrecognised on_failure
+// value.
+var ErrInvalidOnFailure = errors.New("on_failure must be either 'nack' or 'drop'")
+
+//------------------------------------------------------------------------------
+
+// Config contains configuration params for retrying failed operations with an
+// exponential backoff.
+type Config struct {
+	MaxRetries      int     `json:"max_retries" yaml:"max_retries"`
+	InitialPeriodMS int     `json:"initial_period_ms" yaml:"initial_period_ms"`
+	MaxPeriodMS     int     `json:"max_period_ms" yaml:"max_period_ms"`
+	Jitter          float64 `json:"jitter" yaml:"jitter"`
+	OnFailure       string  `json:"on_failure" yaml:"on_failure"`
+}
+
+// NewConfig creates a new Config with default values.
+func NewConfig() Config {
+	return Config{
+		MaxRetries:      0,
+		InitialPeriodMS: 1000,
+		MaxPeriodMS:     60000,
+		Jitter:          0,
+		OnFailure:       OnFailureNack,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Validate returns an error if the fields of the config are invalid.
+func (c Config) Validate() error {
+	if c.OnFailure != OnFailureNack && c.OnFailure != OnFailureDrop {
+		return ErrInvalidOnFailure
+	}
+	if c.MaxRetries < 0 {
+		return errors.New("max_retries must not be negative")
+	}
+	if c.Jitter < 0 {
+		return errors.New("jitter must not be negative")
+	}
+	if c.InitialPeriodMS <= 0 {
+		return errors.New("initial_period_ms must be greater than zero")
+	}
+	if c.MaxPeriodMS <= 0 {
+		return errors.New("max_period_ms must be greater than zero")
+	}
+	if c.InitialPeriodMS > c.MaxPeriodMS {
+		return errors.New("initial_period_ms must not be greater than max_period_ms")
+	}
+	return nil
+}
+
+// Throttle returns a throttle that applies the backoff of the config, and is
+// interrupted when closeChan is closed.
+func (c Config) Throttle(closeChan <-chan struct{}) *throttle.Type {
+	return throttle.New(
+		throttle.OptMaxUnthrottledRetries(0),
+		throttle.OptThrottlePeriod(time.Duration(c.InitialPeriodMS)*time.Millisecond),
+		throttle.OptMaxExponentPeriod(time.Duration(c.MaxPeriodMS)*time.Millisecond),
+		throttle.OptJitter(c.Jitter),
+		throttle.OptCloseChan(closeChan),
+	)
+}
+
+//------------------------------------------------------------------------------
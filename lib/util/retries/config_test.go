@@ -0,0 +1,103 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package retries
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConfigValidate(t *testing.T) {
+	conf := NewConfig()
+	if err := conf.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	conf.OnFailure = OnFailureDrop
+	if err := conf.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	conf.OnFailure = "nope"
+	if err := conf.Validate(); err != ErrInvalidOnFailure {
+		t.Errorf("Wrong error: %v != %v", err, ErrInvalidOnFailure)
+	}
+
+	conf = NewConfig()
+	conf.MaxRetries = -1
+	if err := conf.Validate(); err == nil {
+		t.Error("Expected error from negative retries")
+	}
+
+	conf = NewConfig()
+	conf.InitialPeriodMS = 0
+	if err := conf.Validate(); err == nil {
+		t.Error("Expected error from zero initial period")
+	}
+
+	conf = NewConfig()
+	conf.MaxPeriodMS = -1
+	if err := conf.Validate(); err == nil {
+		t.Error("Expected error from negative max period")
+	}
+
+	conf = NewConfig()
+	conf.InitialPeriodMS = 2000
+	conf.MaxPeriodMS = 1000
+	if err := conf.Validate(); err == nil {
+		t.Error("Expected error from initial period greater than max period")
+	}
+
+	conf = NewConfig()
+	conf.InitialPeriodMS = 1000
+	conf.MaxPeriodMS = 1000
+	if err := conf.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConfigThrottle(t *testing.T) {
+	conf := NewConfig()
+	conf.InitialPeriodMS = 10
+	conf.MaxPeriodMS = 20
+
+	closeChan := make(chan struct{})
+	throt := conf.Throttle(closeChan)
+
+	for _, exp := range []time.Duration{
+		time.Millisecond * 10,
+		time.Millisecond * 20,
+		time.Millisecond * 20,
+	} {
+		tBefore := time.Now()
+		if !throt.ExponentialRetry() {
+			t.Fatal("Throttle closed early")
+		}
+		if act := time.Since(tBefore); act < exp || act > exp*3 {
+			t.Errorf("Unexpected retry period: %v != %v", act, exp)
+		}
+	}
+
+	close(closeChan)
+	if throt.ExponentialRetry() {
+		t.Error("Expected throttle to be interrupted")
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package schemaregistry
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/http/auth"
+	"github.com/Jeffail/benthos/lib/util/tls"
+)
+
+//------------------------------------------------------------------------------
+
+// Errors returned when parsing the Confluent wire format.
+var (
+	ErrNoMagicByte    = errors.New("message does not begin with the schema registry magic byte")
+	ErrHeaderTooShort = errors.New("message is too short to contain a schema registry header")
+)
+
+// magicByte is the first byte of all messages in the Confluent wire format.
+const magicByte byte = 0
+
+// headerLen is the length of the Confluent wire header, consisting of the magic
+// byte followed by a four byte schema ID.
+const headerLen = 5
+
+// DecodeHeader parses the Confluent wire header of a payload, returning the
+// schema ID and the remaining bytes following the header.
+func DecodeHeader(b []byte) (int, []byte, error) {
+	if len(b) < headerLen {
+		return 0, nil, ErrHeaderTooShort
+	}
+	if b[0] != magicByte {
+		return 0, nil, ErrNoMagicByte
+	}
+	return int(binary.BigEndian.Uint32(b[1:headerLen])), b[headerLen:], nil
+}
+
+// EncodeHeader returns a payload prefixed with a Confluent wire header
+// containing a schema ID.
+func EncodeHeader(id int, payload []byte) []byte {
+	b := make([]byte, headerLen, headerLen+len(payload))
+	b[0] = magicByte
+	binary.BigEndian.PutUint32(b[1:headerLen], uint32(id))
+	return append(b, payload...)
+}
+
+//------------------------------------------------------------------------------
+
+// Config is a configuration struct for a schema registry client.
+type Config struct {
+	URL         string     `json:"url" yaml:"url"`
+	TimeoutMS   int64      `json:"timeout_ms" yaml:"timeout_ms"`
+	TLS         tls.Config `json:"tls" yaml:"tls"`
+	auth.Config `json:",inline" yaml:",inline"`
+}
+
+// NewConfig creates a new Config with default values.
+func NewConfig() Config {
+	return Config{
+		URL:       "",
+		TimeoutMS: 5000,
+		TLS:       tls.NewConfig(),
+		Config:    auth.NewConfig(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Client obtains schemas from a schema registry.
+type Client struct {
+	client http.Client
+	conf   Config
+}
+
+// New creates a new schema registry client.
+func New(conf Config) (*Client, error) {
+	if len(conf.URL) == 0 {
+		return nil, errors.New("schema registry url must not be empty")
+	}
+	c := &Client{
+		conf: conf,
+	}
+	c.client.Timeout = time.Duration(conf.TimeoutMS) * time.Millisecond
+	if conf.TLS.Enabled {
+		tlsConf, err := conf.TLS.Get()
+		if err != nil {
+			return nil, err
+		}
+		c.client.Transport = &http.Transport{
+			TLSClientConfig: tlsConf,
+		}
+	}
+	return c, nil
+}
+
+//------------------------------------------------------------------------------
+
+// SchemaURL returns the URL from which a schema of an ID is obtained.
+func (c *Client) SchemaURL(id int) string {
+	return fmt.Sprintf("%v/schemas/ids/%v", strings.TrimSuffix(c.conf.URL, "/"), id)
+}
+
+// GetSchemaByID attempts to obtain a schema from the registry by its ID.
+func (c *Client) GetSchemaByID(id int) (string, error) {
+	req, err := http.NewRequest("GET", c.SchemaURL(id), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/vnd.schemaregistry.v1+json")
+	if err = c.conf.Config.Sign(req); err != nil {
+		return "", err
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return "", err
+	}
+	if res.StatusCode != http.StatusOK {
+		return "", types.ErrUnexpectedHTTPRes{Code: res.StatusCode, S: res.Status}
+	}
+
+	var resBody struct {
+		Schema string `json:"schema"`
+	}
+	if err = json.Unmarshal(body, &resBody); err != nil {
+		return "", fmt.Errorf("failed to parse registry response: %v", err)
+	}
+	if len(resBody.Schema) == 0 {
+		return "", fmt.Errorf("registry response for schema %v was empty", id)
+	}
+	return resBody.Schema, nil
+}
+
+//------------------------------------------------------------------------------
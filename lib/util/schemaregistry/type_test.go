@@ -0,0 +1,119 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package schemaregistry
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func TestWireHeader(t *testing.T) {
+	payload := []byte("hello world")
+	b := EncodeHeader(1234, payload)
+	if exp, act := []byte{0, 0, 0, 4, 210}, b[:5]; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong header: %v != %v", act, exp)
+	}
+
+	id, rest, err := DecodeHeader(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := 1234, id; exp != act {
+		t.Errorf("Wrong schema id: %v != %v", act, exp)
+	}
+	if exp, act := payload, rest; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong payload: %s != %s", act, exp)
+	}
+
+	if _, _, err = DecodeHeader([]byte{0, 0}); err != ErrHeaderTooShort {
+		t.Errorf("Wrong error: %v != %v", err, ErrHeaderTooShort)
+	}
+	if _, _, err = DecodeHeader([]byte("hello world")); err != ErrNoMagicByte {
+		t.Errorf("Wrong error: %v != %v", err, ErrNoMagicByte)
+	}
+}
+
+func TestClientGetSchema(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "foo" || pass != "bar" {
+			http.Error(w, "nope", http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/schemas/ids/1":
+			w.Write([]byte(`{"schema":"{\"type\":\"string\"}"}`))
+		case "/schemas/ids/2":
+			w.Write([]byte(`not json`))
+		default:
+			http.Error(w, `{"error_code":40403,"message":"Schema not found"}`, http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	conf := NewConfig()
+	conf.URL = ts.URL + "/"
+	conf.BasicAuth.Enabled = true
+	conf.BasicAuth.Username = "foo"
+	conf.BasicAuth.Password = "bar"
+
+	c, err := New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schema, err := c.GetSchemaByID(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := `{"type":"string"}`, schema; exp != act {
+		t.Errorf("Wrong schema: %v != %v", act, exp)
+	}
+
+	if _, err = c.GetSchemaByID(2); err == nil {
+		t.Error("Expected error from bad response")
+	}
+	_, err = c.GetSchemaByID(3)
+	if httpErr, ok := err.(types.ErrUnexpectedHTTPRes); !ok || httpErr.Code != http.StatusNotFound {
+		t.Errorf("Wrong error: %v", err)
+	}
+
+	conf.BasicAuth.Enabled = false
+	if c, err = New(conf); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.GetSchemaByID(1); err == nil {
+		t.Error("Expected error from missing auth")
+	}
+}
+
+func TestClientNoURL(t *testing.T) {
+	if _, err := New(NewConfig()); err == nil {
+		t.Error("Expected error from empty url")
+	}
+}
+
+//------------------------------------------------------------------------------
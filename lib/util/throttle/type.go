@@ -1,6 +1,7 @@
 package throttle
 
 import (
+	"math/rand"
 	"sync/atomic"
 	"time"
 )
@@ -25,6 +26,10 @@ type Type struct {
 	// the baseThrottlePeriod.
 	throttlePeriod int64
 
+	// jitter is the maximum proportion of the throttle period that is randomly
+	// added to each throttle.
+	jitter float64
+
 	// closeChan can interrupt a throttle when closed.
 	closeChan <-chan struct{}
 
@@ -75,6 +80,15 @@ func OptThrottlePeriod(period time.Duration) func(*Type) {
 	}
 }
 
+// OptJitter sets a maximum proportion of the throttle period to be randomly
+// added to each throttle, e.g. a jitter of 0.5 results in a throttle lasting
+// between 100% and 150% of the throttle period.
+func OptJitter(j float64) func(*Type) {
+	return func(t *Type) {
+		t.jitter = j
+	}
+}
+
 // OptCloseChan sets a read-only channel that, if closed, will interrupt a retry
 // throttle early.
 func OptCloseChan(c <-chan struct{}) func(*Type) {
@@ -92,8 +106,12 @@ func (t *Type) Retry() bool {
 	if rets := atomic.AddInt64(&t.consecutiveRetries, 1); rets <= t.unthrottledRetries {
 		return true
 	}
+	period := atomic.LoadInt64(&t.throttlePeriod)
+	if t.jitter > 0 {
+		period += int64(float64(period) * t.jitter * rand.Float64())
+	}
 	select {
-	case <-time.After(time.Duration(atomic.LoadInt64(&t.throttlePeriod))):
+	case <-time.After(time.Duration(period)):
 	case <-t.closeChan:
 		return false
 	}
@@ -160,3 +160,23 @@ func TestThrottleExponent(t *testing.T) {
 		t.Errorf("Unexpected retry period: %v != %v", act, exp)
 	}
 }
+
+func TestThrottleJitter(t *testing.T) {
+	t.Parallel()
+
+	base := time.Millisecond * 50
+	throt := New(
+		OptMaxUnthrottledRetries(0),
+		OptThrottlePeriod(base),
+		OptJitter(1),
+	)
+
+	for i := 0; i < 5; i++ {
+		tBefore := time.Now()
+		throt.Retry()
+
+		if act := time.Since(tBefore); act < base || act > base*2+time.Millisecond*10 {
+			t.Errorf("Retry period outside of jitter bounds: %v", act)
+		}
+	}
+}
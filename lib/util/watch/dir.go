@@ -0,0 +1,249 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// Op describes the type of change made to a file.
+type Op int
+
+// Types of change that can be made to a file.
+const (
+	OpCreate Op = iota
+	OpModify
+	OpRemove
+)
+
+// String returns a human readable name for the change.
+func (o Op) String() string {
+	switch o {
+	case OpCreate:
+		return "create"
+	case OpModify:
+		return "modify"
+	case OpRemove:
+		return "remove"
+	}
+	return "unknown"
+}
+
+// Event describes a change made to a file within a watched directory.
+type Event struct {
+	Path string
+	Op   Op
+}
+
+//------------------------------------------------------------------------------
+
+// fileState is the observed state of a file at a single poll.
+type fileState struct {
+	exists bool
+	sum    uint64
+}
+
+// Dir polls a directory recursively and emits an event for each file that is
+// created, modified or removed within it. As with File, an event is only
+// emitted once the state of a file has remained the same for a full poll
+// period, which means that a file being rewritten or replaced does not result
+// in a remove followed by a create.
+type Dir struct {
+	running int32
+	dir     string
+	period  time.Duration
+	filter  func(path string) bool
+
+	eventChan  chan Event
+	closeChan  chan struct{}
+	closedChan chan struct{}
+}
+
+// NewDir creates a new Dir watcher for a directory and begins polling it. Files
+// that exist before NewDir returns are used as the starting point and therefore
+// do not result in events, but any change made to them afterwards does.
+func NewDir(dir string, opts ...func(*Dir)) *Dir {
+	d := &Dir{
+		running:    1,
+		dir:        filepath.Clean(dir),
+		period:     time.Second,
+		filter:     func(string) bool { return true },
+		eventChan:  make(chan Event),
+		closeChan:  make(chan struct{}),
+		closedChan: make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	initial, err := d.snapshot(nil)
+	if err != nil {
+		initial = map[string]fileState{}
+	}
+	go d.loop(initial)
+	return d
+}
+
+//------------------------------------------------------------------------------
+
+// OptDirSetPeriod sets the period between each poll of the directory.
+func OptDirSetPeriod(period time.Duration) func(*Dir) {
+	return func(d *Dir) {
+		d.period = period
+	}
+}
+
+// OptDirSetFilter sets a closure that determines whether a file within the
+// directory should be watched. Files that are not watched are never read.
+func OptDirSetFilter(filter func(path string) bool) func(*Dir) {
+	return func(d *Dir) {
+		d.filter = filter
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// snapshot returns the state of each watched file within the directory. Files
+// that exist but cannot be read take their state from prev.
+func (d *Dir) snapshot(prev map[string]fileState) (map[string]fileState, error) {
+	states := map[string]fileState{}
+	if _, err := os.Stat(d.dir); err != nil {
+		if os.IsNotExist(err) {
+			return states, nil
+		}
+		return nil, err
+	}
+	err := filepath.Walk(d.dir, func(path string, info os.FileInfo, werr error) error {
+		if werr != nil {
+			return werr
+		}
+		if info.IsDir() || !d.filter(path) {
+			return nil
+		}
+		sum, err := checksum(path)
+		if err != nil {
+			states[path] = prev[path]
+			return nil
+		}
+		states[path] = fileState{exists: true, sum: sum}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return states, nil
+}
+
+func (d *Dir) loop(emitted map[string]fileState) {
+	defer close(d.closedChan)
+
+	pending := map[string]fileState{}
+	for k, v := range emitted {
+		pending[k] = v
+	}
+
+	for {
+		select {
+		case <-time.After(d.period):
+		case <-d.closeChan:
+			return
+		}
+
+		current, err := d.snapshot(pending)
+		if err != nil {
+			continue
+		}
+
+		paths := []string{}
+		seen := map[string]struct{}{}
+		for _, states := range []map[string]fileState{current, pending, emitted} {
+			for k := range states {
+				if _, exists := seen[k]; !exists {
+					seen[k] = struct{}{}
+					paths = append(paths, k)
+				}
+			}
+		}
+		sort.Strings(paths)
+
+		for _, path := range paths {
+			state := current[path]
+			if state != pending[path] {
+				pending[path] = state
+				continue
+			}
+			prev := emitted[path]
+			if state == prev {
+				if !state.exists {
+					delete(pending, path)
+					delete(emitted, path)
+				}
+				continue
+			}
+
+			event := Event{Path: path, Op: OpModify}
+			if !prev.exists {
+				event.Op = OpCreate
+			} else if !state.exists {
+				event.Op = OpRemove
+			}
+			select {
+			case d.eventChan <- event:
+			case <-d.closeChan:
+				return
+			}
+			emitted[path] = state
+		}
+	}
+}
+
+// EventChan returns a channel that receives an event for each change made to a
+// watched file. Events are not queued, and the directory is not polled again
+// until all events of a poll have been read.
+func (d *Dir) EventChan() <-chan Event {
+	return d.eventChan
+}
+
+// CloseAsync shuts down the watcher and stops polling.
+func (d *Dir) CloseAsync() {
+	if atomic.CompareAndSwapInt32(&d.running, 1, 0) {
+		close(d.closeChan)
+	}
+}
+
+// WaitForClose blocks until the watcher has stopped polling.
+func (d *Dir) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-d.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,122 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package watch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDirEvents(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_watch_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fooPath := filepath.Join(dir, "foo.yaml")
+	barPath := filepath.Join(dir, "nested", "bar.yaml")
+	ignoredPath := filepath.Join(dir, "ignored.txt")
+
+	if err = ioutil.WriteFile(fooPath, []byte("foo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewDir(
+		dir,
+		OptDirSetPeriod(time.Millisecond*10),
+		OptDirSetFilter(func(path string) bool {
+			return strings.HasSuffix(path, ".yaml")
+		}),
+	)
+	defer func() {
+		d.CloseAsync()
+		if err := d.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	expectEvent := func(exp Event) {
+		select {
+		case act := <-d.EventChan():
+			if act != exp {
+				t.Errorf("Wrong event: %v != %v", act, exp)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for event: %v", exp)
+		}
+	}
+	expectNone := func() {
+		select {
+		case act := <-d.EventChan():
+			t.Errorf("Unexpected event: %v", act)
+		case <-time.After(time.Millisecond * 100):
+		}
+	}
+
+	expectNone()
+
+	if err = os.Mkdir(filepath.Dir(barPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(barPath, []byte("bar"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(ignoredPath, []byte("ignored"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	expectEvent(Event{Path: barPath, Op: OpCreate})
+	expectNone()
+
+	if err = ioutil.WriteFile(fooPath, []byte("foo2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	expectEvent(Event{Path: fooPath, Op: OpModify})
+
+	if err = ioutil.WriteFile(fooPath, []byte("foo2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	expectNone()
+
+	if err = os.Remove(barPath); err != nil {
+		t.Fatal(err)
+	}
+	expectEvent(Event{Path: barPath, Op: OpRemove})
+	expectNone()
+}
+
+func TestOpString(t *testing.T) {
+	tests := map[Op]string{
+		OpCreate: "create",
+		OpModify: "modify",
+		OpRemove: "remove",
+		Op(10):   "unknown",
+	}
+	for op, exp := range tests {
+		if act := op.String(); act != exp {
+			t.Errorf("Wrong string for op %d: %v != %v", op, act, exp)
+		}
+	}
+}
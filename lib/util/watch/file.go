@@ -0,0 +1,144 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package watch
+
+import (
+	"hash/fnv"
+	"io/ioutil"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// File polls a file on disk and signals whenever its contents change. A change
+// is only signalled once the contents have remained the same for a full poll
+// period, which prevents partially written files from being reported.
+type File struct {
+	running int32
+	path    string
+	period  time.Duration
+
+	changeChan chan struct{}
+	closeChan  chan struct{}
+	closedChan chan struct{}
+}
+
+// NewFile creates a new File watcher for a path and begins polling it. The
+// current contents of the file are used as the starting point and therefore do
+// not result in a signal.
+func NewFile(path string, opts ...func(*File)) *File {
+	f := &File{
+		running:    1,
+		path:       path,
+		period:     time.Second,
+		changeChan: make(chan struct{}, 1),
+		closeChan:  make(chan struct{}),
+		closedChan: make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+	go f.loop()
+	return f
+}
+
+//------------------------------------------------------------------------------
+
+// OptFileSetPeriod sets the period between each poll of the file.
+func OptFileSetPeriod(period time.Duration) func(*File) {
+	return func(f *File) {
+		f.period = period
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// checksum returns a hash of the contents of a file.
+func checksum(path string) (uint64, error) {
+	fileBytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	h := fnv.New64a()
+	h.Write(fileBytes)
+	return h.Sum64(), nil
+}
+
+func (f *File) loop() {
+	defer close(f.closedChan)
+
+	// A file that cannot be read is ignored until it can be, as editors often
+	// remove and recreate files when saving.
+	emitted, _ := checksum(f.path)
+	pending := emitted
+
+	for {
+		select {
+		case <-time.After(f.period):
+		case <-f.closeChan:
+			return
+		}
+		sum, err := checksum(f.path)
+		if err != nil {
+			continue
+		}
+		if sum != pending {
+			pending = sum
+			continue
+		}
+		if sum != emitted {
+			emitted = sum
+			select {
+			case f.changeChan <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// ChangeChan returns a channel that receives a signal each time the contents
+// of the file change. Signals are not queued, and therefore multiple changes
+// made before the channel is read result in a single signal.
+func (f *File) ChangeChan() <-chan struct{} {
+	return f.changeChan
+}
+
+// CloseAsync shuts down the watcher and stops polling.
+func (f *File) CloseAsync() {
+	if atomic.CompareAndSwapInt32(&f.running, 1, 0) {
+		close(f.closeChan)
+	}
+}
+
+// WaitForClose blocks until the watcher has stopped polling.
+func (f *File) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-f.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
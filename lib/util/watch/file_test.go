@@ -0,0 +1,95 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package watch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileChanges(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_watch_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "foo.yaml")
+	if err = ioutil.WriteFile(path, []byte("foo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	f := NewFile(path, OptFileSetPeriod(time.Millisecond*10))
+	defer func() {
+		f.CloseAsync()
+		if err := f.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	select {
+	case <-f.ChangeChan():
+		t.Fatal("Unexpected change signal")
+	case <-time.After(time.Millisecond * 100):
+	}
+
+	if err = ioutil.WriteFile(path, []byte("bar"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-f.ChangeChan():
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for change signal")
+	}
+
+	// Removing the file should not result in a signal, nor should rewriting it
+	// with the same contents.
+	if err = os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-f.ChangeChan():
+		t.Fatal("Unexpected change signal")
+	case <-time.After(time.Millisecond * 100):
+	}
+	if err = ioutil.WriteFile(path, []byte("bar"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-f.ChangeChan():
+		t.Fatal("Unexpected change signal")
+	case <-time.After(time.Millisecond * 100):
+	}
+
+	if err = ioutil.WriteFile(path, []byte("baz"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-f.ChangeChan():
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for change signal")
+	}
+}